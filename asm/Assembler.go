@@ -0,0 +1,606 @@
+// Package asm : two pass 6502 assembler used to write tests and fixtures in
+// assembly instead of hand assembled hex strings
+//
+// The syntax follows ca65 closely enough to read back the GoNES disassembler
+// output:
+//
+//	        .org $8000              ; or *=$8000
+//	PPUADDR = $2006                 ; equates
+//	reset:  LDX #<table             ; labels end with a colon
+//	@loop:  LDA table,x             ; @labels are local to the last global label
+//	        BNE @loop
+//	        STA a:$0010             ; a: forces absolute, z: forces zero page
+//	        ASL a                   ; accumulator mode
+//	table:  .byte $01, "text", 'c'  ; .byte/.db, .word/.dw/.addr, .res count[, fill]
+//
+// Mnemonics, registers and directives are case insensitive, symbols are not.
+// Comments start with ; or //.
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Program : assembled code, a single block starting at Origin
+type Program struct {
+	Origin  uint16
+	Code    []byte
+	Symbols map[string]uint16
+}
+
+// Error : assembly error, with the line of the source that caused it
+type Error struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %v: %s", e.Line, e.Err, strings.TrimSpace(e.Text))
+}
+
+// Assemble : assembles the source into a program
+func Assemble(source string) (*Program, error) {
+	a := &assembler{
+		labels:     make(map[string]int),
+		equates:    make(map[string]*statement),
+		evaluating: make(map[string]bool),
+	}
+	for i, line := range strings.Split(source, "\n") {
+		if err := a.parse(i+1, line); err != nil {
+			return nil, err
+		}
+	}
+	return a.emit()
+}
+
+// MustAssemble : same as Assemble, but panics on errors, for tests and fixtures
+func MustAssemble(source string) *Program {
+	p, err := Assemble(source)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Hex : code as space separated hex bytes, e.g. "A9 3F 8D 06 20"
+func (p *Program) Hex() string {
+	s := make([]string, len(p.Code))
+	for i, b := range p.Code {
+		s[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(s, " ")
+}
+
+// End : address right after the last byte of the program
+func (p *Program) End() int {
+	return int(p.Origin) + len(p.Code)
+}
+
+// statementKind : what a line of source produces
+type statementKind byte
+
+const (
+	kindInstruction statementKind = iota
+	kindByte
+	kindWord
+	kindRes
+	kindEquate
+)
+
+// statement : a line of source after the first pass, with its address and size
+// fixed so the second pass only has to fill in the values
+type statement struct {
+	kind     statementKind
+	line     int
+	text     string
+	scope    string
+	address  int
+	size     int
+	mnemonic string
+	mode     mode
+	operand  string
+	args     []string
+}
+
+// assembler : state shared by both passes
+type assembler struct {
+	statements []*statement
+	labels     map[string]int
+	equates    map[string]*statement
+	evaluating map[string]bool
+	scope      string
+	pc         int
+	origin     int
+	emitted    bool
+}
+
+func (a *assembler) fail(s *statement, format string, args ...interface{}) error {
+	return &Error{s.line, s.text, fmt.Errorf(format, args...)}
+}
+
+// parse : first pass over a line, defines its labels and decides the size of
+// the code it produces
+func (a *assembler) parse(number int, line string) error {
+	s := &statement{line: number, text: line}
+	text := strings.TrimSpace(stripComment(line))
+
+	// labels
+	for {
+		n := symbolLength(text)
+		if n == 0 || n >= len(text) || text[n] != ':' {
+			break
+		}
+		name := text[:n]
+		if !strings.HasPrefix(name, "@") {
+			a.scope = name
+		}
+		if err := a.define(s, a.local(a.scope, name)); err != nil {
+			return err
+		}
+		a.labels[a.local(a.scope, name)] = a.pc
+		text = strings.TrimSpace(text[n+1:])
+	}
+	if text == "" {
+		return nil
+	}
+	s.scope = a.scope
+	s.address = a.pc
+
+	// *=$8000 and equates
+	if n := symbolLength(text); n > 0 || text[0] == '*' {
+		if n == 0 {
+			n = 1
+		}
+		if rest := strings.TrimSpace(text[n:]); strings.HasPrefix(rest, "=") {
+			if text[0] == '*' {
+				return a.org(s, strings.TrimSpace(rest[1:]))
+			}
+			s.kind = kindEquate
+			s.operand = strings.TrimSpace(rest[1:])
+			name := a.local(a.scope, text[:n])
+			if err := a.define(s, name); err != nil {
+				return err
+			}
+			a.equates[name] = s
+			return nil
+		}
+	}
+
+	word := text
+	operand := ""
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		word = text[:i]
+		operand = strings.TrimSpace(text[i:])
+	}
+
+	if strings.HasPrefix(word, ".") {
+		return a.directive(s, strings.ToLower(word), operand)
+	}
+	return a.instruction(s, strings.ToUpper(word), operand)
+}
+
+// define : checks that a symbol is only defined once
+func (a *assembler) define(s *statement, name string) error {
+	_, label := a.labels[name]
+	_, equate := a.equates[name]
+	if label || equate {
+		return a.fail(s, "%s already defined", name)
+	}
+	return nil
+}
+
+// local : full name of a symbol, @labels belong to the scope they are used in
+func (a *assembler) local(scope, name string) string {
+	if strings.HasPrefix(name, "@") {
+		return scope + name
+	}
+	return name
+}
+
+// advance : moves the program counter past the code of a statement
+func (a *assembler) advance(s *statement, size int) error {
+	if !a.emitted {
+		a.origin = a.pc
+		a.emitted = true
+	}
+	s.size = size
+	a.pc += size
+	a.statements = append(a.statements, s)
+	if a.pc > 0x10000 {
+		return a.fail(s, "program goes past $FFFF")
+	}
+	return nil
+}
+
+func (a *assembler) org(s *statement, operand string) error {
+	address, err := a.value(s, operand)
+	if err != nil {
+		return err
+	}
+	if address < 0 || address > 0xFFFF {
+		return a.fail(s, "origin $%X out of range", address)
+	}
+	if a.emitted && address < a.pc {
+		return a.fail(s, "origin $%04X is before the current address $%04X", address, a.pc)
+	}
+	a.pc = address
+	return nil
+}
+
+func (a *assembler) directive(s *statement, name, operand string) error {
+	switch name {
+	case ".org":
+		return a.org(s, operand)
+	case ".setcpu":
+		// only the 6502 is supported, kept for ca65 compatibility
+		return nil
+	case ".byte", ".db":
+		s.kind = kindByte
+		s.args = splitArgs(operand)
+		size := 0
+		for _, arg := range s.args {
+			if isString(arg) {
+				size += len(arg) - 2
+			} else {
+				size++
+			}
+		}
+		return a.advance(s, size)
+	case ".word", ".dw", ".addr":
+		s.kind = kindWord
+		s.args = splitArgs(operand)
+		return a.advance(s, 2*len(s.args))
+	case ".res", ".ds":
+		s.kind = kindRes
+		s.args = splitArgs(operand)
+		if len(s.args) < 1 || len(s.args) > 2 {
+			return a.fail(s, ".res takes a count and an optional fill value")
+		}
+		count, err := a.value(s, s.args[0])
+		if err != nil {
+			return err
+		}
+		if count < 0 {
+			return a.fail(s, "negative .res count %d", count)
+		}
+		return a.advance(s, count)
+	}
+	return a.fail(s, "unknown directive %s", name)
+}
+
+func (a *assembler) instruction(s *statement, mnemonic, operand string) error {
+	modes, ok := opcodes[mnemonic]
+	if !ok {
+		return a.fail(s, "unknown instruction %s", mnemonic)
+	}
+	s.kind = kindInstruction
+	s.mnemonic = mnemonic
+
+	m, expr, force := parseOperand(operand)
+	if _, ok := modes[relative]; ok && m == absolute {
+		m = relative
+	}
+
+	switch m {
+	case accumulator:
+		// a label called "a" on an instruction without an accumulator mode
+		if _, ok := modes[accumulator]; !ok {
+			m, expr = absolute, operand
+		}
+	}
+
+	switch m {
+	case implied:
+		if _, ok := modes[implied]; !ok {
+			m = accumulator
+		}
+	case absolute, absoluteX, absoluteY:
+		_, hasAbsolute := modes[m]
+		_, hasZeroPage := modes[m.zeroPageOf()]
+		if hasZeroPage && force != 'a' {
+			if force == 'z' || !hasAbsolute {
+				m = m.zeroPageOf()
+			} else if value, err := a.value(s, expr); err == nil && value >= 0 && value <= 0xFF {
+				m = m.zeroPageOf()
+			} else if _, forward := unwrap(err).(*undefinedError); err != nil && !forward {
+				return err
+			}
+		}
+	}
+
+	if _, ok := modes[m]; !ok {
+		return a.fail(s, "addressing mode not supported by %s", mnemonic)
+	}
+	s.mode = m
+	s.operand = expr
+	return a.advance(s, m.size())
+}
+
+// value : evaluates an expression at the address of the statement
+func (a *assembler) value(s *statement, expr string) (int, error) {
+	value, err := evaluate(expr, s.address, func(name string) (int, error) {
+		return a.symbol(s.scope, name)
+	})
+	if err != nil {
+		return 0, &Error{s.line, s.text, err}
+	}
+	return value, nil
+}
+
+// symbol : value of a label or equate, equates are evaluated when used so
+// they can refer to labels defined after them
+func (a *assembler) symbol(scope, name string) (int, error) {
+	name = a.local(scope, name)
+	if value, ok := a.labels[name]; ok {
+		return value, nil
+	}
+	s, ok := a.equates[name]
+	if !ok {
+		return 0, &undefinedError{name}
+	}
+	if a.evaluating[name] {
+		return 0, fmt.Errorf("%s is defined in terms of itself", name)
+	}
+	a.evaluating[name] = true
+	defer delete(a.evaluating, name)
+	return evaluate(s.operand, s.address, func(name string) (int, error) {
+		return a.symbol(s.scope, name)
+	})
+}
+
+// emit : second pass, every symbol is known now
+func (a *assembler) emit() (*Program, error) {
+	p := &Program{Origin: uint16(a.origin), Symbols: make(map[string]uint16)}
+
+	for name, value := range a.labels {
+		p.Symbols[name] = uint16(value)
+	}
+	for name, s := range a.equates {
+		value, err := a.symbol("", name)
+		if err != nil {
+			return nil, a.fail(s, "%v", err)
+		}
+		p.Symbols[name] = uint16(value)
+	}
+
+	for _, s := range a.statements {
+		// .org leaves a gap that is filled with zeros
+		for p.End() < s.address {
+			p.Code = append(p.Code, 0x00)
+		}
+		code, err := a.encode(s)
+		if err != nil {
+			return nil, err
+		}
+		p.Code = append(p.Code, code...)
+	}
+	return p, nil
+}
+
+// encode : bytes of a statement
+func (a *assembler) encode(s *statement) ([]byte, error) {
+	var code []byte
+
+	byteValue := func(expr string) error {
+		value, err := a.value(s, expr)
+		if err != nil {
+			return err
+		}
+		if value < -128 || value > 0xFF {
+			return a.fail(s, "value $%X does not fit in a byte", value)
+		}
+		code = append(code, byte(value))
+		return nil
+	}
+	wordValue := func(expr string) error {
+		value, err := a.value(s, expr)
+		if err != nil {
+			return err
+		}
+		if value < -32768 || value > 0xFFFF {
+			return a.fail(s, "value $%X does not fit in a word", value)
+		}
+		code = append(code, byte(value), byte(value>>8))
+		return nil
+	}
+
+	switch s.kind {
+	case kindByte:
+		for _, arg := range s.args {
+			if isString(arg) {
+				code = append(code, arg[1:len(arg)-1]...)
+			} else if err := byteValue(arg); err != nil {
+				return nil, err
+			}
+		}
+		return code, nil
+	case kindWord:
+		for _, arg := range s.args {
+			if err := wordValue(arg); err != nil {
+				return nil, err
+			}
+		}
+		return code, nil
+	case kindRes:
+		fill := 0
+		if len(s.args) == 2 {
+			value, err := a.value(s, s.args[1])
+			if err != nil {
+				return nil, err
+			}
+			fill = value
+		}
+		for i := 0; i < s.size; i++ {
+			code = append(code, byte(fill))
+		}
+		return code, nil
+	}
+
+	code = append(code, opcodes[s.mnemonic][s.mode])
+	switch s.mode {
+	case implied, accumulator:
+		return code, nil
+	case relative:
+		target, err := a.value(s, s.operand)
+		if err != nil {
+			return nil, err
+		}
+		offset := target - (s.address + 2)
+		if offset < -128 || offset > 127 {
+			return nil, a.fail(s, "branch target $%04X out of range", target)
+		}
+		return append(code, byte(offset)), nil
+	case immediate:
+		return code, byteValue(s.operand)
+	case absolute, absoluteX, absoluteY, indirect:
+		return code, wordValue(s.operand)
+	}
+
+	// zero page operands are addresses, negative values make no sense there
+	value, err := a.value(s, s.operand)
+	if err != nil {
+		return nil, err
+	}
+	if value < 0 || value > 0xFF {
+		return nil, a.fail(s, "address $%X is not in the zero page", value)
+	}
+	return append(code, byte(value)), nil
+}
+
+// parseOperand : addressing mode from the syntax of the operand, absolute
+// modes are narrowed to zero page or relative later, force is the a: or z:
+// prefix when there is one
+func parseOperand(operand string) (m mode, expr string, force byte) {
+	compact := strings.ToLower(strings.Replace(strings.Replace(operand, " ", "", -1), "\t", "", -1))
+	switch {
+	case compact == "":
+		return implied, "", 0
+	case compact == "a":
+		return accumulator, "", 0
+	case strings.HasPrefix(compact, "#"):
+		return immediate, strings.TrimSpace(operand[strings.Index(operand, "#")+1:]), 0
+	}
+
+	index := byte(0)
+	if n := len(compact); n > 2 && compact[n-2] == ',' && (compact[n-1] == 'x' || compact[n-1] == 'y') {
+		index = compact[n-1]
+		operand = strings.TrimSpace(operand[:strings.LastIndex(operand, ",")])
+	}
+
+	if strings.HasPrefix(operand, "(") && enclosed(operand) {
+		inner := strings.TrimSpace(operand[1 : len(operand)-1])
+		switch {
+		case index == 'y':
+			return indirectY, inner, 0
+		case index == 0 && strings.HasSuffix(strings.ToLower(strings.Replace(inner, " ", "", -1)), ",x"):
+			return indirectX, strings.TrimSpace(inner[:strings.LastIndex(inner, ",")]), 0
+		case index == 0:
+			return indirect, inner, 0
+		}
+	}
+
+	if lower := strings.ToLower(operand); strings.HasPrefix(lower, "a:") || strings.HasPrefix(lower, "z:") {
+		force = lower[0]
+		operand = strings.TrimSpace(operand[2:])
+	}
+
+	switch index {
+	case 'x':
+		return absoluteX, operand, force
+	case 'y':
+		return absoluteY, operand, force
+	}
+	return absolute, operand, force
+}
+
+// enclosed : the ( at the start of the text is closed by its last character,
+// so "($10),y" is indirect while "(1+2)*3" is a plain expression
+func enclosed(text string) bool {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i == len(text)-1
+			}
+		}
+	}
+	return false
+}
+
+// stripComment : removes ; and // comments that are not inside quotes
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';', c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitArgs : splits directive arguments on the commas outside of quotes
+func splitArgs(operand string) []string {
+	var args []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(operand); i++ {
+		c := operand[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(operand[start:i]))
+			start = i + 1
+		}
+	}
+	if rest := strings.TrimSpace(operand[start:]); rest != "" || len(args) > 0 {
+		args = append(args, rest)
+	}
+	return args
+}
+
+// symbolLength : length of the symbol at the start of the text, 0 if none
+func symbolLength(text string) int {
+	if text == "" || !isSymbolStart(text[0]) {
+		return 0
+	}
+	n := 1
+	for n < len(text) && isAlphanumeric(text[n]) {
+		n++
+	}
+	return n
+}
+
+func isString(arg string) bool {
+	return len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"'
+}
+
+// unwrap : error returned by the expression evaluator inside an Error
+func unwrap(err error) error {
+	if e, ok := err.(*Error); ok {
+		return e.Err
+	}
+	return err
+}
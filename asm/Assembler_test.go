@@ -0,0 +1,196 @@
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func assemble(t *testing.T, source string) *Program {
+	t.Helper()
+	p, err := Assemble(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAssembleAddressingModes(t *testing.T) {
+	tests := []struct {
+		source string
+		hex    string
+	}{
+		{"NOP", "EA"},
+		{"ASL", "0A"},
+		{"asl a", "0A"},
+		{"LDA #$3F", "A9 3F"},
+		{"LDA #%00001111", "A9 0F"},
+		{"LDA #'A'", "A9 41"},
+		{"LDA $10", "A5 10"},
+		{"LDA $10,X", "B5 10"},
+		{"LDX $10, y", "B6 10"},
+		{"LDA $1234", "AD 34 12"},
+		{"LDA $1234,x", "BD 34 12"},
+		{"LDA $1234,Y", "B9 34 12"},
+		{"LDA $10,y", "B9 10 00"},
+		{"LDA a:$0010", "AD 10 00"},
+		{"LDA a:$10,x", "BD 10 00"},
+		{"LDA z:$10", "A5 10"},
+		{"JMP ($0200)", "6C 00 02"},
+		{"LDA ($80,x)", "A1 80"},
+		{"LDA ( $80 , X )", "A1 80"},
+		{"LDA ($80),y", "B1 80"},
+		{"LDA (1+2)*2", "A5 06"},
+		{"LDA #<$1234", "A9 34"},
+		{"LDA #>$1234", "A9 12"},
+		{"LDA #-1", "A9 FF"},
+		{"LDA #1<<4|2", "A9 12"},
+		{"BNE *", "D0 FE"},
+		{"BEQ *+4", "F0 02"},
+		{"JMP *", "4C 00 80"},
+	}
+	for _, test := range tests {
+		p, err := Assemble(".org $8000\n" + test.source)
+		if err != nil {
+			t.Errorf("%q: %v", test.source, err)
+			continue
+		}
+		if p.Hex() != test.hex {
+			t.Errorf("%q: Expected: %s, got: %s", test.source, test.hex, p.Hex())
+		}
+	}
+}
+
+func TestAssembleLabels(t *testing.T) {
+	p := assemble(t, `
+PPUADDR = $2006
+PPUDATA = PPUADDR + 1
+count   = end - table       ; equates can use labels defined later
+
+*=$8000
+reset:  LDX #0
+@loop:  LDA table,x         // the table is not known yet, so it is absolute
+        STA PPUDATA
+        INX
+        CPX #count
+        BNE @loop
+        JSR sub
+@done:  JMP @done
+
+sub:    LDA zero            ; zero page label defined later, stays absolute
+@loop:  DEY                 ; @loop again, local to sub
+        BNE @loop
+        RTS
+
+table:  .byte $01, 2, "Hi", 'c'
+end:
+        .word reset, table
+        .res 2, $EA
+        .res 1
+zero = $00
+`)
+
+	want := "A2 00 BD 1A 80 8D 07 20 E8 E0 05 D0 F5 20 13 80 4C 10 80 " +
+		"AD 00 00 88 D0 FD 60 " +
+		"01 02 48 69 63 00 80 1A 80 EA EA 00"
+	if p.Hex() != want {
+		t.Errorf("Expected: %s, got: %s", want, p.Hex())
+	}
+	if p.Origin != 0x8000 || p.End() != 0x8000+len(p.Code) {
+		t.Errorf("Expected origin $8000, got: $%04X", p.Origin)
+	}
+
+	symbols := map[string]uint16{
+		"PPUDATA":    0x2007,
+		"count":      5,
+		"reset":      0x8000,
+		"reset@loop": 0x8002,
+		"sub":        0x8013,
+		"sub@loop":   0x8016,
+		"table":      0x801A,
+	}
+	for name, value := range symbols {
+		if p.Symbols[name] != value {
+			t.Errorf("%s: Expected: $%04X, got: $%04X", name, value, p.Symbols[name])
+		}
+	}
+}
+
+func TestAssembleOrgGap(t *testing.T) {
+	p := assemble(t, ".org $FFFA\n.word 1\n.org $FFFE\n.word $1234")
+	if p.Origin != 0xFFFA || p.Hex() != "01 00 00 00 34 12" {
+		t.Errorf("Expected the gap to be filled with zeros, got: $%04X %s", p.Origin, p.Hex())
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		line   int
+		err    string
+	}{
+		{"LDA #1\nFOO", 2, "unknown instruction FOO"},
+		{"STX $1234,x", 1, "addressing mode not supported by STX"},
+		{"JSR #1", 1, "addressing mode not supported by JSR"},
+		{"LDA #$100", 1, "does not fit in a byte"},
+		{"STX $1234,y", 1, "not in the zero page"},
+		{"LDA missing", 1, "undefined symbol \"missing\""},
+		{"x: NOP\nx: NOP", 2, "x already defined"},
+		{".org $8000\nBNE far\n.res 200\nfar: RTS", 2, "out of range"},
+		{".org $8000\nNOP\n.org $7000", 3, "before the current address"},
+		{"a = b\nb = a\nLDA a", 3, "defined in terms of itself"},
+		{".include \"x.s\"", 1, "unknown directive .include"},
+	}
+	for _, test := range tests {
+		_, err := Assemble(test.source)
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%q: Expected an *Error, got: %v", test.source, err)
+			continue
+		}
+		if e.Line != test.line || !strings.Contains(e.Error(), test.err) {
+			t.Errorf("%q: Expected %q at line %d, got: %v", test.source, test.err, test.line, e)
+		}
+	}
+}
+
+// TestAssembleFixtures : the files in test/opcode keep the hand assembled
+// bytes on their second line and the source after them
+func TestAssembleFixtures(t *testing.T) {
+	files, _ := filepath.Glob("../test/opcode/*.txt")
+	if len(files) == 0 {
+		t.Skip("no fixtures found")
+	}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+		if len(lines) < 3 {
+			t.Errorf("%s: missing source", file)
+			continue
+		}
+
+		p, err := Assemble(strings.Join(lines[2:], "\n"))
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if origin := fmt.Sprintf("%X", p.Origin); origin != strings.TrimSpace(lines[0]) {
+			t.Errorf("%s: Expected origin: %s, got: %s", file, strings.TrimSpace(lines[0]), origin)
+		}
+		// an empty hex line means the program is assembled when the fixture runs
+		if hex := strings.TrimSpace(lines[1]); hex != "" && p.Hex() != hex {
+			t.Errorf("%s: Expected: %s, got: %s", file, strings.TrimSpace(lines[1]), p.Hex())
+		}
+	}
+}
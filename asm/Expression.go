@@ -0,0 +1,211 @@
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// undefinedError : expression uses a symbol that is not known yet, during the
+// first pass this means a forward reference
+type undefinedError struct {
+	name string
+}
+
+func (e *undefinedError) Error() string {
+	return fmt.Sprintf("undefined symbol %q", e.name)
+}
+
+// binaryOperators : operators by precedence, lowest first
+var binaryOperators = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// expression : recursive descent evaluator for operand expressions
+// numbers: $FF hex, %1010 binary, 255 decimal, 'A' character
+// unary: - + ~ < (low byte) > (high byte), * alone is the current address
+type expression struct {
+	text    string
+	pos     int
+	pc      int
+	resolve func(name string) (int, error)
+}
+
+// evaluate : value of an expression, pc is the address of the statement
+func evaluate(text string, pc int, resolve func(string) (int, error)) (int, error) {
+	e := &expression{text: text, pc: pc, resolve: resolve}
+	value, err := e.binary(0)
+	if err != nil {
+		return 0, err
+	}
+	e.skipSpaces()
+	if e.pos < len(e.text) {
+		return 0, fmt.Errorf("unexpected %q in expression %q", e.text[e.pos:], text)
+	}
+	return value, nil
+}
+
+func (e *expression) skipSpaces() {
+	for e.pos < len(e.text) && (e.text[e.pos] == ' ' || e.text[e.pos] == '\t') {
+		e.pos++
+	}
+}
+
+// operator : consumes one of the operators if it is next in the text
+func (e *expression) operator(operators []string) (string, bool) {
+	e.skipSpaces()
+	for _, op := range operators {
+		if strings.HasPrefix(e.text[e.pos:], op) {
+			e.pos += len(op)
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (e *expression) binary(level int) (int, error) {
+	if level == len(binaryOperators) {
+		return e.unary()
+	}
+	left, err := e.binary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := e.operator(binaryOperators[level])
+		if !ok {
+			return left, nil
+		}
+		right, err := e.binary(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "|":
+			left |= right
+		case "^":
+			left ^= right
+		case "&":
+			left &= right
+		case "<<":
+			left <<= uint(right)
+		case ">>":
+			left >>= uint(right)
+		case "+":
+			left += right
+		case "-":
+			left -= right
+		case "*":
+			left *= right
+		case "/", "%":
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero in expression %q", e.text)
+			}
+			if op == "/" {
+				left /= right
+			} else {
+				left %= right
+			}
+		}
+	}
+}
+
+func (e *expression) unary() (int, error) {
+	e.skipSpaces()
+	if e.pos >= len(e.text) {
+		return 0, fmt.Errorf("missing value in expression %q", e.text)
+	}
+	op := e.text[e.pos]
+	switch op {
+	case '-', '+', '~', '<', '>':
+		e.pos++
+		value, err := e.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '-':
+			return -value, nil
+		case '~':
+			return ^value, nil
+		case '<':
+			return value & 0xFF, nil
+		case '>':
+			return (value >> 8) & 0xFF, nil
+		}
+		return value, nil
+	}
+	return e.primary()
+}
+
+func (e *expression) primary() (int, error) {
+	start := e.pos
+	c := e.text[e.pos]
+	switch {
+	case c == '(':
+		e.pos++
+		value, err := e.binary(0)
+		if err != nil {
+			return 0, err
+		}
+		e.skipSpaces()
+		if e.pos >= len(e.text) || e.text[e.pos] != ')' {
+			return 0, fmt.Errorf("missing ) in expression %q", e.text)
+		}
+		e.pos++
+		return value, nil
+	case c == '*':
+		e.pos++
+		return e.pc, nil
+	case c == '\'':
+		if e.pos+2 >= len(e.text) || e.text[e.pos+2] != '\'' {
+			return 0, fmt.Errorf("bad character literal in expression %q", e.text)
+		}
+		e.pos += 3
+		return int(e.text[start+1]), nil
+	case c == '$' || c == '%':
+		e.pos++
+		base := 16
+		if c == '%' {
+			base = 2
+		}
+		for e.pos < len(e.text) && isAlphanumeric(e.text[e.pos]) {
+			e.pos++
+		}
+		value, err := strconv.ParseInt(e.text[start+1:e.pos], base, 32)
+		if err != nil {
+			return 0, fmt.Errorf("bad number %q", e.text[start:e.pos])
+		}
+		return int(value), nil
+	case c >= '0' && c <= '9':
+		for e.pos < len(e.text) && isAlphanumeric(e.text[e.pos]) {
+			e.pos++
+		}
+		value, err := strconv.ParseInt(e.text[start:e.pos], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("bad number %q", e.text[start:e.pos])
+		}
+		return int(value), nil
+	case isSymbolStart(c):
+		e.pos++
+		for e.pos < len(e.text) && isAlphanumeric(e.text[e.pos]) {
+			e.pos++
+		}
+		return e.resolve(e.text[start:e.pos])
+	}
+	return 0, fmt.Errorf("unexpected %q in expression %q", e.text[e.pos:], e.text)
+}
+
+// isSymbolStart : symbols start with a letter, _ or @ for local labels
+func isSymbolStart(c byte) bool {
+	return c == '_' || c == '@' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isAlphanumeric(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Scoppio/GoNES/nes"
+)
+
+// consoleOptions : how the console is set up, for the window and the
+// headless runner alike
+type consoleOptions struct {
+	pal        string
+	region     string
+	ntscFilter bool
+	// genie : Game Genie codes separated by commas, a leading - enters the
+	// code turned off
+	genie string
+	// cheats : directory keeping the codes of each ROM, none when empty
+	cheats string
+	// movie : FM2 file played back from power on
+	movie string
+	// record : FM2 file the input is recorded to from power on
+	record string
+}
+
+// openConsole : loads the ROM into a new console set up with the options
+func openConsole(rom string, opts consoleOptions) (*nes.Console, error) {
+	console := nes.CreateConsole()
+	if err := console.LoadROM(rom); err != nil {
+		return nil, err
+	}
+	palette, err := loadPalette(opts.pal, rom, console.Cartridge())
+	if err != nil {
+		return nil, err
+	}
+	console.SetPalette(palette)
+	if opts.region != "" && opts.region != "auto" {
+		tv, err := nes.ParseTVSystem(opts.region)
+		if err != nil {
+			return nil, err
+		}
+		console.SetTVSystem(tv)
+	}
+	if opts.ntscFilter {
+		console.SetNTSCFilter(nes.CreateNTSCFilter(nes.DefaultNTSCParams()))
+	}
+	if err := enterGameGenie(console, opts); err != nil {
+		return nil, err
+	}
+	return console, nil
+}
+
+// enterGameGenie : loads the codes saved for the ROM, enters the ones of
+// -genie and saves them all back
+func enterGameGenie(console *nes.Console, opts consoleOptions) error {
+	if opts.cheats != "" {
+		if err := console.LoadGameGenie(opts.cheats); err != nil {
+			return err
+		}
+	}
+	if opts.genie == "" {
+		return nil
+	}
+	genie := console.GameGenie()
+	for _, code := range strings.Split(opts.genie, ",") {
+		code = strings.TrimSpace(code)
+		off := strings.HasPrefix(code, "-")
+		code = strings.TrimPrefix(code, "-")
+		if err := genie.Add(code); err != nil {
+			return err
+		}
+		if off {
+			genie.SetEnabled(code, false)
+		}
+	}
+	if opts.cheats != "" {
+		return console.SaveGameGenie(opts.cheats)
+	}
+	return nil
+}
+
+// tasStatus : the frame and lag counters and the input of both controllers,
+// for the window and the debugger
+func tasStatus(console *nes.Console) string {
+	lag := ""
+	if console.Lagged() {
+		lag = " LAG"
+	}
+	return fmt.Sprintf("frame %d lag %d%s\n1P %s\n2P %s",
+		console.FrameCount(), console.LagCount(), lag,
+		console.Controller(0).Display(), console.Controller(1).Display())
+}
+
+// startMovie : plays -movie back or starts recording -record, the movie
+// being recorded is returned to be saved with saveMovie once the run is over
+func startMovie(console *nes.Console, rom string, opts consoleOptions) (*nes.Movie, error) {
+	if opts.movie != "" && opts.record != "" {
+		return nil, fmt.Errorf("-movie and -record can not be used together")
+	}
+	if opts.movie != "" {
+		movie, err := nes.OpenMovie(opts.movie)
+		if err != nil {
+			return nil, err
+		}
+		if err := movie.CheckROM(console.Cartridge()); err != nil {
+			fmt.Fprintln(os.Stderr, "warning:", err)
+		}
+		console.PlayMovie(movie)
+		return nil, nil
+	}
+	if opts.record != "" {
+		movie := nes.CreateMovie(filepath.Base(rom), console.Cartridge())
+		console.RecordMovie(movie)
+		return movie, nil
+	}
+	return nil, nil
+}
+
+// saveMovie : writes the recorded movie, nothing happens without one
+func saveMovie(path string, movie *nes.Movie) error {
+	if movie == nil {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := movie.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Scoppio/GoNES/nes"
+)
+
+func TestOpenConsoleGameGenie(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rom := writeROM(t, dir, counterROM)
+	cheats := filepath.Join(dir, "cheats")
+
+	console, err := openConsole(rom, consoleOptions{genie: "SXIOPO, -ZEXPYGLA", cheats: cheats})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := console.GameGenie().Cheats(); len(got) != 2 || !got[0].Enabled || got[1].Enabled {
+		t.Errorf("Expected SXIOPO on and ZEXPYGLA off, got: %+v", got)
+	}
+
+	// the codes were saved for the ROM
+	console, err = openConsole(rom, consoleOptions{cheats: cheats})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := console.GameGenie().Cheats(); len(got) != 2 {
+		t.Errorf("Expected the 2 saved codes, got: %+v", got)
+	}
+
+	if _, err := openConsole(rom, consoleOptions{genie: "SXIOPB"}); err == nil {
+		t.Error("Expected an error for a bad code")
+	}
+}
+
+func TestTASStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	console, err := openConsole(writeROM(t, dir, counterROM), consoleOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	console.SetController(0, nes.ButtonLeft|nes.ButtonA)
+	console.RunFrame()
+	console.RunFrame()
+	want := "frame 2 lag 0\n1P <      A\n2P         "
+	if got := tasStatus(console); got != want {
+		t.Errorf("Expected %q, got: %q", want, got)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Scoppio/GoNES/nes"
+)
+
+// debugger : the console driven by the terminal debugger and what it shows
+type debugger struct {
+	console      *nes.Console
+	mapAsm       map[nes.Word]string
+	viewSelected string
+	search       *nes.CheatSearch
+}
+
+// cheatListed : candidates the debugger shows after a search
+const cheatListed = 16
+
+func createDebugger() *debugger {
+	d := &debugger{console: nes.CreateConsole(), viewSelected: "ccode"}
+	d.console.Reset()
+	return d
+}
+
+// SetRom : Put a ROM on the memory of the Nes Emulator
+func (d *debugger) SetRom(rom string) error {
+	if err := d.console.LoadROM(rom); err != nil {
+		return err
+	}
+	d.mapAsm = d.console.Disassemble(0x0000, 0xFFFF)
+	filename := time.Now().Format("2006-01-02_15:04:05")
+	if err := nes.WriteDisassemble(nes.DisassembleCartridge(d.console.Cartridge()), "../output/disasemble_"+filename+".s"); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+func (d *debugger) tick() {
+	d.console.Step()
+}
+
+func (d *debugger) reset() {
+	d.console.Reset()
+	// finish the reset sequence
+	d.console.Step()
+}
+
+// cheatCommand : runs a line typed in the cheats view, one of
+//
+//	search                 start over with every RAM address
+//	eq, changed, gt, lt    keep the addresses whose value did that since the last search
+//	value N                keep the addresses holding N
+//	freeze ADDR N          keep ADDR at N
+//	unfreeze ADDR          let the game change ADDR again
+//	frozen                 list the frozen addresses
+//
+// numbers are decimal or $hex
+func (d *debugger) cheatCommand(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	args := make([]int, len(fields)-1)
+	for i, field := range fields[1:] {
+		n, err := parseCheatNumber(field)
+		if err != nil {
+			return "", err
+		}
+		args[i] = n
+	}
+	want := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s takes %d values", fields[0], n)
+		}
+		return nil
+	}
+
+	switch fields[0] {
+	case "search":
+		d.search = nes.CreateCheatSearch(d.console)
+		return d.cheatCandidates(), nil
+	case "freeze":
+		if err := want(2); err != nil {
+			return "", err
+		}
+		if err := d.console.Freeze(nes.Word(args[0]), byte(args[1])); err != nil {
+			return "", err
+		}
+		return d.cheatFrozen(), nil
+	case "unfreeze":
+		if err := want(1); err != nil {
+			return "", err
+		}
+		d.console.Unfreeze(nes.Word(args[0]))
+		return d.cheatFrozen(), nil
+	case "frozen":
+		return d.cheatFrozen(), nil
+	}
+
+	comparison, err := nes.ParseCheatComparison(fields[0])
+	if err != nil {
+		return "", err
+	}
+	if comparison == nes.CheatValue {
+		err = want(1)
+	} else {
+		err = want(0)
+		args = append(args, 0)
+	}
+	if err != nil {
+		return "", err
+	}
+	if d.search == nil {
+		d.search = nes.CreateCheatSearch(d.console)
+	}
+	d.search.Filter(comparison, byte(args[0]))
+	return d.cheatCandidates(), nil
+}
+
+// cheatCandidates : how many addresses are left and the first ones
+func (d *debugger) cheatCandidates() string {
+	candidates := d.search.Candidates()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d candidates\n", len(candidates))
+	for i, c := range candidates {
+		if i == cheatListed {
+			sb.WriteString("...\n")
+			break
+		}
+		fmt.Fprintf(&sb, "$%04X $%02X -> $%02X\n", c.Address, c.Previous, c.Value)
+	}
+	return sb.String()
+}
+
+// cheatFrozen : the frozen addresses and their values
+func (d *debugger) cheatFrozen() string {
+	var sb strings.Builder
+	for _, f := range d.console.Frozen() {
+		fmt.Fprintf(&sb, "$%04X = $%02X\n", f.Address, f.Value)
+	}
+	return sb.String()
+}
+
+// parseCheatNumber : $hex or decimal
+func parseCheatNumber(s string) (int, error) {
+	base := 10
+	if strings.HasPrefix(s, "$") {
+		s, base = s[1:], 16
+	}
+	n, err := strconv.ParseUint(s, base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("bad number %q", s)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDebuggerCheats(t *testing.T) {
+	d := createDebugger()
+	out, err := d.cheatCommand("search")
+	if err != nil || !strings.HasPrefix(out, "2048 candidates") {
+		t.Errorf("Expected the 2KB of RAM as candidates, got: %q %v", out, err)
+	}
+
+	d.cheatCommand("freeze $10 7")
+	out, err = d.cheatCommand("value $07")
+	if err != nil || !strings.Contains(out, "$0010 $00 -> $07") {
+		t.Errorf("Expected $0010 to be left, got: %q %v", out, err)
+	}
+	if out, _ := d.cheatCommand("frozen"); out != "$0010 = $07\n" {
+		t.Errorf("Expected $0010 to be frozen, got: %q", out)
+	}
+	if out, _ := d.cheatCommand("unfreeze 16"); out != "" {
+		t.Errorf("Expected nothing frozen, got: %q", out)
+	}
+
+	for _, bad := range []string{"value", "freeze $10", "freeze $8000 1", "eq 1", "about", "value $1G"} {
+		if _, err := d.cheatCommand(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Scoppio/GoNES/nes"
+)
+
+const (
+	// headlessFrameLimit : frames run while waiting for -until when -frames is
+	// not given, ten minutes of NTSC video
+	headlessFrameLimit = 10 * 60 * 60
+)
+
+// exit codes of the headless runner
+const (
+	exitOK     = 0
+	exitUnmet  = 1
+	exitError  = 2
+	exitJammed = 3
+)
+
+// headlessOptions : what the headless runner does, from the command line
+type headlessOptions struct {
+	consoleOptions
+	frames          int
+	until           string
+	input           string
+	screenshot      string
+	screenshotEvery int
+	ram             string
+	trace           string
+	// debugger views written at the end
+	patterns   string
+	palette    int
+	nametables string
+	palettes   string
+	sprites    string
+}
+
+// inputChange : buttons held on both controllers from a frame on
+type inputChange struct {
+	frame   int
+	buttons [2]nes.Buttons
+}
+
+// runHeadless : runs the ROM without a display and returns the exit code,
+// 1 when the -until condition never held and 3 when the CPU jammed
+func runHeadless(rom string, opts headlessOptions) int {
+	fail := func(err error) int {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	console, err := openConsole(rom, opts.consoleOptions)
+	if err != nil {
+		return fail(err)
+	}
+	if opts.input != "" && opts.movie != "" {
+		return fail(fmt.Errorf("-input and -movie both set the controllers"))
+	}
+	recording, err := startMovie(console, rom, opts.consoleOptions)
+	if err != nil {
+		return fail(err)
+	}
+
+	var until []nes.Expectation
+	for _, field := range strings.Fields(opts.until) {
+		e, err := nes.ParseExpectation(field)
+		if err != nil {
+			return fail(err)
+		}
+		until = append(until, e)
+	}
+	frames := opts.frames
+	if frames <= 0 {
+		frames = headlessFrameLimit
+	}
+
+	var script []inputChange
+	if opts.input != "" {
+		if script, err = readInputScript(opts.input); err != nil {
+			return fail(err)
+		}
+	}
+
+	if opts.trace != "" {
+		f, err := os.Create(opts.trace)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+		w := bufio.NewWriter(f)
+		defer func() {
+			if err := w.Flush(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+		console.SetTrace(w)
+	}
+
+	code := exitOK
+	if len(until) > 0 {
+		code = exitUnmet
+	}
+	frame := 0
+	for frame < frames {
+		for len(script) > 0 && script[0].frame <= frame {
+			console.SetController(0, script[0].buttons[0])
+			console.SetController(1, script[0].buttons[1])
+			script = script[1:]
+		}
+
+		console.RunFrame()
+		frame++
+		if opts.screenshotEvery > 0 && frame%opts.screenshotEvery == 0 && opts.screenshot != "" {
+			if err := writePNG(numbered(opts.screenshot, frame), console.Frame()); err != nil {
+				return fail(err)
+			}
+		}
+
+		if console.Jammed() {
+			fmt.Fprintf(os.Stderr, "CPU jammed on frame %d: %s\n", frame, console.TraceLine())
+			code = exitJammed
+			break
+		}
+		if len(until) > 0 && satisfiesAll(console, until) {
+			code = exitOK
+			break
+		}
+	}
+	if code == exitUnmet {
+		fmt.Fprintf(os.Stderr, "%q did not hold within %d frames\n", opts.until, frames)
+	}
+
+	ppu := console.PPU()
+	views := []struct {
+		path string
+		view func() image.Image
+	}{
+		{opts.screenshot, func() image.Image { return console.Frame() }},
+		{opts.patterns, func() image.Image { return patternTables(ppu, byte(opts.palette)) }},
+		{opts.nametables, func() image.Image { return ppu.GetNameTables() }},
+		{opts.palettes, func() image.Image { return ppu.GetPaletteRAM() }},
+		{opts.sprites, func() image.Image { return ppu.GetSprites() }},
+	}
+	for _, v := range views {
+		if v.path == "" {
+			continue
+		}
+		if err := writePNG(v.path, v.view()); err != nil {
+			return fail(err)
+		}
+	}
+	if opts.ram != "" {
+		if err := ioutil.WriteFile(opts.ram, console.RAM(), 0644); err != nil {
+			return fail(err)
+		}
+	}
+	if err := saveMovie(opts.record, recording); err != nil {
+		return fail(err)
+	}
+	return code
+}
+
+func satisfiesAll(console *nes.Console, checks []nes.Expectation) bool {
+	for _, e := range checks {
+		if !console.Satisfies(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// numbered : shot.png becomes shot-120.png for frame 120
+func numbered(path string, frame int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), frame, ext)
+}
+
+// patternTables : both pattern tables side by side
+func patternTables(ppu *nes.PPU2C02, palette byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 128))
+	for i := 0; i < 2; i++ {
+		table := ppu.GetPatternTable(i, palette)
+		draw.Draw(img, table.Bounds().Add(image.Pt(128*i, 0)), table, image.ZP, draw.Src)
+	}
+	return img
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readInputScript : reads the buttons to press, one change per line:
+//
+//	# frame  player 1  [player 2]
+//	0        -
+//	60       start
+//	62       -
+//	100      a+right   b
+//
+// The buttons stay held until the next line, frames count from 0
+func readInputScript(path string) ([]inputChange, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var script []inputChange
+	for i, line := range strings.Split(string(text), "\n") {
+		if comment := strings.IndexByte(line, '#'); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected a frame and up to two controllers", path, i+1)
+		}
+
+		change := inputChange{}
+		if change.frame, err = strconv.Atoi(fields[0]); err != nil || change.frame < 0 {
+			return nil, fmt.Errorf("%s:%d: bad frame %q", path, i+1, fields[0])
+		}
+		if len(script) > 0 && change.frame < script[len(script)-1].frame {
+			return nil, fmt.Errorf("%s:%d: frames must be in order", path, i+1)
+		}
+		for port, field := range fields[1:] {
+			if change.buttons[port], err = nes.ParseButtons(field); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+			}
+		}
+		script = append(script, change)
+	}
+	return script, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// writeROM : writes an NROM iNES file with the program at $C000
+func writeROM(t *testing.T, dir, source string) string {
+	program, err := asm.Assemble(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prg := make([]byte, 16384)
+	copy(prg, program.Code)
+	prg[0x3FFC], prg[0x3FFD] = 0x00, 0xC0
+
+	rom := append([]byte{'N', 'E', 'S', 0x1A, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, prg...)
+	rom = append(rom, make([]byte, 8192)...)
+	path := filepath.Join(dir, "test.nes")
+	if err := ioutil.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// counterROM : counts frames in $10 and copies controller 1 to $11
+const counterROM = `
+        .org $C000
+reset:  LDA #$01
+        STA $4016
+        LDA #$00
+        STA $4016
+        LDX #$08
+@read:  LDA $4016
+        LSR A
+        ROL $11
+        DEX
+        BNE @read
+        INC $10
+@wait:  BIT $2002
+        BPL @wait
+        JMP reset
+`
+
+func TestHeadlessOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rom := writeROM(t, dir, counterROM)
+	input := filepath.Join(dir, "input.txt")
+	ioutil.WriteFile(input, []byte("# frame p1\n0 -\n5 start+a\n"), 0644)
+
+	opts := headlessOptions{
+		frames:          10,
+		input:           input,
+		screenshot:      filepath.Join(dir, "shot.png"),
+		screenshotEvery: 5,
+		ram:             filepath.Join(dir, "ram.bin"),
+		trace:           filepath.Join(dir, "trace.log"),
+		patterns:        filepath.Join(dir, "chr.png"),
+		palette:         4,
+		nametables:      filepath.Join(dir, "nt.png"),
+		palettes:        filepath.Join(dir, "palettes.png"),
+		sprites:         filepath.Join(dir, "oam.png"),
+	}
+	if code := runHeadless(rom, opts); code != exitOK {
+		t.Fatalf("Expected exit code %d, got: %d", exitOK, code)
+	}
+
+	for _, name := range []string{"shot.png", "shot-5.png", "shot-10.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+	for name, size := range map[string]image.Point{
+		"chr.png": {256, 128}, "nt.png": {512, 480}, "palettes.png": {256, 32}, "oam.png": {64, 128},
+	} {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil || img.Bounds().Size() != size {
+			t.Errorf("Expected %s to be a %v PNG", name, size)
+		}
+	}
+	ram, _ := ioutil.ReadFile(opts.ram)
+	if len(ram) != 2048 || ram[0x11] != 0x90 || ram[0x10] < 9 {
+		t.Errorf("Expected 2KB of RAM with the frames counted and the buttons read, got %d bytes", len(ram))
+	}
+	trace, _ := ioutil.ReadFile(opts.trace)
+	if !strings.HasPrefix(string(trace), "C000  A9 01     LDA #$01") {
+		t.Errorf("Expected the trace to start at the reset vector, got: %.40q", trace)
+	}
+}
+
+func TestHeadlessMovie(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rom := writeROM(t, dir, counterROM)
+	input := filepath.Join(dir, "input.txt")
+	ioutil.WriteFile(input, []byte("0 right\n5 start+a\n8 b\n"), 0644)
+	movie := filepath.Join(dir, "run.fm2")
+	recorded, played := filepath.Join(dir, "recorded.bin"), filepath.Join(dir, "played.bin")
+
+	opts := headlessOptions{consoleOptions: consoleOptions{record: movie}, frames: 10, input: input, ram: recorded}
+	if code := runHeadless(rom, opts); code != exitOK {
+		t.Fatalf("Expected exit code %d, got: %d", exitOK, code)
+	}
+	fm2, _ := ioutil.ReadFile(movie)
+	if !strings.Contains(string(fm2), "romFilename test.nes\n") || !strings.HasSuffix(string(fm2), "|0|......B.|........||\n") {
+		t.Errorf("Expected an FM2 movie ending with B held, got: %q", fm2)
+	}
+
+	opts = headlessOptions{consoleOptions: consoleOptions{movie: movie}, frames: 10, ram: played}
+	if code := runHeadless(rom, opts); code != exitOK {
+		t.Fatalf("Expected exit code %d, got: %d", exitOK, code)
+	}
+	a, _ := ioutil.ReadFile(recorded)
+	b, _ := ioutil.ReadFile(played)
+	if len(a) != 2048 || !bytes.Equal(a, b) {
+		t.Error("Expected the movie to end with the RAM it was recorded with")
+	}
+
+	opts.input = input
+	if code := runHeadless(rom, opts); code != exitError {
+		t.Errorf("Expected -input and -movie together to fail, got: %d", code)
+	}
+}
+
+func TestHeadlessExitCodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rom := writeROM(t, dir, counterROM)
+	if code := runHeadless(rom, headlessOptions{frames: 100, until: "[$10]=20"}); code != exitOK {
+		t.Errorf("Expected exit code %d, got: %d", exitOK, code)
+	}
+	if code := runHeadless(rom, headlessOptions{frames: 5, until: "[$10]=20"}); code != exitUnmet {
+		t.Errorf("Expected exit code %d, got: %d", exitUnmet, code)
+	}
+	if code := runHeadless(rom, headlessOptions{frames: 5, until: "Q=1"}); code != exitError {
+		t.Errorf("Expected exit code %d, got: %d", exitError, code)
+	}
+	// the longer PAL frames still count one at a time
+	if code := runHeadless(rom, headlessOptions{consoleOptions: consoleOptions{region: "pal"}, frames: 50, until: "[$10]=50"}); code != exitOK {
+		t.Errorf("Expected exit code %d, got: %d", exitOK, code)
+	}
+	if code := runHeadless(rom, headlessOptions{consoleOptions: consoleOptions{region: "secam"}, frames: 5}); code != exitError {
+		t.Errorf("Expected exit code %d, got: %d", exitError, code)
+	}
+
+	rom = writeROM(t, dir, "\t.org $C000\n\tLDA #$01\n\t.byte $02\n")
+	if code := runHeadless(rom, headlessOptions{frames: 5}); code != exitJammed {
+		t.Errorf("Expected exit code %d, got: %d", exitJammed, code)
+	}
+}
+
+func TestReadInputScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "input.txt")
+
+	ioutil.WriteFile(path, []byte("0 -\n\n60 start # press start\n70 a+right b\n"), 0644)
+	script, err := readInputScript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(script) != 3 || script[1].frame != 60 || script[2].buttons[0].String() != "a+right" || script[2].buttons[1].String() != "b" {
+		t.Errorf("Unexpected script: %v", script)
+	}
+
+	for _, bad := range []string{"x start", "10 turbo", "10 a\n5 b", "1 a b c"} {
+		ioutil.WriteFile(path, []byte(bad), 0644)
+		if _, err := readInputScript(path); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Scoppio/GoNES/nes"
+	"github.com/jroimartin/gocui"
+)
+
+const (
+	height     = 480
+	width      = 780
+	rows       = 240
+	columns    = 256
+	swatchSize = 6
+)
+
+func initialization(rompath string) {
+
+}
+
+func main() {
+	testROMs := flag.String("testroms", "", "run every test ROM in the directory using the $6000 status protocol")
+	junitReport := flag.String("junit", "", "write the test ROM results as JUnit XML to this file")
+	jsonReport := flag.String("json", "", "write the test ROM results as JSON to this file")
+
+	var headless headlessOptions
+	flag.IntVar(&headless.frames, "frames", 0, "run the ROM headless for this many frames")
+	flag.StringVar(&headless.until, "until", "", "run the ROM headless until all the checks hold, like \"[$6000]=$00 A=1\"")
+	flag.StringVar(&headless.input, "input", "", "buttons to press while running headless, one \"frame player1 [player2]\" per line")
+	flag.StringVar(&headless.screenshot, "screenshot", "", "write the last frame to this PNG file")
+	flag.IntVar(&headless.screenshotEvery, "screenshot-every", 0, "also write a numbered screenshot every this many frames")
+	flag.StringVar(&headless.ram, "ram", "", "write the 2KB of RAM to this file at the end")
+	flag.StringVar(&headless.trace, "trace", "", "write the CPU trace to this file")
+	flag.StringVar(&headless.pal, "pal", "", "colors to draw with: a .pal file, 2c02, rgb or ntsc[:hue=0,saturation=1,contrast=1,brightness=0,gamma=1], game.pal next to the ROM by default")
+	flag.StringVar(&headless.region, "region", "auto", "timing to run with: ntsc, pal, dendy or auto to follow the ROM header")
+	flag.BoolVar(&headless.ntscFilter, "ntsc-filter", false, "draw the picture through a simulated NTSC composite signal")
+	flag.StringVar(&headless.genie, "genie", "", "Game Genie codes separated by commas, like \"SXIOPO,-AATOZA\", a leading - enters a code turned off")
+	flag.StringVar(&headless.movie, "movie", "", "play this FM2 movie back from power on")
+	flag.StringVar(&headless.record, "record", "", "record the input from power on to this FM2 movie")
+	flag.StringVar(&headless.cheats, "cheats", "", "directory keeping the Game Genie codes of each ROM, -genie codes are added to them")
+	flag.StringVar(&headless.patterns, "patterns", "", "write both pattern tables to this PNG file")
+	flag.IntVar(&headless.palette, "palette", 0, "palette 0 to 7 used to color -patterns")
+	flag.StringVar(&headless.nametables, "nametables", "", "write the four nametables with the scroll outlined to this PNG file")
+	flag.StringVar(&headless.palettes, "palettes", "", "write the palette RAM to this PNG file")
+	flag.StringVar(&headless.sprites, "sprites", "", "write the 64 OAM sprites to this PNG file")
+	scale := flag.Int("scale", 2, "size of the window, the picture is scaled by 1 to 4")
+	debug := flag.Bool("debug", false, "open the terminal debugger instead of a window")
+	flag.Parse()
+
+	if *testROMs != "" {
+		os.Exit(runTestROMs(*testROMs, *junitReport, *jsonReport))
+	}
+
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: GoNES [flags] rom.nes")
+		flag.PrintDefaults()
+		os.Exit(exitError)
+	}
+	if headless.frames > 0 || headless.until != "" {
+		os.Exit(runHeadless(flag.Arg(0), headless))
+	}
+	if !*debug {
+		os.Exit(runWindow(flag.Arg(0), *scale, headless.consoleOptions))
+	}
+
+	os.Exit(runDebugger(flag.Arg(0)))
+}
+
+// runDebugger : shows the console in the terminal until Ctrl-C, Ctrl-D steps
+// an instruction, Ctrl-F a frame, Ctrl-R resets and Tab switches the code view
+func runDebugger(rom string) int {
+	d := createDebugger()
+	if err := d.SetRom(rom); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	g, err := gocui.NewGui(gocui.OutputNormal)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer g.Close()
+
+	g.SetManagerFunc(d.layout)
+	g.Mouse = true
+	g.Cursor = true
+
+	if err := d.setKeybindings(g); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return 0
+}
+
+// setKeybindings : the keys of the debugger, Enter only runs a command in the
+// cheats view, the others work from every view
+func (d *debugger) setKeybindings(g *gocui.Gui) error {
+	bindings := []struct {
+		view    string
+		key     gocui.Key
+		handler func(*gocui.Gui, *gocui.View) error
+	}{
+		{"", gocui.KeyCtrlC, quit},
+		{"", gocui.KeyCtrlD, d.tickEmulator},
+		{"", gocui.KeyCtrlF, d.advanceFrame},
+		{"", gocui.KeyCtrlR, d.resetEmulator},
+		{"", gocui.KeyTab, d.changeCodeView},
+		{"cheats", gocui.KeyEnter, d.runCheatLine},
+	}
+	for _, b := range bindings {
+		if err := g.SetKeybinding(b.view, b.key, gocui.ModNone, b.handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTestROMs : runs a directory of test ROMs headless, prints a summary and
+// returns the exit code for the process, 1 when a ROM failed and 2 when the
+// ROMs or the reports could not be read or written. Skipped ROMs do not fail
+// the run
+func runTestROMs(dir, junitReport, jsonReport string) int {
+	results, err := nes.RunTestROMDirectory(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	code := exitOK
+	for _, r := range results {
+		state := "PASS"
+		switch r.Outcome {
+		case nes.TestROMSkipped:
+			state = "SKIP"
+		case nes.TestROMFailed, nes.TestROMError:
+			state = "FAIL"
+			code = exitUnmet
+		}
+		fmt.Printf("%s %s (status $%s) %s%s\n", state, r.Name, nes.Hex(uint32(r.Status), 2), r.Error, r.Text)
+	}
+
+	write := func(filename string, report func(f *os.File) error) {
+		if filename == "" {
+			return
+		}
+		f, err := os.Create(filename)
+		if err == nil {
+			err = report(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = exitError
+		}
+	}
+	write(junitReport, func(f *os.File) error { return nes.WriteJUnitReport(f, filepath.Base(dir), results) })
+	write(jsonReport, func(f *os.File) error { return nes.WriteJSONReport(f, results) })
+	return code
+}
+
+func (d *debugger) layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	if v, err := g.SetView("views", 0, 0, maxX/5, 7); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Views"
+		fmt.Fprintln(v, "6502 Asm\nC code\nstack\nregister\nmemory\nwatch")
+	}
+	if v, err := g.SetView("watch", maxX/5+1, maxY-11, 4*(maxX/5), maxY-1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		fmt.Fprintln(v, "_cursor = 0x01\n_cursor+1 = 0x00\n_cursor+2 = 0xa0")
+	}
+	if v, err := g.SetView("assembly", maxX/5+1, 0, 4*(maxX/5), maxY-12); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		if d.viewSelected == "asm" {
+			s := []string{";",
+				"; File generated by cc65 v 2.18 - Git 0f08ae2",
+				";",
+				"    .fopt  compiler,\"cc65 v 2.18 - Git 0f08ae2\"",
+				"    .setcpu  \"6502\"",
+				"    .smart  on",
+				"    .autoimport  on",
+				"    .case  on",
+				"    .debuginfo	off",
+				"    .importzp  sp, sreg, regsave, regbank",
+				"    .importzp  tmp1, tmp2, tmp3, tmp4, ptr1, ptr2, ptr3, ptr4",
+				"    .macpack  longbranch",
+				"    .forceimport  __STARTUP__",
+				"    .import  _pal_bg",
+				"    .import  _pal_spr",
+				"    .import  _ppu_wait_nmi",
+				"    .import  _ppu_off",
+				"    .import  _ppu_on_all",
+				"    .import  _oam_clear",
+				"    .import  _oam_spr",
+				"    .import  _oam_meta_spr",
+				"    .import  _pad_poll",
+				"    .import  _bank_spr",
+				"    .import  _rand8",
+				"    .import  _set_rand",
+				"    .import  _vram_adr",
+				"    .import  _vram_put",
+				"    .import  _set_vram_buffer",
+				"    .import  _multi_vram_buffer_horz",
+				"    .import  _clear_vram_buffer",
+				"    .import  _get_pad_new",
+				"    .import  _set_scroll_y",
+				"    .import  _get_ppu_addr",
+				"    .import  _get_at_addr",
+				"    .import  _set_data_pointer",
+				"    .import  _set_mt_pointer",
+				"    .import  _buffer_4_mt",
+				"    .import  _flush_vram_update_nmi",
+				"    .export  _play_sound",
+				"    .export  _i"}
+			t := strings.Join(s, "\n")
+			v.Title = "Assembly Code"
+			v.Wrap = true
+			fmt.Fprintln(v, t)
+		} else {
+			s := []string{"void _init(void)",
+				"{",
+				"    ppu_off();    // screen off",
+				"    pal_bg(palette_bg);  // load the BG palette",
+				"    pal_spr(palette_sp); // load the Sprite palette",
+				"    ppu_on_all();  //  turn on screen",
+				"    set_vram_buffer();  // PPU pointed to VRAM Buffer",
+				"    bank_spr(0);  // set bank for sprite",
+				"    reset_game();  // reset game variables",
+				"}",
+				"",
+				"void reset_game(void)",
+				"{",
+				"    cursor.card = NULL;",
+				"    cursor.cell = 0;",
+				"    (*table_ptr)[0] = NULL;",
+				"    (*table_ptr)[1] = NULL;",
+				"    (*table_ptr)[2] = NULL;",
+				"    (*table_ptr)[3] = NULL;",
+				"    red_size_pt = 0;",
+				"    yellow_size_pt = 0;",
+				"    green_size_pt = 0;",
+				"    blue_size_pt = 0;",
+				"    red_bc_count = 3;",
+				"    yellow_bc_count = 3;",
+				"    green_bc_count = 3;",
+				"    blue_bc_count = 3;",
+				"    round = 13;",
+				"    round_score = 0;",
+				"    pp = 0;",
+				"    second_forever = 0;",
+				"    map_registers = NULL;",
+				"    challenge = 0;",
+				"    kicked_client = FALSE;",
+				"    ",
+				"    shuffle_decks();",
+				"}"}
+
+			t := strings.Join(s, "\n")
+
+			v.Title = "C code"
+			v.Wrap = true
+			fmt.Fprintln(v, t)
+		}
+
+	}
+
+	if v, err := g.SetView("memory", 0, 8, maxX/5, maxY-1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Memory map"
+		fmt.Fprint(v, d.console.MemoryMap())
+	}
+
+	// the counters change with every frame, the view is drawn again each time
+	v, err := g.SetView("tas", 4*(maxX/5)+1, 0, maxX-1, 4)
+	if err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	v.Title = "Frame"
+	v.Clear()
+	fmt.Fprint(v, tasStatus(d.console))
+
+	if v, err := g.SetView("registers", 4*(maxX/5)+1, 5, maxX-1, 14); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		s := []string{"a: 0x00",
+			"tick-count: 0",
+			"pc: 0x00",
+			"x: 0x00",
+			"y: 0x00",
+			"stkptr: 0x00",
+			"N: 0",
+			"V: 0 ",
+			"U: 0",
+			"B: 0",
+			"I: 0",
+			"Z: 0",
+			"C: 0"}
+		t := strings.Join(s, "\n")
+		v.Title = "Registers"
+		v.Wrap = true
+		fmt.Fprintln(v, t)
+	}
+
+	if v, err := g.SetView("stack", 4*(maxX/5)+1, 15, maxX-1, maxY/2); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		s := []string{"0x25",
+			"0x11",
+			"0x13",
+			"0x01"}
+		t := strings.Join(s, "\n")
+
+		v.Title = "Stack"
+		v.Wrap = true
+		fmt.Fprintln(v, t)
+	}
+
+	if v, err := g.SetView("cheats", 4*(maxX/5)+1, maxY/2+1, maxX-1, maxY-1); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Cheats"
+		v.Editable = true
+		fmt.Fprintln(v, "search, eq, changed, gt, lt, value N, freeze ADDR N, unfreeze ADDR, frozen")
+		v.SetCursor(0, 1)
+		// the commands are typed there, the other keys work from any view
+		if _, err := g.SetCurrentView("cheats"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *debugger) tickEmulator(g *gocui.Gui, v *gocui.View) error {
+	d.tick()
+	return nil
+}
+func (d *debugger) advanceFrame(g *gocui.Gui, v *gocui.View) error {
+	d.console.RunFrame()
+	return nil
+}
+func (d *debugger) resetEmulator(g *gocui.Gui, v *gocui.View) error {
+	d.reset()
+	return nil
+}
+
+// runCheatLine : runs the line under the cursor of the cheats view and shows
+// what it printed, the cursor goes to the line after it for the next command
+func (d *debugger) runCheatLine(g *gocui.Gui, v *gocui.View) error {
+	_, y := v.Cursor()
+	line, _ := v.Line(y)
+	out, err := d.cheatCommand(line)
+	if err != nil {
+		out = err.Error() + "\n"
+	}
+	v.Clear()
+	fmt.Fprint(v, out)
+	return v.SetCursor(0, strings.Count(out, "\n"))
+}
+
+func (d *debugger) changeCodeView(g *gocui.Gui, v *gocui.View) error {
+	if d.viewSelected == "asm" {
+		d.viewSelected = "ccode"
+	} else {
+		d.viewSelected = "asm"
+	}
+	return nil
+}
+
+func memoryPointer(g *gocui.Gui) error {
+
+	// drawCPU(516, 15)
+	// drawCode(516, 112, 16)
+
+	// drawRAM(2, 12, 0x0000, 16, 16)
+	// draw palette selected
+	// drawRect(float64(int(516)+int(selectedPalette)*(swatchSize*5)-1), 132, swatchSize*4+2, swatchSize+2, &colornames.White)
+
+	// for p := 0; p < 8; p++ {
+	// 	for s := 0; s < 4; s++ {
+	// 		drawRect(float64(516+p*(swatchSize*5)+s*swatchSize), 133, swatchSize, swatchSize, nes.ppu.GetColorFromPaletteRAM(byte(p), byte(s)))
+	// 	}
+	// }
+
+	// elapsedTime = -lastUpdate.Sub(time.Now()).Seconds()
+	// lastUpdate = time.Now()
+	return nil
+}
+
+func quit(g *gocui.Gui, v *gocui.View) error {
+	return gocui.ErrQuit
+}
+
+// func drawCPU(x, y float64) {
+// 	c := cpu
+// 	redGreen := func(b bool) color.RGBA {
+// 		if b {
+// 			return colornames.White
+// 		}
+// 		return colornames.Red
+// 	}
+// 	drawString(x, y, "CPU", colornames.White)
+// 	drawString(x-30+64, y, "N", redGreen(c.StatusRegister(N)))
+// 	drawString(x-30+80, y, "V", redGreen(c.StatusRegister(V)))
+// 	drawString(x-30+96, y, "U", redGreen(c.StatusRegister(U)))
+// 	drawString(x-30+112, y, "B", redGreen(c.StatusRegister(B)))
+// 	drawString(x-30+128, y, "D", redGreen(c.StatusRegister(D)))
+// 	drawString(x-30+144, y, "I", redGreen(c.StatusRegister(I)))
+// 	drawString(x-30+160, y, "Z", redGreen(c.StatusRegister(Z)))
+// 	drawString(x-30+178, y, "C", redGreen(c.StatusRegister(C)))
+// 	drawString(x, y+12, fmt.Sprintln("PC: ", fmt.Sprintf("$%s [%d]", nes.Hex(uint32(c.pc), 4), c.pc)), colornames.White)
+// 	drawString(x, y+24, fmt.Sprintln("A : ", fmt.Sprintf("$%s   [%d]", nes.Hex(uint32(c.a), 2), c.a)), colornames.White)
+// 	drawString(x, y+36, fmt.Sprintln("X : ", fmt.Sprintf("$%s   [%d]", nes.Hex(uint32(c.x), 2), c.x)), colornames.White)
+// 	drawString(x, y+48, fmt.Sprintln("Y : ", fmt.Sprintf("$%s   [%d]", nes.Hex(uint32(c.y), 2), c.y)), colornames.White)
+// 	drawString(x, y+60, fmt.Sprintln("Stack P: ", fmt.Sprintf("$%s", nes.Hex(uint32(c.stkp), 4))), colornames.White)
+// 	drawString(x, y+72, fmt.Sprintln("Clock Count: ", ClockCount), colornames.White)
+// 	drawString(x, y+84, fmt.Sprintln("Operation Count: ", OperationCount), colornames.White)
+// 	drawString(x, y, fmt.Sprintln("Clock: ", c.cycles), colornames.White)
+// 	drawString(x, y, fmt.Sprintln("ADD ABS: ", fmt.Sprintf("0x%X", c.addressAbs)), colornames.White)
+// 	drawString(x, y, fmt.Sprintln("ADD REL: ", fmt.Sprintf("0x%X", c.addressRel)), colornames.White)
+// }
+
+// func drawRAM(x, y float64, addr Word, rows, columns int) {
+// 	RAMX := x
+// 	RAMY := y
+// 	for row := 0; row < rows; row++ {
+// 		var sOffset bytes.Buffer
+// 		sOffset.WriteByte('$')
+// 		sOffset.WriteString(nes.Hex(uint32(addr), 4))
+// 		sOffset.WriteByte(':')
+// 		for col := 0; col < columns; col++ {
+// 			v, e := nes.CPURead(addr, true)
+// 			if e != nil {
+// 				//
+// 			}
+// 			if cpu.pc == addr {
+// 				sOffset.WriteByte('>')
+// 			} else {
+// 				sOffset.WriteByte(' ')
+// 			}
+// 			sOffset.WriteString(nes.Hex(uint32(v), 2))
+// 			addr++
+// 		}
+// 		drawString(RAMX, RAMY, sOffset.String(), colornames.White)
+// 		RAMY += 11
+// 	}
+// }
+
+// func drawCode(x, y float64, lines int) {
+// 	//
+// 	pc := cpu.pc
+// 	yPos := float64(lines>>1)*11 + y
+// 	if ida, ok := mapAsm[pc]; ok {
+// 		drawString(x, yPos, ida, colornames.Cyan)
+// 		for yPos < float64(lines)*10+y {
+// 			pc++
+// 			if ida, ok = mapAsm[pc]; ok {
+// 				if len(ida) > 0 {
+// 					yPos += 11
+// 					drawString(x, yPos, ida, colornames.White)
+// 				}
+// 			}
+// 		}
+// 	}
+// 	pc = cpu.pc
+// 	yPos = float64(lines>>1)*11 + y
+// 	if _, ok := mapAsm[pc]; ok {
+// 		for yPos > y {
+// 			pc--
+// 			if adi, ok := mapAsm[pc]; ok {
+// 				yPos -= 11
+// 				drawString(x, yPos, adi, colornames.White)
+// 			}
+// 		}
+// 	}
+// }
+
+func drawString(x, y float64, message string, color color.RGBA) {
+	// basicTxt.Dot = pixel.V(x, height-y)
+	// basicTxt.Color = color
+	// fmt.Fprintln(basicTxt, message)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jroimartin/gocui"
+)
+
+func TestDebuggerKeybindings(t *testing.T) {
+	d := createDebugger()
+	g := &gocui.Gui{}
+	if err := d.setKeybindings(g); err != nil {
+		t.Fatal(err)
+	}
+
+	// deleting a keybinding only succeeds when it was registered
+	registered := []struct {
+		view string
+		key  gocui.Key
+	}{
+		{"", gocui.KeyCtrlC},
+		{"", gocui.KeyCtrlD},
+		{"", gocui.KeyCtrlF},
+		{"", gocui.KeyCtrlR},
+		{"cheats", gocui.KeyEnter},
+	}
+	for _, kb := range registered {
+		if err := g.DeleteKeybinding(kb.view, kb.key, gocui.ModNone); err != nil {
+			t.Errorf("Expected key %v to be bound on view %q: %v", kb.key, kb.view, err)
+		}
+	}
+	if g.DeleteKeybinding("", gocui.KeyEnter, gocui.ModNone) == nil {
+		t.Error("Expected enter to be bound on the cheats view only")
+	}
+}
+
+func TestDebuggerAdvanceFrame(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := createDebugger()
+	if err := d.console.LoadROM(writeROM(t, dir, counterROM)); err != nil {
+		t.Fatal(err)
+	}
+	frame := d.console.FrameCount()
+	if err := d.advanceFrame(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if d.console.FrameCount() != frame+1 {
+		t.Errorf("Expected frame %d, got: %d", frame+1, d.console.FrameCount())
+	}
+}
+
+func TestRunTestROMsSkipsUnsupportedMappers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// an MMC3 cartridge, mapper 4 is not emulated
+	header := []byte{'N', 'E', 'S', 0x1A, 1, 1, 0x40, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	rom := append(header, make([]byte, 16384+8192)...)
+	if err := ioutil.WriteFile(filepath.Join(dir, "mmc3.nes"), rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := filepath.Join(dir, "report.json")
+	if code := runTestROMs(dir, "", report); code != exitOK {
+		t.Errorf("Expected exit code %d for a skipped ROM, got: %d", exitOK, code)
+	}
+	if data, _ := ioutil.ReadFile(report); !strings.Contains(string(data), `"outcome": "skipped"`) {
+		t.Errorf("Expected the ROM to be reported as skipped, got: %s", data)
+	}
+
+	// a report that can not be written is an error, not a failed ROM
+	report = filepath.Join(dir, "missing", "report.xml")
+	if code := runTestROMs(dir, report, ""); code != exitError {
+		t.Errorf("Expected exit code %d for an unwritable report, got: %d", exitError, code)
+	}
+}
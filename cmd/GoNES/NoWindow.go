@@ -0,0 +1,16 @@
+//go:build headless
+// +build headless
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// runWindow : built with the headless tag there is no window, only the
+// headless runner and the debugger
+func runWindow(rom string, scale int, opts consoleOptions) int {
+	fmt.Fprintln(os.Stderr, "built without a window, run with -frames or -until, or -debug")
+	return exitError
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+)
+
+const (
+	// fastForwardSpeed : how many times faster fast forward runs
+	fastForwardSpeed = 4
+)
+
+// framePacer : decides how many frames to emulate so the game runs at the
+// speed of the console whatever the refresh rate of the display
+type framePacer struct {
+	period time.Duration
+	next   time.Time
+}
+
+func createFramePacer(rate float64, now time.Time) *framePacer {
+	return &framePacer{time.Duration(float64(time.Second) / rate), now}
+}
+
+// due : frames to run at now, running behind by more than a few frames
+// drops them instead of trying to catch up
+func (p *framePacer) due(now time.Time, speed int) int {
+	frames := 0
+	for !now.Before(p.next) {
+		frames++
+		p.next = p.next.Add(p.period / time.Duration(speed))
+		if frames == 4*speed {
+			p.next = now.Add(p.period / time.Duration(speed))
+			break
+		}
+	}
+	return frames
+}
+
+// wait : time left until the next frame is due
+func (p *framePacer) wait(now time.Time) time.Duration {
+	return p.next.Sub(now)
+}
+
+// fpsCounter : frames shown in the last second
+type fpsCounter struct {
+	frames int
+	start  time.Time
+	fps    float64
+}
+
+// count : adds frames and returns the rate measured over the last second
+func (f *fpsCounter) count(frames int, now time.Time) float64 {
+	f.frames += frames
+	if elapsed := now.Sub(f.start); elapsed >= time.Second {
+		f.fps = float64(f.frames) / elapsed.Seconds()
+		f.frames, f.start = 0, now
+	}
+	return f.fps
+}
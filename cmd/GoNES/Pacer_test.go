@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Scoppio/GoNES/nes"
+)
+
+func TestFramePacer(t *testing.T) {
+	start := time.Now()
+	p := createFramePacer(nes.NTSC.FrameRate(), start)
+	period := p.period
+
+	if got := p.due(start, 1); got != 1 {
+		t.Errorf("Expected the first frame right away, got: %d", got)
+	}
+	if got := p.due(start.Add(period/2), 1); got != 0 {
+		t.Errorf("Expected no frame before the period, got: %d", got)
+	}
+
+	// a second of NTSC video is a bit more than 60 frames
+	frames := 1
+	for now := start.Add(period / 2); now.Before(start.Add(time.Second)); now = now.Add(time.Millisecond) {
+		frames += p.due(now, 1)
+	}
+	if frames != 61 {
+		t.Errorf("Expected 61 frames in a second, got: %d", frames)
+	}
+
+	// fast forward runs several frames per period
+	p = createFramePacer(nes.NTSC.FrameRate(), start)
+	frames = 0
+	for now := start; now.Before(start.Add(time.Second)); now = now.Add(time.Millisecond) {
+		frames += p.due(now, fastForwardSpeed)
+	}
+	if frames < 60*fastForwardSpeed || frames > 61*fastForwardSpeed {
+		t.Errorf("Expected about %d frames in a second, got: %d", 60*fastForwardSpeed, frames)
+	}
+
+	// after a long stall the pacer gives up catching up
+	p = createFramePacer(nes.NTSC.FrameRate(), start)
+	if got := p.due(start.Add(time.Second), 1); got != 4 {
+		t.Errorf("Expected at most 4 frames, got: %d", got)
+	}
+	if wait := p.wait(start.Add(time.Second)); wait <= 0 || wait > period {
+		t.Errorf("Expected to wait less than a frame, got: %v", wait)
+	}
+}
+
+func TestFPSCounter(t *testing.T) {
+	start := time.Now()
+	f := &fpsCounter{start: start}
+	for i := 1; i <= 60; i++ {
+		f.count(1, start.Add(time.Duration(i)*time.Second/60))
+	}
+	if fps := f.count(0, start.Add(time.Second)); fps < 59.9 || fps > 60.1 {
+		t.Errorf("Expected 60 FPS, got: %.2f", fps)
+	}
+}
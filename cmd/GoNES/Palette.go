@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Scoppio/GoNES/nes"
+)
+
+// loadPalette : the palette picked with -pal for the ROM:
+//
+//	""            game.pal next to game.nes when there is one, otherwise the
+//	              RGB palette for Vs. System games and the 2C02 one for the rest
+//	2c02          the default palette
+//	rgb           the 2C03/2C05 palette, also 2c03 and 2c05
+//	ntsc[:knobs]  generated from the NTSC signal, like ntsc:hue=-5,gamma=1.2
+//	anything else a .pal file
+func loadPalette(spec, rom string, cart *nes.Cartridge) (*nes.Palette, error) {
+	switch lower := strings.ToLower(spec); {
+	case spec == "":
+		perGame := strings.TrimSuffix(rom, filepath.Ext(rom)) + ".pal"
+		if _, err := os.Stat(perGame); err == nil {
+			return nes.OpenPalette(perGame)
+		}
+		return nes.PaletteFor(cart), nil
+	case lower == "2c02":
+		return nes.DefaultPalette(), nil
+	case lower == "rgb" || lower == "2c03" || lower == "2c05":
+		return nes.RGBPalette(), nil
+	case lower == "ntsc" || strings.HasPrefix(lower, "ntsc:"):
+		params, err := nes.ParseNTSCParams(strings.TrimPrefix(lower[4:], ":"))
+		if err != nil {
+			return nil, err
+		}
+		return nes.GeneratePalette(params), nil
+	}
+	return nes.OpenPalette(spec)
+}
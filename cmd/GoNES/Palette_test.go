@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Scoppio/GoNES/nes"
+)
+
+func TestLoadPalette(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rom := writeROM(t, dir, counterROM)
+	cart, err := nes.OpenCartridge(rom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same := func(spec string, want *nes.Palette) {
+		got, err := loadPalette(spec, rom, cart)
+		if err != nil {
+			t.Errorf("%q: %v", spec, err)
+		} else if *got != *want {
+			t.Errorf("%q: unexpected palette", spec)
+		}
+	}
+	same("", nes.DefaultPalette())
+	same("2C02", nes.DefaultPalette())
+	same("rgb", nes.RGBPalette())
+	same("2c05", nes.RGBPalette())
+	same("ntsc", nes.GeneratePalette(nes.DefaultNTSCParams()))
+	same("ntsc:hue=10", nes.GeneratePalette(nes.NTSCParams{Hue: 10, Saturation: 1, Contrast: 1, Gamma: 1}))
+
+	// a palette next to the ROM is picked for that game
+	data := make([]byte, 192)
+	data[0] = 0xFF
+	ioutil.WriteFile(filepath.Join(dir, "test.pal"), data, 0644)
+	got, err := loadPalette("", rom, cart)
+	if err != nil || got[0][0].R != 0xFF {
+		t.Errorf("Expected test.pal to be loaded, got: %v", err)
+	}
+
+	for _, bad := range []string{"ntsc:tint=1", filepath.Join(dir, "missing.pal"), rom} {
+		if _, err := loadPalette(bad, rom, cart); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"time"
+
+	"github.com/Scoppio/GoNES/nes"
+	"github.com/faiface/pixel"
+	"github.com/faiface/pixel/pixelgl"
+	"github.com/faiface/pixel/text"
+	"golang.org/x/image/font/basicfont"
+)
+
+// keyMap : keys of each controller button
+//
+//	player 1: arrows, X = A, Z = B, right shift = Select, enter = Start
+//	player 2: WASD, K = A, J = B, G = Select, H = Start
+var keyMap = [2]map[nes.Buttons]pixelgl.Button{
+	{
+		nes.ButtonA: pixelgl.KeyX, nes.ButtonB: pixelgl.KeyZ,
+		nes.ButtonSelect: pixelgl.KeyRightShift, nes.ButtonStart: pixelgl.KeyEnter,
+		nes.ButtonUp: pixelgl.KeyUp, nes.ButtonDown: pixelgl.KeyDown,
+		nes.ButtonLeft: pixelgl.KeyLeft, nes.ButtonRight: pixelgl.KeyRight,
+	},
+	{
+		nes.ButtonA: pixelgl.KeyK, nes.ButtonB: pixelgl.KeyJ,
+		nes.ButtonSelect: pixelgl.KeyG, nes.ButtonStart: pixelgl.KeyH,
+		nes.ButtonUp: pixelgl.KeyW, nes.ButtonDown: pixelgl.KeyS,
+		nes.ButtonLeft: pixelgl.KeyA, nes.ButtonRight: pixelgl.KeyD,
+	},
+}
+
+// hotkeys of the window
+const (
+	keyPause        = pixelgl.KeyP
+	keyFrameAdvance = pixelgl.KeyN
+	keyFastForward  = pixelgl.KeyTab
+	keyReset        = pixelgl.KeyR
+	keyFPS          = pixelgl.KeyF1
+	keyTAS          = pixelgl.KeyF2
+	keyQuit         = pixelgl.KeyEscape
+)
+
+// runWindow : plays the ROM in a window until it is closed
+func runWindow(rom string, scale int, opts consoleOptions) int {
+	if scale < 1 {
+		scale = 1
+	} else if scale > 4 {
+		scale = 4
+	}
+	console, err := openConsole(rom, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	recording, err := startMovie(console, rom, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	pixelgl.Run(func() {
+		err = playWindow(console, rom, scale)
+	})
+	if err == nil {
+		err = saveMovie(opts.record, recording)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// windowBounds : the size of the window for an integer scale of the picture
+func windowBounds(scale int) pixel.Rect {
+	return pixel.R(0, 0, float64(nes.FrameWidth*scale), float64(nes.FrameHeight*scale))
+}
+
+func playWindow(console *nes.Console, title string, scale int) error {
+	win, err := pixelgl.NewWindow(pixelgl.WindowConfig{
+		Title:  "GoNES - " + title,
+		Bounds: windowBounds(scale),
+	})
+	if err != nil {
+		return err
+	}
+
+	atlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
+	overlay := text.New(pixel.ZV, atlas)
+	showFPS := true
+	showTAS := false
+	paused := false
+
+	pacer := createFramePacer(console.TVSystem().FrameRate(), time.Now())
+	counter := &fpsCounter{start: time.Now()}
+
+	for !win.Closed() && !win.JustPressed(keyQuit) {
+		for i, key := range []pixelgl.Button{pixelgl.Key1, pixelgl.Key2, pixelgl.Key3, pixelgl.Key4} {
+			if win.JustPressed(key) && scale != i+1 {
+				scale = i + 1
+				win.SetBounds(windowBounds(scale))
+			}
+		}
+		if win.JustPressed(keyPause) {
+			paused = !paused
+		}
+		if win.JustPressed(keyFPS) {
+			showFPS = !showFPS
+		}
+		if win.JustPressed(keyTAS) {
+			showTAS = !showTAS
+		}
+		// advancing a frame pauses the game first
+		advance := win.JustPressed(keyFrameAdvance)
+		if advance {
+			paused = true
+		}
+		if win.JustPressed(keyReset) {
+			console.Reset()
+		}
+
+		for port, keys := range keyMap {
+			var buttons nes.Buttons
+			for button, key := range keys {
+				if win.Pressed(key) {
+					buttons |= button
+				}
+			}
+			console.SetController(port, buttons)
+		}
+
+		speed := 1
+		if win.Pressed(keyFastForward) {
+			speed = fastForwardSpeed
+		}
+		frames := pacer.due(time.Now(), speed)
+		if paused {
+			frames = 0
+			if advance {
+				frames = 1
+			}
+		}
+		for i := 0; i < frames; i++ {
+			console.RunFrame()
+		}
+		fps := counter.count(frames, time.Now())
+
+		win.Clear(color.Black)
+		picture := pixel.PictureDataFromImage(console.Frame())
+		pixel.NewSprite(picture, picture.Bounds()).
+			Draw(win, pixel.IM.Scaled(pixel.ZV, float64(scale)).Moved(win.Bounds().Center()))
+
+		if showFPS || paused || showTAS {
+			overlay.Clear()
+			if showFPS {
+				fmt.Fprintf(overlay, "%.1f FPS", fps)
+			}
+			if paused {
+				fmt.Fprint(overlay, " PAUSED")
+			}
+			if showTAS {
+				fmt.Fprintf(overlay, "\n%s", tasStatus(console))
+			}
+			overlay.Draw(win, pixel.IM.Moved(pixel.V(4, win.Bounds().H()-atlas.LineHeight())))
+		}
+		win.Update()
+
+		// sleep until the next frame is due
+		if wait := pacer.wait(time.Now()); wait > time.Millisecond {
+			time.Sleep(wait - time.Millisecond)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+module github.com/Scoppio/GoNES
+
+go 1.13
+
+require (
+	github.com/faiface/pixel v0.10.0
+	github.com/jroimartin/gocui v0.5.0
+	golang.org/x/image v0.18.0
+)
@@ -0,0 +1,263 @@
+package nes
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// blarggStatus : address where the test ROM writes its status byte
+	blarggStatus = Word(0x6000)
+	// blarggText : address of the zero terminated result text
+	blarggText = Word(0x6004)
+	// blarggRunning : status while the test is still running
+	blarggRunning = 0x80
+	// blarggResetRequested : status asking for a reset of the console
+	blarggResetRequested = 0x81
+
+	// cpuClockRate : NTSC CPU cycles per second
+	cpuClockRate = 1789773
+	// blarggResetDelay : the ROM wants at least 100ms before the reset is pressed
+	blarggResetDelay = cpuClockRate / 10
+	// TestROMTimeout : emulated CPU cycles a ROM is allowed to run before giving up
+	TestROMTimeout = 60 * cpuClockRate
+)
+
+var (
+	blarggSignature = [3]byte{0xDE, 0xB0, 0x61}
+)
+
+// Outcomes of a test ROM
+const (
+	// TestROMPassed : the ROM wrote $00 as its status
+	TestROMPassed = "passed"
+	// TestROMFailed : the ROM wrote another status
+	TestROMFailed = "failed"
+	// TestROMError : the ROM could not be run to the end, see Error
+	TestROMError = "error"
+	// TestROMSkipped : the ROM needs hardware that is not emulated, like its
+	// mapper, and was not run
+	TestROMSkipped = "skipped"
+)
+
+// TestROMResult : outcome of running a single test ROM
+type TestROMResult struct {
+	Name     string  `json:"name"`
+	Path     string  `json:"path"`
+	Status   byte    `json:"status"`
+	Outcome  string  `json:"outcome"`
+	Passed   bool    `json:"passed"`
+	Text     string  `json:"text"`
+	Error    string  `json:"error,omitempty"`
+	Resets   int     `json:"resets"`
+	Cycles   int     `json:"cycles"`
+	Duration float64 `json:"duration"`
+}
+
+// RunTestROM : loads a ROM from a file and runs it until it reports a result
+// using the $6000 status protocol
+func RunTestROM(path string) TestROMResult {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	cart, err := OpenCartridge(path)
+	if err != nil {
+		return TestROMResult{Name: name, Path: path, Outcome: TestROMError, Error: err.Error()}
+	}
+	return RunTestCartridge(name, path, cart, TestROMTimeout)
+}
+
+// RunTestCartridge : runs an already loaded cartridge until the status byte at
+// $6000 leaves $80, pressing reset whenever the ROM asks for it with $81.
+// The ROM fails with an error if it does not finish within timeout CPU cycles
+// and is skipped when its mapper is not supported
+func RunTestCartridge(name, path string, cart *Cartridge, timeout int) (result TestROMResult) {
+	result = TestROMResult{Name: name, Path: path, Status: blarggRunning}
+	start := time.Now()
+
+	bus := CreateBus(CreateCPU(), CreatePPU())
+	defer func() {
+		if r := recover(); r != nil {
+			result.Error = fmt.Sprint("emulator panic: ", r)
+		}
+		switch {
+		case result.Outcome == TestROMSkipped:
+		case result.Error != "":
+			result.Outcome = TestROMError
+		case result.Passed:
+			result.Outcome = TestROMPassed
+		default:
+			result.Outcome = TestROMFailed
+		}
+		result.Cycles = bus.cpu.clockCount
+		result.Duration = time.Since(start).Seconds()
+	}()
+
+	if !cart.MapperSupported() {
+		result.Outcome = TestROMSkipped
+		result.Text = fmt.Sprintf("mapper %d is not supported", cart.mapperID)
+		return result
+	}
+
+	bus.InsertCartridge(cart)
+	bus.Reset()
+
+	running := false
+	resetAt := -1
+	for bus.cpu.clockCount < timeout {
+		bus.ExecuteOperation()
+
+		if !bus.hasBlarggSignature() {
+			continue
+		}
+
+		status, _ := bus.CPURead(blarggStatus, true)
+		switch {
+		case status == blarggRunning:
+			running = true
+			continue
+		case status == blarggResetRequested:
+			running = true
+			if resetAt < 0 {
+				resetAt = bus.cpu.clockCount + blarggResetDelay
+			} else if bus.cpu.clockCount >= resetAt {
+				bus.Reset()
+				result.Resets++
+				resetAt = -1
+			}
+			continue
+		case !running:
+			// the status byte is only meaningful after the test has started
+			continue
+		}
+
+		result.Status = status
+		result.Passed = status == 0x00
+		result.Text = bus.blarggText()
+		return result
+	}
+
+	result.Text = bus.blarggText()
+	result.Error = "timed out waiting for the test to finish"
+	return result
+}
+
+// RunTestROMDirectory : runs every .nes file inside a directory in name order
+func RunTestROMDirectory(dir string) ([]TestROMResult, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, f := range files {
+		if !f.IsDir() && strings.EqualFold(filepath.Ext(f.Name()), ".nes") {
+			paths = append(paths, filepath.Join(dir, f.Name()))
+		}
+	}
+	sort.Strings(paths)
+
+	results := make([]TestROMResult, 0, len(paths))
+	for _, path := range paths {
+		results = append(results, RunTestROM(path))
+	}
+	return results, nil
+}
+
+// hasBlarggSignature : checks if the ROM has written the DE B0 61 marker
+func (b *Bus) hasBlarggSignature() bool {
+	for i, s := range blarggSignature {
+		if d, _ := b.CPURead(blarggStatus+1+Word(i), true); d != s {
+			return false
+		}
+	}
+	return true
+}
+
+// blarggText : reads the zero terminated text written after the signature
+func (b *Bus) blarggText() string {
+	var text strings.Builder
+	for address := blarggText; address <= 0x7FFF; address++ {
+		d, _ := b.CPURead(address, true)
+		if d == 0x00 {
+			break
+		}
+		text.WriteByte(d)
+	}
+	return strings.TrimSpace(text.String())
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+// WriteJUnitReport : writes the results as a JUnit XML test suite
+func WriteJUnitReport(w io.Writer, suite string, results []TestROMResult) error {
+	report := junitTestSuite{Name: suite, Tests: len(results)}
+	total := 0.0
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      r.Name,
+			ClassName: suite,
+			Time:      fmt.Sprintf("%.3f", r.Duration),
+			SystemOut: r.Text,
+		}
+		if r.Outcome == TestROMSkipped {
+			tc.Skipped = &junitSkipped{r.Text}
+			report.Skipped++
+		} else if r.Error != "" {
+			tc.Error = &junitFailure{r.Error, r.Text}
+			report.Errors++
+		} else if !r.Passed {
+			tc.Failure = &junitFailure{fmt.Sprintf("status $%s", Hex(uint32(r.Status), 2)), r.Text}
+			report.Failures++
+		}
+		total += r.Duration
+		report.TestCases = append(report.TestCases, tc)
+	}
+	report.Time = fmt.Sprintf("%.3f", total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	return e.Encode(report)
+}
+
+// WriteJSONReport : writes the results as a JSON array
+func WriteJSONReport(w io.Writer, results []TestROMResult) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(results)
+}
@@ -0,0 +1,171 @@
+package nes
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// blarggROMs : directory holding the community test ROMs, it can be changed
+// with the GONES_TEST_ROMS environment variable
+func blarggROMs() string {
+	if dir := os.Getenv("GONES_TEST_ROMS"); dir != "" {
+		return dir
+	}
+	return "../test/roms/blargg"
+}
+
+func TestBlarggROMs(t *testing.T) {
+	dir := blarggROMs()
+	if _, err := os.Stat(dir); err != nil {
+		t.Skipf("%s not found", dir)
+	}
+
+	results, err := RunTestROMDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		r := r
+		t.Run(r.Name, func(t *testing.T) {
+			if r.Outcome == TestROMSkipped {
+				t.Skip(r.Text)
+			}
+			if r.Error != "" {
+				t.Fatalf("%s: %s", r.Error, r.Text)
+			}
+			if !r.Passed {
+				t.Fatalf("status $%s: %s", Hex(uint32(r.Status), 2), r.Text)
+			}
+		})
+	}
+}
+
+func TestBlarggProtocolPass(t *testing.T) {
+	// LDA #$80, STA $6000, signature DE B0 61, text "OK", LDA #$00, STA $6000, JMP *
+	rom := "A9 80 8D 00 60 A9 DE 8D 01 60 A9 B0 8D 02 60 A9 61 8D 03 60 " +
+		"A9 4F 8D 04 60 A9 4B 8D 05 60 A9 00 8D 06 60 A9 00 8D 00 60 4C 28 80"
+
+	r := RunTestCartridge("pass", "", TestCartridge(rom, 0x8000), TestROMTimeout)
+
+	assertTrue(t, r.Error == "")
+	assertTrue(t, r.Passed)
+	assertTrue(t, r.Outcome == TestROMPassed)
+	assertEqualsB(t, 0x00, r.Status)
+	if r.Text != "OK" {
+		t.Errorf("Expected: %q, got: %q", "OK", r.Text)
+	}
+}
+
+func TestBlarggProtocolFail(t *testing.T) {
+	// LDA #$80, STA $6000, signature DE B0 61, text "3", status $03, JMP *
+	rom := "A9 80 8D 00 60 A9 DE 8D 01 60 A9 B0 8D 02 60 A9 61 8D 03 60 " +
+		"A9 33 8D 04 60 A9 00 8D 05 60 A9 03 8D 00 60 4C 23 80"
+
+	r := RunTestCartridge("fail", "", TestCartridge(rom, 0x8000), TestROMTimeout)
+
+	assertTrue(t, r.Error == "")
+	assertFalse(t, r.Passed)
+	assertTrue(t, r.Outcome == TestROMFailed)
+	assertEqualsB(t, 0x03, r.Status)
+	if r.Text != "3" {
+		t.Errorf("Expected: %q, got: %q", "3", r.Text)
+	}
+}
+
+func TestBlarggProtocolResetRequest(t *testing.T) {
+	// First run sets $6010 and asks for a reset with $81, the run after the
+	// reset sees $6010 set and reports success
+	rom := asm.MustAssemble(`
+		.org $8000
+		        LDA $6010
+		        BNE passed
+		        LDA #$01
+		        STA $6010
+		        LDA #$DE
+		        STA $6001
+		        LDA #$B0
+		        STA $6002
+		        LDA #$61
+		        STA $6003
+		        LDA #$81
+		        STA $6000
+		hang:   JMP hang
+		passed: LDA #$00
+		        STA $6004
+		        STA $6000
+		done:   JMP done
+	`)
+
+	r := RunTestCartridge("reset", "", TestCartridge(rom.Hex(), 0x8000), TestROMTimeout)
+
+	assertTrue(t, r.Error == "")
+	assertTrue(t, r.Passed)
+	if r.Resets != 1 {
+		t.Errorf("Expected: %d resets, got: %d", 1, r.Resets)
+	}
+	assertTrue(t, r.Cycles >= blarggResetDelay)
+}
+
+func TestBlarggProtocolTimeout(t *testing.T) {
+	r := RunTestCartridge("timeout", "", TestCartridge("4C 00 80", 0x8000), cpuClockRate)
+
+	assertFalse(t, r.Passed)
+	assertTrue(t, r.Error != "")
+	assertTrue(t, r.Outcome == TestROMError)
+}
+
+func TestBlarggReports(t *testing.T) {
+	results := []TestROMResult{
+		{Name: "cpu_timing", Passed: true, Text: "Passed"},
+		{Name: "ppu_vbl_nmi", Status: 0x02, Text: "Failed #2"},
+	}
+
+	var junit bytes.Buffer
+	assertNil(t, WriteJUnitReport(&junit, "blargg", results))
+	if !strings.Contains(junit.String(), `<testsuite name="blargg" tests="2" failures="1" errors="0"`) {
+		t.Errorf("unexpected JUnit report:\n%s", junit.String())
+	}
+	if !strings.Contains(junit.String(), `<failure message="status $02">Failed #2</failure>`) {
+		t.Errorf("unexpected JUnit report:\n%s", junit.String())
+	}
+
+	var report bytes.Buffer
+	assertNil(t, WriteJSONReport(&report, results))
+	var decoded []TestROMResult
+	assertNil(t, json.Unmarshal(report.Bytes(), &decoded))
+	if len(decoded) != 2 || decoded[1].Text != "Failed #2" {
+		t.Errorf("unexpected JSON report:\n%s", report.String())
+	}
+	// the status byte keeps its key, the outcome has its own
+	if !strings.Contains(report.String(), `"status": 2,`) {
+		t.Errorf("unexpected JSON report:\n%s", report.String())
+	}
+}
+
+func TestBlarggUnsupportedMapper(t *testing.T) {
+	cart := TestCartridge("4C 00 80", 0x8000)
+	cart.mapperID = 4
+	r := RunTestCartridge("mmc3", "", cart, cpuClockRate)
+
+	assertTrue(t, r.Outcome == TestROMSkipped)
+	assertTrue(t, r.Error == "")
+	assertFalse(t, r.Passed)
+
+	var junit bytes.Buffer
+	assertNil(t, WriteJUnitReport(&junit, "blargg", []TestROMResult{r}))
+	if !strings.Contains(junit.String(), `tests="1" failures="0" errors="0" skipped="1"`) ||
+		!strings.Contains(junit.String(), `<skipped message="mapper 4 is not supported"></skipped>`) {
+		t.Errorf("unexpected JUnit report:\n%s", junit.String())
+	}
+
+	var report bytes.Buffer
+	assertNil(t, WriteJSONReport(&report, []TestROMResult{r}))
+	if !strings.Contains(report.String(), `"outcome": "skipped"`) {
+		t.Errorf("unexpected JSON report:\n%s", report.String())
+	}
+}
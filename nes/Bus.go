@@ -0,0 +1,321 @@
+package nes
+
+// Bus Databus and things connected to it
+type Bus struct {
+	cpu  *CPU6502
+	ppu  *PPU2C02
+	cart *Cartridge
+	ram  [2 * 1024]byte
+
+	// clockCount : counts total clocks so far
+	clockCount int
+	// operationCount : number of operations executed
+	operationCount int
+	// dataBus : last value on the CPU data bus, reading an address nothing
+	// drives returns it again
+	dataBus byte
+	// tvSystem : sets how many PPU dots run for each CPU cycle
+	tvSystem TVSystem
+
+	controller [2]controllerPort
+
+	// OAM DMA, a write to $4014 copies a page of CPU memory to the OAM while
+	// the CPU waits
+	dmaPage     byte
+	dmaAddr     byte
+	dmaData     byte
+	dmaDummy    bool
+	dmaTransfer bool
+
+	// memory : the devices answering the CPU
+	memory MemoryMap
+	// genie : Game Genie codes patching the reads from the cartridge
+	genie GameGenie
+	// freezes : addresses written again after every frame
+	freezes []Freeze
+
+	// lag frames, the ones where the game never read the controllers
+	controllerRead bool
+	lagged         bool
+	lagCount       int
+}
+
+// CreateBus : creates a new bus
+func CreateBus(cpu *CPU6502, ppu *PPU2C02) *Bus {
+	bus := &Bus{cpu, ppu, nil, [2 * 1024]byte{}, 0, 0, 0, NTSC, [2]controllerPort{}, 0, 0, 0, true, false, MemoryMap{}, GameGenie{}, nil, false, false, 0}
+	cpu.ConnectBus(bus)
+	ppu.ConnectBus(bus)
+	bus.registerDevices()
+	return bus
+}
+
+// registerDevices : puts the hardware of the console on the memory map, the
+// cartridge last so its mapper sees every access to its range first
+func (b *Bus) registerDevices() {
+	b.memory.Register("RAM", 0x0000, 0x1FFF,
+		func(address Word, readOnly bool) (byte, bool, error) {
+			return b.ram[address&0x07FF], true, nil
+		},
+		func(address Word, data byte) (bool, error) {
+			b.ram[address&0x07FF] = data
+			return true, nil
+		})
+	b.memory.Register("PPU registers", 0x2000, 0x3FFF,
+		func(address Word, readOnly bool) (byte, bool, error) {
+			d, err := b.ppu.CPURead(address&0x0007, readOnly)
+			return d, true, err
+		},
+		func(address Word, data byte) (bool, error) {
+			return true, b.ppu.CPUWrite(address&0x0007, data)
+		})
+	b.memory.Register("OAM DMA", 0x4014, 0x4014, nil,
+		func(address Word, data byte) (bool, error) {
+			b.dmaPage = data
+			b.dmaAddr = 0x00
+			b.dmaTransfer = true
+			return true, nil
+		})
+	b.memory.Register("Controllers", 0x4016, 0x4017,
+		func(address Word, readOnly bool) (byte, bool, error) {
+			if !readOnly {
+				b.controllerRead = true
+			}
+			// the controllers only drive the low bits
+			return b.dataBus&0xE0 | b.controller[address&0x0001].read(readOnly), true, nil
+		},
+		func(address Word, data byte) (bool, error) {
+			if address != 0x4016 {
+				// $4017 belongs to the APU
+				return false, nil
+			}
+			// the strobe reaches both controllers
+			b.controller[0].write(data)
+			b.controller[1].write(data)
+			return true, nil
+		})
+	// without a cartridge SetCodeEntry still leaves the reset vector in RAM
+	b.memory.Register("Reset vector", 0xFFFC, 0xFFFD, nil,
+		func(address Word, data byte) (bool, error) {
+			b.ram[address&0x07FF] = data
+			return true, nil
+		})
+	b.memory.Register("Cartridge", 0x4020, 0xFFFF,
+		func(address Word, readOnly bool) (byte, bool, error) {
+			d, ok := b.CartCPURead(address)
+			return d, ok, nil
+		},
+		func(address Word, data byte) (bool, error) {
+			return b.CartCPUWrite(address, data), nil
+		})
+}
+
+// MemoryMap : the devices on the CPU bus, to add hardware or a layer over the
+// existing one
+func (b *Bus) MemoryMap() *MemoryMap {
+	return &b.memory
+}
+
+// PreLoadMemory : inserts data into memory using string format
+// inserted data must be in hexadecimal writen as a string
+// and they may have space after each 2 bytes
+// example : "A9 0F 8D 15 40 60" or "A90F8D154060"
+func (b *Bus) PreLoadMemory(offset Word, data string) {
+	nOffset := offset
+	for i := 0; i < len(data); i += 2 {
+		b.CPUWrite(nOffset, ByteToHex(data[i])<<4|ByteToHex(data[i+1]))
+		if i+2 < len(data) && data[i+2] == byte(' ') {
+			i++
+		}
+		nOffset++
+	}
+}
+
+// SetCodeEntry : Set the address that starts your program
+func (b *Bus) SetCodeEntry(address Word) {
+	b.CPUWrite(0xFFFC, byte(address))
+	b.CPUWrite(0xFFFD, byte(address>>8))
+}
+
+// CPURead : allow the reading of data by the CPU, open bus when nothing
+// answers the address
+func (b *Bus) CPURead(address Word, readOnly bool) (byte, error) {
+	d, ok, e := b.memory.Read(address, readOnly)
+	if !ok {
+		d = b.dataBus
+	}
+	if !readOnly {
+		b.dataBus = d
+	}
+	return d, e
+}
+
+// CartCPURead : allows the read of CPU data on the cart, with the Game Genie
+// codes applied
+func (b *Bus) CartCPURead(address Word) (byte, bool) {
+	if b.cart != nil {
+		d, ok := b.cart.CPURead(address)
+		if ok && address >= 0x8000 {
+			d = b.genie.apply(address, d)
+		}
+		return d, ok
+	}
+	return 0, false
+}
+
+// CartCPUWrite : write data on the cart from the CPU
+func (b *Bus) CartCPUWrite(address Word, data byte) bool {
+	if b.cart != nil {
+		return b.cart.CPUWrite(address, data)
+	}
+	return false
+}
+
+// CPUWrite : write data from the CPU
+func (b *Bus) CPUWrite(address Word, data byte) error {
+	b.dataBus = data
+	_, e := b.memory.Write(address, data)
+	return e
+}
+
+// SetController : sets the buttons held on the controller in port 0 or 1
+func (b *Bus) SetController(port int, buttons Buttons) {
+	b.controller[port].buttons = buttons
+}
+
+// SetTVSystem : changes the timing of the CPU and the PPU
+func (b *Bus) SetTVSystem(r TVSystem) {
+	b.tvSystem = r
+	b.ppu.tvSystem = r
+}
+
+// TVSystem : the timing the bus runs with
+func (b *Bus) TVSystem() TVSystem {
+	return b.tvSystem
+}
+
+// cpuClockDue : true when the next clock of the bus also clocks the CPU, every
+// 3rd dot on NTSC and Dendy and 5 dots out of 16 on PAL
+func (b *Bus) cpuClockDue() bool {
+	t := b.tvSystem.timing()
+	return b.clockCount*t.cpuClocks%t.ppuClocks < t.cpuClocks
+}
+
+// Clock : Bus clock implementation pulses the clock to all things attached to it
+func (b *Bus) Clock() {
+
+	frame := b.ppu.frameCount
+	b.ppu.Clock()
+	if b.ppu.frameCount != frame {
+		b.endFrame()
+	}
+
+	if b.cpuClockDue() {
+		if b.dmaTransfer {
+			b.clockDMA()
+		} else {
+			b.cpu.Clock()
+		}
+	}
+
+	// the CPU takes the NMI between instructions
+	if b.ppu.NonMaskableInterrupt && b.cpu.Complete() && !b.dmaTransfer {
+		b.ppu.NonMaskableInterrupt = false
+		b.cpu.NonMaskableInterruptRequest()
+	}
+
+	b.clockCount++
+}
+
+// endFrame : counts the frame as lag when the controllers were not read
+// during it and writes the frozen addresses
+func (b *Bus) endFrame() {
+	b.lagged = !b.controllerRead
+	if b.lagged {
+		b.lagCount++
+	}
+	b.controllerRead = false
+	if b.freezes != nil {
+		b.applyFreezes()
+	}
+}
+
+// clockDMA : a CPU cycle of the OAM DMA, it waits for an even cycle and then
+// alternates reading a byte and writing it to the OAM, 513 or 514 cycles in all
+func (b *Bus) clockDMA() {
+	b.cpu.clockCount++
+	if b.dmaDummy {
+		if b.cpu.clockCount%2 == 1 {
+			b.dmaDummy = false
+		}
+		return
+	}
+	if b.cpu.clockCount%2 == 0 {
+		b.dmaData, _ = b.CPURead(Word(b.dmaPage)<<8|Word(b.dmaAddr), false)
+		return
+	}
+	b.ppu.oam[b.ppu.oamAddr] = b.dmaData
+	b.ppu.oamAddr++
+	b.dmaAddr++
+	if b.dmaAddr == 0x00 {
+		b.dmaTransfer = false
+		b.dmaDummy = true
+	}
+}
+
+// ExecuteOperation : This function clocks the bus until a function is executed completely
+// and the next clock of the bus is also a clock of the CPU
+func (b *Bus) ExecuteOperation() {
+	for b.cpu.Complete() {
+		b.Clock()
+	}
+	for !b.cpu.Complete() || !b.cpuClockDue() {
+		b.Clock()
+	}
+}
+
+// ExecuteFrame : clocks the bus until the PPU finishes drawing the current frame
+func (b *Bus) ExecuteFrame() {
+	for !b.ppu.Complete() {
+		b.Clock()
+	}
+	b.ppu.frameComplete = false
+}
+
+// Reset : resets the whole system
+func (b *Bus) Reset() {
+	b.cpu.Reset()
+	b.ppu.Reset()
+	b.cart.Reset()
+	b.operationCount = 0
+	b.clockCount = 0
+	b.dmaTransfer = false
+	b.dmaDummy = true
+}
+
+// PowerCycle : turns the system off and on, the RAM and the chips lose what
+// they held, the palette and the timing stay as they were set
+func (b *Bus) PowerCycle() {
+	palette, tvSystem := b.ppu.palette, b.ppu.tvSystem
+	*b.cpu = *CreateCPU()
+	*b.ppu = *CreatePPU()
+	b.cpu.ConnectBus(b)
+	b.ppu.ConnectBus(b)
+	b.ppu.InsertCartridge(b.cart)
+	b.ppu.palette, b.ppu.tvSystem = palette, tvSystem
+	b.ram = [2 * 1024]byte{}
+	b.dataBus = 0
+	b.controllerRead, b.lagged, b.lagCount = false, false, 0
+	for i := range b.controller {
+		b.controller[i] = controllerPort{buttons: b.controller[i].buttons}
+	}
+	b.Reset()
+}
+
+// InsertCartridge : sets the ROM to the appropriate memory position for the PPU and Bus
+func (b *Bus) InsertCartridge(c *Cartridge) {
+	b.cart = c
+	// the codes and the freezes belong to the game that was in
+	b.genie = GameGenie{}
+	b.freezes = nil
+	b.ppu.InsertCartridge(c)
+}
@@ -0,0 +1,61 @@
+package nes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+func TestOpenBusReads(t *testing.T) {
+	program := asm.MustAssemble(`
+		.org $8000
+reset:  LDA $5000       ; nothing there, the $50 of the operand is left
+        STA $10
+        LDA $4016       ; only the low bits come from the controller
+        STA $11
+@loop:  JMP @loop
+`)
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(TestCartridge(program.Hex(), 0x8000))
+	nes.Reset()
+	for i := 0; i < 6; i++ {
+		nes.ExecuteOperation()
+	}
+	assertEqualsB(t, 0x50, nes.ram[0x10])
+	assertEqualsB(t, 0x40, nes.ram[0x11]&0xE0)
+
+	// a write drives the bus too, peeking at it does not
+	nes.CPUWrite(0x0000, 0xA5)
+	d, _ := nes.CPURead(0x4100, true)
+	assertEqualsB(t, 0xA5, d)
+	nes.CPURead(0x0010, true)
+	d, _ = nes.CPURead(0x4100, false)
+	assertEqualsB(t, 0xA5, d)
+}
+
+func TestCartridgeWithoutPRGRAM(t *testing.T) {
+	for _, c := range []struct {
+		flags7, prgRAM byte
+		size           int
+	}{
+		{0x00, 0x00, 8192},
+		{0x08, 0x00, 0},
+		{0x08, 0x07, 8192},
+		{0x08, 0x75, 10240},
+	} {
+		header := []byte{'N', 'E', 'S', 0x1A, 1, 1, 0, c.flags7, 0, 0, c.prgRAM, 0, 0, 0, 0, 0}
+		cart, err := ReadCartridge(bytes.NewReader(append(header, make([]byte, 16384+8192)...)))
+		assertNil(t, err)
+		assertTrue(t, len(cart.PRGRam) == c.size)
+	}
+
+	header := []byte{'N', 'E', 'S', 0x1A, 1, 1, 0, 0x08, 0, 0, 0, 0, 0, 0, 0, 0}
+	cart, _ := ReadCartridge(bytes.NewReader(append(header, make([]byte, 16384+8192)...)))
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(cart)
+	nes.CPUWrite(0x6000, 0x12)
+	nes.CPUWrite(0x0000, 0x34)
+	d, _ := nes.CPURead(0x6000, false)
+	assertEqualsB(t, 0x34, d)
+}
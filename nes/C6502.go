@@ -1,9 +1,4 @@
-package main
-
-import (
-	"bytes"
-	"log"
-)
+package nes
 
 const (
 	// C : Carry flag
@@ -26,36 +21,39 @@ const (
 	Stack = Word(0x0100)
 )
 
-var (
-	// OpCodesLookupTable : table with all the instructions of the 6502
-	OpCodesLookupTable []Instruction
-)
+// opCodes : the instructions of the 6502 by opcode, filled once at start up
+// and only read afterwards
+var opCodes [256]Instruction
 
 // CPU6502 : Struct that represents the 6502 chip
 type CPU6502 struct {
 	a, x, y, stkp, status, fetched, opcode, cycles byte
 	pc, addressAbs, addressRel                     Word
-	bus                                            *Bus
+	clockCount                                     int
+	// jammed : a KIL opcode halted the CPU, only a reset brings it back
+	jammed bool
+	bus    *Bus
 }
 
 func init() {
-	OpCodesLookupTable = []Instruction{
-		{"BRK", BRK, IMM, 7}, {"ORA", ORA, IZX, 6}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 3}, {"ORA", ORA, ZP0, 3}, {"ASL", ASL, ZP0, 5}, {"???", XXX, IMP, 5}, {"PHP", PHP, IMP, 3}, {"ORA", ORA, IMM, 2}, {"ASL", ASL, IMP, 2}, {"ANC", PAN, IMM, 2}, {"???", NOP, IMP, 4}, {"ORA", ORA, ABS, 4}, {"ASL", ASL, ABS, 6}, {"???", XXX, IMP, 6},
-		{"BPL", BPL, REL, 2}, {"ORA", ORA, IZY, 5}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 4}, {"ORA", ORA, ZPX, 4}, {"ASL", ASL, ZPX, 6}, {"???", XXX, IMP, 6}, {"CLC", CLC, IMP, 2}, {"ORA", ORA, ABY, 4}, {"???", NOP, IMP, 2}, {"???", XXX, IMP, 7}, {"???", NOP, IMP, 4}, {"ORA", ORA, ABX, 4}, {"ASL", ASL, ABX, 7}, {"???", XXX, IMP, 7},
-		{"JSR", JSR, ABS, 6}, {"AND", AND, IZX, 6}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"BIT", BIT, ZP0, 3}, {"AND", AND, ZP0, 3}, {"ROL", ROL, ZP0, 5}, {"???", XXX, IMP, 5}, {"PLP", PLP, IMP, 4}, {"AND", AND, IMM, 2}, {"ROL", ROL, IMP, 2}, {"???", XXX, IMP, 2}, {"BIT", BIT, ABS, 4}, {"AND", AND, ABS, 4}, {"ROL", ROL, ABS, 6}, {"???", XXX, IMP, 6},
-		{"BMI", BMI, REL, 2}, {"AND", AND, IZY, 5}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 4}, {"AND", AND, ZPX, 4}, {"ROL", ROL, ZPX, 6}, {"???", XXX, IMP, 6}, {"SEC", SEC, IMP, 2}, {"AND", AND, ABY, 4}, {"???", NOP, IMP, 2}, {"???", XXX, IMP, 7}, {"???", NOP, IMP, 4}, {"AND", AND, ABX, 4}, {"ROL", ROL, ABX, 7}, {"???", XXX, IMP, 7},
-		{"RTI", RTI, IMP, 6}, {"EOR", EOR, IZX, 6}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 3}, {"EOR", EOR, ZP0, 3}, {"LSR", LSR, ZP0, 5}, {"???", XXX, IMP, 5}, {"PHA", PHA, IMP, 3}, {"EOR", EOR, IMM, 2}, {"LSR", LSR, IMP, 2}, {"???", XXX, IMP, 2}, {"JMP", JMP, ABS, 3}, {"EOR", EOR, ABS, 4}, {"LSR", LSR, ABS, 6}, {"???", XXX, IMP, 6},
-		{"BVC", BVC, REL, 2}, {"EOR", EOR, IZY, 5}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 4}, {"EOR", EOR, ZPX, 4}, {"LSR", LSR, ZPX, 6}, {"???", XXX, IMP, 6}, {"CLI", CLI, IMP, 2}, {"EOR", EOR, ABY, 4}, {"???", NOP, IMP, 2}, {"???", XXX, IMP, 7}, {"???", NOP, IMP, 4}, {"EOR", EOR, ABX, 4}, {"LSR", LSR, ABX, 7}, {"???", XXX, IMP, 7},
-		{"RTS", RTS, IMP, 6}, {"ADC", ADC, IZX, 6}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 3}, {"ADC", ADC, ZP0, 3}, {"ROR", ROR, ZP0, 5}, {"???", XXX, IMP, 5}, {"PLA", PLA, IMP, 4}, {"ADC", ADC, IMM, 2}, {"ROR", ROR, IMP, 2}, {"???", XXX, IMP, 2}, {"JMP", JMP, IND, 5}, {"ADC", ADC, ABS, 4}, {"ROR", ROR, ABS, 6}, {"???", XXX, IMP, 6},
-		{"BVS", BVS, REL, 2}, {"ADC", ADC, IZY, 5}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 4}, {"ADC", ADC, ZPX, 4}, {"ROR", ROR, ZPX, 6}, {"???", XXX, IMP, 6}, {"SEI", SEI, IMP, 2}, {"ADC", ADC, ABY, 4}, {"???", NOP, IMP, 2}, {"???", XXX, IMP, 7}, {"???", NOP, IMP, 4}, {"ADC", ADC, ABX, 4}, {"ROR", ROR, ABX, 7}, {"???", XXX, IMP, 7},
-		{"???", NOP, IMP, 2}, {"STA", STA, IZX, 6}, {"???", NOP, IMP, 2}, {"???", XXX, IMP, 6}, {"STY", STY, ZP0, 3}, {"STA", STA, ZP0, 3}, {"STX", STX, ZP0, 3}, {"???", XXX, IMP, 3}, {"DEY", DEY, IMP, 2}, {"???", NOP, IMP, 2}, {"TXA", TXA, IMP, 2}, {"???", XXX, IMP, 2}, {"STY", STY, ABS, 4}, {"STA", STA, ABS, 4}, {"STX", STX, ABS, 4}, {"???", XXX, IMP, 4},
-		{"BCC", BCC, REL, 2}, {"STA", STA, IZY, 6}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 6}, {"STY", STY, ZPX, 4}, {"STA", STA, ZPX, 4}, {"STX", STX, ZPY, 4}, {"???", XXX, IMP, 4}, {"TYA", TYA, IMP, 2}, {"STA", STA, ABY, 5}, {"TXS", TXS, IMP, 2}, {"???", XXX, IMP, 5}, {"???", NOP, IMP, 5}, {"STA", STA, ABX, 5}, {"???", XXX, IMP, 5}, {"???", XXX, IMP, 5},
-		{"LDY", LDY, IMM, 2}, {"LDA", LDA, IZX, 6}, {"LDX", LDX, IMM, 2}, {"???", XXX, IMP, 6}, {"LDY", LDY, ZP0, 3}, {"LDA", LDA, ZP0, 3}, {"LDX", LDX, ZP0, 3}, {"???", XXX, IMP, 3}, {"TAY", TAY, IMP, 2}, {"LDA", LDA, IMM, 2}, {"TAX", TAX, IMP, 2}, {"???", XXX, IMP, 2}, {"LDY", LDY, ABS, 4}, {"LDA", LDA, ABS, 4}, {"LDX", LDX, ABS, 4}, {"???", XXX, IMP, 4},
-		{"BCS", BCS, REL, 2}, {"LDA", LDA, IZY, 5}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 5}, {"LDY", LDY, ZPX, 4}, {"LDA", LDA, ZPX, 4}, {"LDX", LDX, ZPY, 4}, {"???", XXX, IMP, 4}, {"CLV", CLV, IMP, 2}, {"LDA", LDA, ABY, 4}, {"TSX", TSX, IMP, 2}, {"???", XXX, IMP, 4}, {"LDY", LDY, ABX, 4}, {"LDA", LDA, ABX, 4}, {"LDX", LDX, ABY, 4}, {"???", XXX, IMP, 4},
-		{"CPY", CPY, IMM, 2}, {"CMP", CMP, IZX, 6}, {"???", NOP, IMP, 2}, {"???", XXX, IMP, 8}, {"CPY", CPY, ZP0, 3}, {"CMP", CMP, ZP0, 3}, {"DEC", DEC, ZP0, 5}, {"???", XXX, IMP, 5}, {"INY", INY, IMP, 2}, {"CMP", CMP, IMM, 2}, {"DEX", DEX, IMP, 2}, {"???", XXX, IMP, 2}, {"CPY", CPY, ABS, 4}, {"CMP", CMP, ABS, 4}, {"DEC", DEC, ABS, 6}, {"???", XXX, IMP, 6},
-		{"BNE", BNE, REL, 2}, {"CMP", CMP, IZY, 5}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 4}, {"CMP", CMP, ZPX, 4}, {"DEC", DEC, ZPX, 6}, {"???", XXX, IMP, 6}, {"CLD", CLD, IMP, 2}, {"CMP", CMP, ABY, 4}, {"NOP", NOP, IMP, 2}, {"???", XXX, IMP, 7}, {"???", NOP, IMP, 4}, {"CMP", CMP, ABX, 4}, {"DEC", DEC, ABX, 7}, {"???", XXX, IMP, 7},
-		{"CPX", CPX, IMM, 2}, {"SBC", SBC, IZX, 6}, {"???", NOP, IMP, 2}, {"???", XXX, IMP, 8}, {"CPX", CPX, ZP0, 3}, {"SBC", SBC, ZP0, 3}, {"INC", INC, ZP0, 5}, {"???", XXX, IMP, 5}, {"INX", INX, IMP, 2}, {"SBC", SBC, IMM, 2}, {"NOP", NOP, IMP, 2}, {"???", SBC, IMP, 2}, {"CPX", CPX, ABS, 4}, {"SBC", SBC, ABS, 4}, {"INC", INC, ABS, 6}, {"???", XXX, IMP, 6},
-		{"BEQ", BEQ, REL, 2}, {"SBC", SBC, IZY, 5}, {"???", XXX, IMP, 2}, {"???", XXX, IMP, 8}, {"???", NOP, IMP, 4}, {"SBC", SBC, ZPX, 4}, {"INC", INC, ZPX, 6}, {"???", XXX, IMP, 6}, {"SED", SED, IMP, 2}, {"SBC", SBC, ABY, 4}, {"NOP", NOP, IMP, 2}, {"???", XXX, IMP, 7}, {"???", NOP, IMP, 4}, {"SBC", SBC, ABX, 4}, {"INC", INC, ABX, 7}, {"???", XXX, IMP, 7}}
+	opCodes = [256]Instruction{
+		{"BRK", BRK, ModeIMM, 7}, {"ORA", ORA, ModeIZX, 6}, {"*KIL", KIL, ModeIMP, 2}, {"*SLO", SLO, ModeIZX, 8}, {"*NOP", NOP, ModeZP0, 3}, {"ORA", ORA, ModeZP0, 3}, {"ASL", ASL, ModeZP0, 5}, {"*SLO", SLO, ModeZP0, 5}, {"PHP", PHP, ModeIMP, 3}, {"ORA", ORA, ModeIMM, 2}, {"ASL", ASL, ModeACC, 2}, {"*ANC", ANC, ModeIMM, 2}, {"*NOP", NOP, ModeABS, 4}, {"ORA", ORA, ModeABS, 4}, {"ASL", ASL, ModeABS, 6}, {"*SLO", SLO, ModeABS, 6},
+		{"BPL", BPL, ModeREL, 2}, {"ORA", ORA, ModeIZY, 5}, {"*KIL", KIL, ModeIMP, 2}, {"*SLO", SLO, ModeIZY, 8}, {"*NOP", NOP, ModeZPX, 4}, {"ORA", ORA, ModeZPX, 4}, {"ASL", ASL, ModeZPX, 6}, {"*SLO", SLO, ModeZPX, 6}, {"CLC", CLC, ModeIMP, 2}, {"ORA", ORA, ModeABY, 4}, {"*NOP", NOP, ModeIMP, 2}, {"*SLO", SLO, ModeABY, 7}, {"*NOP", NOP, ModeABX, 4}, {"ORA", ORA, ModeABX, 4}, {"ASL", ASL, ModeABX, 7}, {"*SLO", SLO, ModeABX, 7},
+		{"JSR", JSR, ModeABS, 6}, {"AND", AND, ModeIZX, 6}, {"*KIL", KIL, ModeIMP, 2}, {"*RLA", RLA, ModeIZX, 8}, {"BIT", BIT, ModeZP0, 3}, {"AND", AND, ModeZP0, 3}, {"ROL", ROL, ModeZP0, 5}, {"*RLA", RLA, ModeZP0, 5}, {"PLP", PLP, ModeIMP, 4}, {"AND", AND, ModeIMM, 2}, {"ROL", ROL, ModeACC, 2}, {"*ANC", ANC, ModeIMM, 2}, {"BIT", BIT, ModeABS, 4}, {"AND", AND, ModeABS, 4}, {"ROL", ROL, ModeABS, 6}, {"*RLA", RLA, ModeABS, 6},
+		{"BMI", BMI, ModeREL, 2}, {"AND", AND, ModeIZY, 5}, {"*KIL", KIL, ModeIMP, 2}, {"*RLA", RLA, ModeIZY, 8}, {"*NOP", NOP, ModeZPX, 4}, {"AND", AND, ModeZPX, 4}, {"ROL", ROL, ModeZPX, 6}, {"*RLA", RLA, ModeZPX, 6}, {"SEC", SEC, ModeIMP, 2}, {"AND", AND, ModeABY, 4}, {"*NOP", NOP, ModeIMP, 2}, {"*RLA", RLA, ModeABY, 7}, {"*NOP", NOP, ModeABX, 4}, {"AND", AND, ModeABX, 4}, {"ROL", ROL, ModeABX, 7}, {"*RLA", RLA, ModeABX, 7},
+		{"RTI", RTI, ModeIMP, 6}, {"EOR", EOR, ModeIZX, 6}, {"*KIL", KIL, ModeIMP, 2}, {"*SRE", SRE, ModeIZX, 8}, {"*NOP", NOP, ModeZP0, 3}, {"EOR", EOR, ModeZP0, 3}, {"LSR", LSR, ModeZP0, 5}, {"*SRE", SRE, ModeZP0, 5}, {"PHA", PHA, ModeIMP, 3}, {"EOR", EOR, ModeIMM, 2}, {"LSR", LSR, ModeACC, 2}, {"*ALR", ALR, ModeIMM, 2}, {"JMP", JMP, ModeABS, 3}, {"EOR", EOR, ModeABS, 4}, {"LSR", LSR, ModeABS, 6}, {"*SRE", SRE, ModeABS, 6},
+		{"BVC", BVC, ModeREL, 2}, {"EOR", EOR, ModeIZY, 5}, {"*KIL", KIL, ModeIMP, 2}, {"*SRE", SRE, ModeIZY, 8}, {"*NOP", NOP, ModeZPX, 4}, {"EOR", EOR, ModeZPX, 4}, {"LSR", LSR, ModeZPX, 6}, {"*SRE", SRE, ModeZPX, 6}, {"CLI", CLI, ModeIMP, 2}, {"EOR", EOR, ModeABY, 4}, {"*NOP", NOP, ModeIMP, 2}, {"*SRE", SRE, ModeABY, 7}, {"*NOP", NOP, ModeABX, 4}, {"EOR", EOR, ModeABX, 4}, {"LSR", LSR, ModeABX, 7}, {"*SRE", SRE, ModeABX, 7},
+		{"RTS", RTS, ModeIMP, 6}, {"ADC", ADC, ModeIZX, 6}, {"*KIL", KIL, ModeIMP, 2}, {"*RRA", RRA, ModeIZX, 8}, {"*NOP", NOP, ModeZP0, 3}, {"ADC", ADC, ModeZP0, 3}, {"ROR", ROR, ModeZP0, 5}, {"*RRA", RRA, ModeZP0, 5}, {"PLA", PLA, ModeIMP, 4}, {"ADC", ADC, ModeIMM, 2}, {"ROR", ROR, ModeACC, 2}, {"*ARR", ARR, ModeIMM, 2}, {"JMP", JMP, ModeIND, 5}, {"ADC", ADC, ModeABS, 4}, {"ROR", ROR, ModeABS, 6}, {"*RRA", RRA, ModeABS, 6},
+		{"BVS", BVS, ModeREL, 2}, {"ADC", ADC, ModeIZY, 5}, {"*KIL", KIL, ModeIMP, 2}, {"*RRA", RRA, ModeIZY, 8}, {"*NOP", NOP, ModeZPX, 4}, {"ADC", ADC, ModeZPX, 4}, {"ROR", ROR, ModeZPX, 6}, {"*RRA", RRA, ModeZPX, 6}, {"SEI", SEI, ModeIMP, 2}, {"ADC", ADC, ModeABY, 4}, {"*NOP", NOP, ModeIMP, 2}, {"*RRA", RRA, ModeABY, 7}, {"*NOP", NOP, ModeABX, 4}, {"ADC", ADC, ModeABX, 4}, {"ROR", ROR, ModeABX, 7}, {"*RRA", RRA, ModeABX, 7},
+		{"*NOP", NOP, ModeIMM, 2}, {"STA", STA, ModeIZX, 6}, {"*NOP", NOP, ModeIMM, 2}, {"*SAX", SAX, ModeIZX, 6}, {"STY", STY, ModeZP0, 3}, {"STA", STA, ModeZP0, 3}, {"STX", STX, ModeZP0, 3}, {"*SAX", SAX, ModeZP0, 3}, {"DEY", DEY, ModeIMP, 2}, {"*NOP", NOP, ModeIMM, 2}, {"TXA", TXA, ModeIMP, 2}, {"*XAA", XAA, ModeIMM, 2}, {"STY", STY, ModeABS, 4}, {"STA", STA, ModeABS, 4}, {"STX", STX, ModeABS, 4}, {"*SAX", SAX, ModeABS, 4},
+		{"BCC", BCC, ModeREL, 2}, {"STA", STA, ModeIZY, 6}, {"*KIL", KIL, ModeIMP, 2}, {"*AHX", AHX, ModeIZY, 6}, {"STY", STY, ModeZPX, 4}, {"STA", STA, ModeZPX, 4}, {"STX", STX, ModeZPY, 4}, {"*SAX", SAX, ModeZPY, 4}, {"TYA", TYA, ModeIMP, 2}, {"STA", STA, ModeABY, 5}, {"TXS", TXS, ModeIMP, 2}, {"*TAS", TAS, ModeABY, 5}, {"*SHY", SHY, ModeABX, 5}, {"STA", STA, ModeABX, 5}, {"*SHX", SHX, ModeABY, 5}, {"*AHX", AHX, ModeABY, 5},
+		{"LDY", LDY, ModeIMM, 2}, {"LDA", LDA, ModeIZX, 6}, {"LDX", LDX, ModeIMM, 2}, {"*LAX", LAX, ModeIZX, 6}, {"LDY", LDY, ModeZP0, 3}, {"LDA", LDA, ModeZP0, 3}, {"LDX", LDX, ModeZP0, 3}, {"*LAX", LAX, ModeZP0, 3}, {"TAY", TAY, ModeIMP, 2}, {"LDA", LDA, ModeIMM, 2}, {"TAX", TAX, ModeIMP, 2}, {"*LAX", LAX, ModeIMM, 2}, {"LDY", LDY, ModeABS, 4}, {"LDA", LDA, ModeABS, 4}, {"LDX", LDX, ModeABS, 4}, {"*LAX", LAX, ModeABS, 4},
+		{"BCS", BCS, ModeREL, 2}, {"LDA", LDA, ModeIZY, 5}, {"*KIL", KIL, ModeIMP, 2}, {"*LAX", LAX, ModeIZY, 5}, {"LDY", LDY, ModeZPX, 4}, {"LDA", LDA, ModeZPX, 4}, {"LDX", LDX, ModeZPY, 4}, {"*LAX", LAX, ModeZPY, 4}, {"CLV", CLV, ModeIMP, 2}, {"LDA", LDA, ModeABY, 4}, {"TSX", TSX, ModeIMP, 2}, {"*LAS", LAS, ModeABY, 4}, {"LDY", LDY, ModeABX, 4}, {"LDA", LDA, ModeABX, 4}, {"LDX", LDX, ModeABY, 4}, {"*LAX", LAX, ModeABY, 4},
+		{"CPY", CPY, ModeIMM, 2}, {"CMP", CMP, ModeIZX, 6}, {"*NOP", NOP, ModeIMM, 2}, {"*DCP", DCP, ModeIZX, 8}, {"CPY", CPY, ModeZP0, 3}, {"CMP", CMP, ModeZP0, 3}, {"DEC", DEC, ModeZP0, 5}, {"*DCP", DCP, ModeZP0, 5}, {"INY", INY, ModeIMP, 2}, {"CMP", CMP, ModeIMM, 2}, {"DEX", DEX, ModeIMP, 2}, {"*AXS", AXS, ModeIMM, 2}, {"CPY", CPY, ModeABS, 4}, {"CMP", CMP, ModeABS, 4}, {"DEC", DEC, ModeABS, 6}, {"*DCP", DCP, ModeABS, 6},
+		{"BNE", BNE, ModeREL, 2}, {"CMP", CMP, ModeIZY, 5}, {"*KIL", KIL, ModeIMP, 2}, {"*DCP", DCP, ModeIZY, 8}, {"*NOP", NOP, ModeZPX, 4}, {"CMP", CMP, ModeZPX, 4}, {"DEC", DEC, ModeZPX, 6}, {"*DCP", DCP, ModeZPX, 6}, {"CLD", CLD, ModeIMP, 2}, {"CMP", CMP, ModeABY, 4}, {"*NOP", NOP, ModeIMP, 2}, {"*DCP", DCP, ModeABY, 7}, {"*NOP", NOP, ModeABX, 4}, {"CMP", CMP, ModeABX, 4}, {"DEC", DEC, ModeABX, 7}, {"*DCP", DCP, ModeABX, 7},
+		{"CPX", CPX, ModeIMM, 2}, {"SBC", SBC, ModeIZX, 6}, {"*NOP", NOP, ModeIMM, 2}, {"*ISB", ISB, ModeIZX, 8}, {"CPX", CPX, ModeZP0, 3}, {"SBC", SBC, ModeZP0, 3}, {"INC", INC, ModeZP0, 5}, {"*ISB", ISB, ModeZP0, 5}, {"INX", INX, ModeIMP, 2}, {"SBC", SBC, ModeIMM, 2}, {"NOP", NOP, ModeIMP, 2}, {"*SBC", SBC, ModeIMM, 2}, {"CPX", CPX, ModeABS, 4}, {"SBC", SBC, ModeABS, 4}, {"INC", INC, ModeABS, 6}, {"*ISB", ISB, ModeABS, 6},
+		{"BEQ", BEQ, ModeREL, 2}, {"SBC", SBC, ModeIZY, 5}, {"*KIL", KIL, ModeIMP, 2}, {"*ISB", ISB, ModeIZY, 8}, {"*NOP", NOP, ModeZPX, 4}, {"SBC", SBC, ModeZPX, 4}, {"INC", INC, ModeZPX, 6}, {"*ISB", ISB, ModeZPX, 6}, {"SED", SED, ModeIMP, 2}, {"SBC", SBC, ModeABY, 4}, {"*NOP", NOP, ModeIMP, 2}, {"*ISB", ISB, ModeABY, 7}, {"*NOP", NOP, ModeABX, 4}, {"SBC", SBC, ModeABX, 4}, {"INC", INC, ModeABX, 7}, {"*ISB", ISB, ModeABX, 7},
+	}
 }
 
 // CreateCPU : creates a new CPU
@@ -124,15 +122,16 @@ func (c *CPU6502) Clock() {
 
 		c.pc++
 
-		c.cycles = OpCodesLookupTable[c.opcode].cycles
-		additionalCycle1 := OpCodesLookupTable[c.opcode].addressmode(c)
-		additionalCycle2 := OpCodesLookupTable[c.opcode].operate(c)
+		c.cycles = opCodes[c.opcode].cycles
+		additionalCycle1 := addressModes[opCodes[c.opcode].mode].address(c)
+		additionalCycle2 := opCodes[c.opcode].operate(c)
 		c.cycles += (additionalCycle1 & additionalCycle2)
 		// Always set the unused status flag bit to 1
 		c.SetStatusRegisterFlag(U, true)
-		OperationCount++
+		c.bus.operationCount++
 	}
 
+	c.clockCount++
 	c.cycles--
 }
 
@@ -162,13 +161,20 @@ func (c *CPU6502) Reset() {
 	c.addressAbs = 0x0000
 	c.fetched = 0x00
 
+	c.jammed = false
+
 	// Reset takes time
 	c.cycles = 8
 }
 
+// Jammed : true when a KIL opcode halted the CPU
+func (c *CPU6502) Jammed() bool {
+	return c.jammed
+}
+
 // InterruptRequest : Sets the system in a state to execute code from an interruption
 func (c *CPU6502) InterruptRequest() {
-	if !c.StatusRegister(I) {
+	if !c.StatusRegister(I) && !c.jammed {
 		c.CPUWriteStack(Word(c.stkp), byte((c.pc>>8)&0x00ff))
 		c.stkp--
 		c.CPUWriteStack(Word(c.stkp), byte(c.pc&0x00FF))
@@ -191,6 +197,9 @@ func (c *CPU6502) InterruptRequest() {
 
 // NonMaskableInterruptRequest : Sets the system in a state to execute code from an interruption
 func (c *CPU6502) NonMaskableInterruptRequest() {
+	if c.jammed {
+		return
+	}
 	c.CPUWriteStack(Word(c.stkp), byte((c.pc>>8)&0x00ff))
 	c.stkp--
 	c.CPUWriteStack(Word(c.stkp), byte(c.pc&0x00FF))
@@ -210,8 +219,14 @@ func (c *CPU6502) NonMaskableInterruptRequest() {
 	c.cycles = 8
 }
 
+// implied : checks if the current instruction has no memory operand
+func (c *CPU6502) implied() bool {
+	mode := opCodes[c.opcode].mode
+	return mode == ModeIMP || mode == ModeACC
+}
+
 func (c *CPU6502) fetch() byte {
-	if !FnEquals(OpCodesLookupTable[c.opcode].addressmode, IMP) {
+	if !c.implied() {
 		f, _ := c.CPURead(c.addressAbs)
 		c.fetched = f
 	}
@@ -342,7 +357,7 @@ func IND(c *CPU6502) byte {
 	readAddress := ptr
 
 	if pointerLow == 0x00ff {
-		readAddress &= 0x00FF
+		readAddress &= 0xFF00
 	} else {
 		readAddress++
 	}
@@ -379,10 +394,12 @@ func XXX(c *CPU6502) byte {
 	return 0
 }
 
-// PAN : Panic code - in place of 0x0B ANC
-// ANC : Panic code - illegal op code, its actually something else, but here we use it as PANIC
-func PAN(c *CPU6502) byte {
-	panic("Test Failed")
+// KIL : Illegal opcode that halts the CPU, it keeps fetching the same opcode
+// and ignores interrupts until the console is reset
+func KIL(c *CPU6502) byte {
+	c.jammed = true
+	c.pc--
+	return 0
 }
 
 // ADC : Add memory to accumulator with carry
@@ -395,7 +412,7 @@ func ADC(c *CPU6502) byte {
 	temp := Word(c.a) + f + flagVal
 	overflows := (^(Word(c.a) ^ Word(c.fetched)) & (Word(c.a) ^ Word(temp))) & 0x0080
 	c.SetStatusRegisterFlag(C, temp > 255)
-	c.SetStatusRegisterFlag(Z, (temp&0xFF00) == 0)
+	c.SetStatusRegisterFlag(Z, (temp&0x00FF) == 0)
 	c.SetStatusRegisterFlag(N, (temp&0x0080) != 0)
 	c.SetStatusRegisterFlag(V, overflows != 0)
 	c.a = byte(temp & 0x00FF)
@@ -478,7 +495,7 @@ func LSR(c *CPU6502) byte {
 	c.SetStatusRegisterFlag(C, (c.fetched&0x01) == 1)
 	temp := c.fetched >> 1
 	c.SetFlagsZeroAndNegative(temp)
-	if FnEquals(OpCodesLookupTable[c.opcode].addressmode, IMP) {
+	if c.implied() {
 		c.a = temp
 	} else {
 		c.CPUWrite(c.addressAbs, temp)
@@ -503,10 +520,10 @@ func PHP(c *CPU6502) byte {
 func ROR(c *CPU6502) byte {
 	c.fetch()
 	temp := Word(c.fetched)>>1 | c.StatusRegisterAsWord(C)<<7
-	c.SetStatusRegisterFlag(C, (temp&0x0001) != 0)
+	c.SetStatusRegisterFlag(C, (c.fetched&0x01) != 0)
 	c.SetFlagsZeroAndNegative(byte(temp & 0x00FF))
 
-	if FnEquals(OpCodesLookupTable[c.opcode].addressmode, IMP) {
+	if c.implied() {
 		c.a = byte(temp & 0x00FF)
 	} else {
 		c.CPUWrite(c.addressAbs, byte(temp&0x00FF))
@@ -634,8 +651,9 @@ func RTI(c *CPU6502) byte {
 	c.stkp++
 	c.status, _ = c.CPUReadStack(Word(c.stkp))
 
-	c.status &= ^(byte(1) << B)
-	c.status &= ^(byte(1) << U)
+	// B only exists on the stack, U always reads as set
+	c.SetStatusRegisterFlag(B, false)
+	c.SetStatusRegisterFlag(U, true)
 
 	c.stkp++
 	lo, _ := c.CPUReadStack(Word(c.stkp))
@@ -677,7 +695,7 @@ func ASL(c *CPU6502) byte {
 	temp := Word(c.fetched) << 1
 	c.SetStatusRegisterFlag(C, (temp&0xFF00) != 0)
 	c.SetFlagsZeroAndNegative(byte(temp))
-	if FnEquals(OpCodesLookupTable[c.opcode].addressmode, IMP) {
+	if c.implied() {
 		c.a = byte(temp & 0x00FF)
 	} else {
 		c.CPUWrite(c.addressAbs, byte(temp&0x00FF))
@@ -768,9 +786,11 @@ func ORA(c *CPU6502) byte {
 
 // PLP : Instruction: Pop Status Register off Stack
 // Function:    Status <- Stack
+// Note:        Break flag is ignored and unused flag stays set
 func PLP(c *CPU6502) byte {
 	c.stkp++
 	c.status, _ = c.CPUReadStack(Word(c.stkp))
+	c.SetStatusRegisterFlag(B, false)
 	c.SetStatusRegisterFlag(U, true)
 	return 0
 }
@@ -886,7 +906,7 @@ func ROL(c *CPU6502) byte {
 
 	c.SetStatusRegisterFlag(C, (temp&0xFF00) != 0)
 	c.SetFlagsZeroAndNegative(byte(temp & 0x00FF))
-	if FnEquals(OpCodesLookupTable[c.opcode].addressmode, IMP) {
+	if c.implied() {
 		c.a = byte(temp & 0x00FF)
 	} else {
 		c.CPUWrite(c.addressAbs, byte(temp&0x00FF))
@@ -902,7 +922,7 @@ func SBC(c *CPU6502) byte {
 	temp := Word(c.a) + value + flagVal
 	c.SetStatusRegisterFlag(C, (temp&0xFF00) != 0)
 	c.SetStatusRegisterFlag(V, ((temp^Word(c.a))&(temp^value)&0x0080) != 0)
-	c.SetStatusRegisterFlag(Z, (temp&0xFF00) == 0)
+	c.SetStatusRegisterFlag(Z, (temp&0x00FF) == 0)
 	c.SetStatusRegisterFlag(N, (temp&0x0080) != 0)
 	c.a = byte(temp & 0x00FF)
 	return 1
@@ -932,175 +952,239 @@ func TYA(c *CPU6502) byte {
 	return 0
 }
 
-func logError(e error) {
-	if e != nil {
-		log.Panicf("%s", e)
+// Unofficial opcodes, based on
+// https://wiki.nesdev.com/w/index.php/Programming_with_unofficial_opcodes
+// The read-modify-write ones do the official operation on memory and then use
+// the value written as the operand of the second one
+
+// SLO : ASL memory then ORA
+// Function:    M = M << 1, A = A | M
+// Flags Out:   N, Z, C
+func SLO(c *CPU6502) byte {
+	c.fetch()
+	c.SetStatusRegisterFlag(C, c.fetched&0x80 != 0)
+	c.fetched <<= 1
+	c.CPUWrite(c.addressAbs, c.fetched)
+	c.a |= c.fetched
+	c.SetFlagsZeroAndNegative(c.a)
+	return 0
+}
+
+// RLA : ROL memory then AND
+// Function:    M = M << 1 | C, A = A & M
+// Flags Out:   N, Z, C
+func RLA(c *CPU6502) byte {
+	c.fetch()
+	carry := byte(c.StatusRegisterAsWord(C))
+	c.SetStatusRegisterFlag(C, c.fetched&0x80 != 0)
+	c.fetched = c.fetched<<1 | carry
+	c.CPUWrite(c.addressAbs, c.fetched)
+	c.a &= c.fetched
+	c.SetFlagsZeroAndNegative(c.a)
+	return 0
+}
+
+// SRE : LSR memory then EOR
+// Function:    M = M >> 1, A = A ^ M
+// Flags Out:   N, Z, C
+func SRE(c *CPU6502) byte {
+	c.fetch()
+	c.SetStatusRegisterFlag(C, c.fetched&0x01 != 0)
+	c.fetched >>= 1
+	c.CPUWrite(c.addressAbs, c.fetched)
+	c.a ^= c.fetched
+	c.SetFlagsZeroAndNegative(c.a)
+	return 0
+}
+
+// RRA : ROR memory then ADC
+// Function:    M = C << 7 | M >> 1, A = A + M + C
+// Flags Out:   N, Z, C, V
+func RRA(c *CPU6502) byte {
+	c.fetch()
+	carry := byte(c.StatusRegisterAsWord(C))
+	c.SetStatusRegisterFlag(C, c.fetched&0x01 != 0)
+	c.fetched = carry<<7 | c.fetched>>1
+	c.CPUWrite(c.addressAbs, c.fetched)
+	c.addWithCarry(c.fetched)
+	return 0
+}
+
+// ANC : AND with the carry taken from bit 7 of the result, like ASL or ROL
+// would
+// Function:    A = A & M, C = N
+// Flags Out:   N, Z, C
+func ANC(c *CPU6502) byte {
+	c.a &= c.fetch()
+	c.SetFlagsZeroAndNegative(c.a)
+	c.SetStatusRegisterFlag(C, c.a&0x80 != 0)
+	return 0
+}
+
+// SAX : Store A AND X at Address
+// Function:    M = A & X
+func SAX(c *CPU6502) byte {
+	c.CPUWrite(c.addressAbs, c.a&c.x)
+	return 0
+}
+
+// LAX : Load the Accumulator and the X Register
+// Function:    A = X = M
+// Flags Out:   N, Z
+func LAX(c *CPU6502) byte {
+	c.a = c.fetch()
+	c.x = c.a
+	c.SetFlagsZeroAndNegative(c.a)
+	return 1
+}
+
+// DCP : DEC memory then CMP
+// Function:    M = M - 1, compare A with M
+// Flags Out:   N, Z, C
+func DCP(c *CPU6502) byte {
+	c.fetch()
+	c.fetched--
+	c.CPUWrite(c.addressAbs, c.fetched)
+	c.SetStatusRegisterFlag(C, c.a >= c.fetched)
+	c.SetFlagsZeroAndNegative(c.a - c.fetched)
+	return 0
+}
+
+// ISB : INC memory then SBC
+// Function:    M = M + 1, A = A - M - (1 - C)
+// Flags Out:   N, Z, C, V
+func ISB(c *CPU6502) byte {
+	c.fetch()
+	c.fetched++
+	c.CPUWrite(c.addressAbs, c.fetched)
+	c.addWithCarry(c.fetched ^ 0xFF)
+	return 0
+}
+
+// ALR : AND then LSR the Accumulator
+// Function:    A = (A & M) >> 1
+// Flags Out:   N, Z, C
+func ALR(c *CPU6502) byte {
+	c.a &= c.fetch()
+	c.SetStatusRegisterFlag(C, c.a&0x01 != 0)
+	c.a >>= 1
+	c.SetFlagsZeroAndNegative(c.a)
+	return 0
+}
+
+// ARR : AND then ROR the Accumulator, C and V come from bits 6 and 5 of the
+// result instead of the shift
+// Function:    A = C << 7 | (A & M) >> 1
+// Flags Out:   N, Z, C, V
+func ARR(c *CPU6502) byte {
+	c.a = byte(c.StatusRegisterAsWord(C)<<7) | (c.a&c.fetch())>>1
+	c.SetFlagsZeroAndNegative(c.a)
+	c.SetStatusRegisterFlag(C, c.a&0x40 != 0)
+	c.SetStatusRegisterFlag(V, (c.a>>6^c.a>>5)&0x01 != 0)
+	return 0
+}
+
+// AXS : X = A AND X minus M, without borrow, C is set like CMP does
+// Function:    X = (A & X) - M
+// Flags Out:   N, Z, C
+func AXS(c *CPU6502) byte {
+	ax := c.a & c.x
+	c.fetch()
+	c.SetStatusRegisterFlag(C, ax >= c.fetched)
+	c.x = ax - c.fetched
+	c.SetFlagsZeroAndNegative(c.x)
+	return 0
+}
+
+// LAS : A, X and the Stack Pointer get M AND the Stack Pointer
+// Function:    A = X = S = M & S
+// Flags Out:   N, Z
+func LAS(c *CPU6502) byte {
+	c.stkp &= c.fetch()
+	c.a, c.x = c.stkp, c.stkp
+	c.SetFlagsZeroAndNegative(c.a)
+	return 1
+}
+
+// XAA : unstable on the real chip, the bits of A that survive depend on the
+// chip and its temperature. $EE is the value most consoles show
+// Function:    A = (A | $EE) & X & M
+// Flags Out:   N, Z
+func XAA(c *CPU6502) byte {
+	c.a = (c.a | 0xEE) & c.x & c.fetch()
+	c.SetFlagsZeroAndNegative(c.a)
+	return 0
+}
+
+// AHX : Store A AND X AND the high byte of the address plus one
+// Function:    M = A & X & (H + 1)
+func AHX(c *CPU6502) byte {
+	c.storeHigh(c.a&c.x, c.y)
+	return 0
+}
+
+// TAS : the Stack Pointer gets A AND X, then stored like AHX
+// Function:    S = A & X, M = S & (H + 1)
+func TAS(c *CPU6502) byte {
+	c.stkp = c.a & c.x
+	c.storeHigh(c.stkp, c.y)
+	return 0
+}
+
+// SHX : Store X AND the high byte of the address plus one
+// Function:    M = X & (H + 1)
+func SHX(c *CPU6502) byte {
+	c.storeHigh(c.x, c.y)
+	return 0
+}
+
+// SHY : Store Y AND the high byte of the address plus one
+// Function:    M = Y & (H + 1)
+func SHY(c *CPU6502) byte {
+	c.storeHigh(c.y, c.x)
+	return 0
+}
+
+// storeHigh : the store of AHX, TAS, SHX and SHY. The value is ANDed with the
+// high byte of the address before indexing plus one, and when indexing crosses
+// a page the value also replaces the high byte of the address
+func (c *CPU6502) storeHigh(value, index byte) {
+	base := c.addressAbs - Word(index)
+	value &= byte(base>>8) + 1
+	address := c.addressAbs
+	if address&0xFF00 != base&0xFF00 {
+		address = Word(value)<<8 | address&0x00FF
 	}
-	e = nil
+	c.CPUWrite(address, value)
+}
+
+// addWithCarry : A = A + value + C, what ADC does and SBC does with the
+// operand inverted
+func (c *CPU6502) addWithCarry(value byte) {
+	temp := Word(c.a) + Word(value) + c.StatusRegisterAsWord(C)
+	c.SetStatusRegisterFlag(C, temp > 0xFF)
+	c.SetStatusRegisterFlag(V, (Word(c.a)^temp)&(Word(value)^temp)&0x80 != 0)
+	c.a = byte(temp)
+	c.SetFlagsZeroAndNegative(c.a)
 }
 
 // Disassemble : This is the disassembly function. Its workings are not required for emulation.
 // It is merely a convenience function to turn the binary instruction code into
-// human readable form. Its included as part of the emulator because it can take
-// advantage of many of the CPUs internal operations to do this.
+// human readable form. Every instruction goes through Decode, which carries the
+// addressing mode and operand length from the lookup table.
 func (c *CPU6502) Disassemble(start, stop Word) map[Word]string {
-	var lo byte = 0
-	var hi byte = 0
-	var lineAddr Word = 0
-	var addr uint32 = uint32(start)
-	bus := c.bus
+	var addr = uint32(start)
 	mapLines := make(map[Word]string)
 
-	// Starting at the specified address we read an instruction
-	// byte, which in turn yields information from the lookup table
-	// as to how many additional bytes we need to read and what the
-	// addressing mode is. I need this info to assemble human readable
-	// syntax, which is different depending upon the addressing mode
-
-	// As the instruction is decoded, a std::string is assembled
-	// with the readable output
-	for uint32(addr) <= uint32(stop) {
-		lineAddr = Word(addr)
-		var sInst bytes.Buffer
-		var e error
-		var opcode byte
-		// Prefix line with instruction address
-		sInst.WriteString("$")
-		sInst.WriteString(Hex(uint32(addr), 4))
-		sInst.WriteString(": ")
-
-		// Read instruction, and get its readable name
-		opcode, e = c.bus.CPURead(Word(addr), true)
-		logError(e)
-		addr++
-
-		sInst.WriteString(OpCodesLookupTable[opcode].name)
-		sInst.WriteByte(' ')
-
-		// Get oprands from desired locations, and form the
-		// instruction based upon its addressing mode. These
-		// routines mimmick the actual fetch routine of the
-		// 6502 in order to get accurate data as part of the
-		// instruction
-		if FnEquals(OpCodesLookupTable[opcode].addressmode, IMP) {
-			sInst.WriteString("{IMP}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, IMM) {
-			var value byte
-			value, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			sInst.WriteString("#$")
-			sInst.WriteString(Hex(uint32(value), 2))
-			sInst.WriteString(" {IMM}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, ZP0) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi = 0x00
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(lo), 2))
-			sInst.WriteString(" {ZP0}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, ZPX) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi = 0x00
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(lo), 2))
-			sInst.WriteString(", X {ZPX}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, ZPY) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi = 0x00
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(lo), 2))
-			sInst.WriteString(", Y {ZPY}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, IZX) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi = 0x00
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(lo), 2))
-			sInst.WriteString(", X {IZX}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, IZY) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi = 0x00
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(lo), 2))
-			sInst.WriteString(", Y {IZY}")
-
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, ABS) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(Word(hi)<<8|Word(lo)), 4))
-			sInst.WriteString(" {ABS}")
-
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, ABX) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(Word(hi)<<8|Word(lo)), 4))
-			sInst.WriteString(", X {ABX}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, ABY) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(Word(hi)<<8|Word(lo)), 4))
-			sInst.WriteString(", Y {ABY}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, IND) {
-			lo, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-			hi, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-
-			sInst.WriteString("($")
-			sInst.WriteString(Hex(uint32(Word(hi)<<8|Word(lo)), 4))
-			sInst.WriteString(") {IND}")
-		} else if FnEquals(OpCodesLookupTable[opcode].addressmode, REL) {
-			var val byte
-			val, e = bus.CPURead(Word(addr), true)
-			logError(e)
-			addr++
-
-			sInst.WriteString("$")
-			sInst.WriteString(Hex(uint32(val), 4))
-			sInst.WriteString(" [$")
-			sInst.WriteString(Hex(uint32(int32(addr)+int32(int8(val))), 4))
-			sInst.WriteString("] {REL}")
-		}
-
-		// Add the formed string to a std::map, using the instruction's
+	for addr <= uint32(stop) {
+		d := c.Decode(Word(addr))
+		addr += uint32(d.Length())
+
+		// Add the formed string to a map, using the instruction's
 		// address as the key. This makes it convenient to look for later
 		// as the instructions are variable in length, so a straight up
 		// incremental index is not sufficient.
-		mapLines[lineAddr] = sInst.String()
+		mapLines[d.Address] = d.String()
 	}
 
 	return mapLines
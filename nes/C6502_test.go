@@ -0,0 +1,247 @@
+package nes
+
+import (
+	"strconv"
+	"testing"
+)
+
+var (
+	testCPU *CPU6502
+)
+
+const (
+	ZeroB byte = 0
+	ZeroW Word = 0
+)
+
+func init() {
+	testCPU = &CPU6502{}
+	ppu := &PPU2C02{}
+
+	CreateBus(testCPU, ppu)
+}
+
+func TestReset(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+	t.Logf("status = 0b%s", strconv.FormatInt(int64(cpu.status), 2))
+
+	assertEqualsB(t, ZeroB, cpu.x)
+	assertEqualsB(t, ZeroB, cpu.y)
+	assertEqualsB(t, ZeroB, cpu.a)
+	assertEqualsB(t, byte(0xFD), cpu.stkp)
+	assertFalse(t, cpu.StatusRegister(C))
+	assertFalse(t, cpu.StatusRegister(Z))
+	assertFalse(t, cpu.StatusRegister(I))
+	assertFalse(t, cpu.StatusRegister(D))
+	assertFalse(t, cpu.StatusRegister(B))
+	assertTrue(t, cpu.StatusRegister(U))
+	assertFalse(t, cpu.StatusRegister(V))
+	assertFalse(t, cpu.StatusRegister(N))
+	assertEqualsW(t, ZeroW, cpu.addressAbs)
+	assertEqualsW(t, ZeroW, cpu.addressRel)
+	assertEqualsW(t, ZeroW, cpu.pc)
+	assertEqualsB(t, ZeroB, cpu.fetched)
+	assertEqualsB(t, byte(8), cpu.cycles)
+}
+
+func TestOperationADC(t *testing.T) {
+
+	cpu := testCPU
+	cpu.Reset()
+
+	cpu.a = byte(0x2)
+	cpu.addressAbs = Word(0x0100)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x3))
+
+	ADC(cpu)
+
+	assertFalse(t, cpu.StatusRegister(C))
+	assertEqualsB(t, byte(0x5), cpu.a)
+}
+
+func TestOperationADCWithCarry(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+
+	cpu.a = byte(0xFE)
+	cpu.addressAbs = Word(0x0100)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x3))
+
+	ADC(cpu)
+
+	assertTrue(t, cpu.StatusRegister(C))
+	assertFalse(t, cpu.StatusRegister(V))
+	assertEqualsB(t, byte(0x1), cpu.a)
+}
+
+func TestOperationADCWithOverflow(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+	v := -10
+	z := -127
+	cpu.a = byte(v)
+	cpu.addressAbs = Word(0x0100)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(z))
+
+	ADC(cpu)
+
+	assertTrue(t, cpu.StatusRegister(C))
+	assertTrue(t, cpu.StatusRegister(V))
+}
+
+func TestOperationPHP(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+
+	oldStatus := byte(0x30)
+
+	stkp := cpu.stkp
+	PHP(cpu)
+	stackedStatus, e := cpu.CPURead(Stack + Word(stkp))
+	assertNil(t, e)
+	assertEqualsB(t, oldStatus, stackedStatus)
+}
+
+func TestOperationJSR(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+	cpu.addressAbs = Word(0xABCD)
+	cpu.pc = Word(2)
+
+	JSR(cpu)
+
+	assertEqualsW(t, Word(0xABCD), cpu.pc)
+}
+
+func TestAddressINDPageBoundaryBug(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+
+	// JMP ($02FF) takes the high byte from $0200, not from $0300
+	cpu.pc = Word(0x0400)
+	cpu.bus.CPUWrite(0x0400, 0xFF)
+	cpu.bus.CPUWrite(0x0401, 0x02)
+	cpu.bus.CPUWrite(0x02FF, 0x34)
+	cpu.bus.CPUWrite(0x0200, 0x12)
+	cpu.bus.CPUWrite(0x0300, 0x56)
+
+	IND(cpu)
+
+	assertEqualsW(t, Word(0x1234), cpu.addressAbs)
+}
+
+func TestOperationANC(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+
+	cpu.a = byte(0xC3)
+	cpu.addressAbs = Word(0x0100)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x81))
+
+	ANC(cpu)
+
+	assertEqualsB(t, byte(0x81), cpu.a)
+	assertTrue(t, cpu.StatusRegister(C))
+	assertTrue(t, cpu.StatusRegister(N))
+	assertFalse(t, cpu.StatusRegister(Z))
+
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x7E))
+	ANC(cpu)
+
+	assertEqualsB(t, ZeroB, cpu.a)
+	assertFalse(t, cpu.StatusRegister(C))
+	assertTrue(t, cpu.StatusRegister(Z))
+}
+
+func TestOperationALRAndARR(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+	cpu.addressAbs = Word(0x0100)
+
+	cpu.a = byte(0xFF)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x03))
+	ALR(cpu)
+	assertEqualsB(t, byte(0x01), cpu.a)
+	assertTrue(t, cpu.StatusRegister(C))
+
+	cpu.a = byte(0x40)
+	cpu.SetStatusRegisterFlag(C, false)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0xFF))
+	ARR(cpu)
+	assertEqualsB(t, byte(0x20), cpu.a)
+	assertFalse(t, cpu.StatusRegister(C))
+	assertTrue(t, cpu.StatusRegister(V))
+
+	cpu.a = byte(0xFF)
+	cpu.SetStatusRegisterFlag(C, true)
+	ARR(cpu)
+	assertEqualsB(t, byte(0xFF), cpu.a)
+	assertTrue(t, cpu.StatusRegister(C))
+	assertFalse(t, cpu.StatusRegister(V))
+	assertTrue(t, cpu.StatusRegister(N))
+}
+
+func TestOperationAXSAndLAS(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+	cpu.addressAbs = Word(0x0100)
+
+	cpu.a, cpu.x = byte(0xF0), byte(0x3C)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x10))
+	AXS(cpu)
+	assertEqualsB(t, byte(0x20), cpu.x)
+	assertTrue(t, cpu.StatusRegister(C))
+
+	// $F0 & $20 - $40
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x40))
+	AXS(cpu)
+	assertEqualsB(t, byte(0xE0), cpu.x)
+	assertFalse(t, cpu.StatusRegister(C))
+	assertTrue(t, cpu.StatusRegister(N))
+
+	cpu.stkp = byte(0xF0)
+	cpu.bus.CPUWrite(cpu.addressAbs, byte(0x3C))
+	LAS(cpu)
+	assertEqualsB(t, byte(0x30), cpu.a)
+	assertEqualsB(t, byte(0x30), cpu.x)
+	assertEqualsB(t, byte(0x30), cpu.stkp)
+}
+
+func TestOperationSHX(t *testing.T) {
+	cpu := testCPU
+	cpu.Reset()
+
+	// $0200,Y stays in the page, X is ANDed with $02 + 1
+	cpu.x, cpu.y = byte(0xFF), byte(0x10)
+	cpu.addressAbs = Word(0x0210)
+	SHX(cpu)
+	value, _ := cpu.CPURead(0x0210)
+	assertEqualsB(t, byte(0x03), value)
+
+	// $02F8,Y crosses into $0300, the value replaces the high byte
+	cpu.x = byte(0x01)
+	cpu.addressAbs = Word(0x0308)
+	SHX(cpu)
+	value, _ = cpu.CPURead(0x0108)
+	assertEqualsB(t, byte(0x01), value)
+}
+
+func TestOpcodesDoNotPanic(t *testing.T) {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(TestCartridge("00 00 00", 0x8000))
+	for opcode, op := range opCodes {
+		nes.cpu.Reset()
+		nes.cpu.pc = 0x8000
+		nes.cpu.opcode = byte(opcode)
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Opcode $%02X %s panics: %v", opcode, op.name, r)
+				}
+			}()
+			addressModes[op.mode].address(nes.cpu)
+			op.operate(nes.cpu)
+		}()
+	}
+}
@@ -0,0 +1,294 @@
+package nes
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+const (
+	// Horizontal : Horizontal
+	Horizontal = 0
+	// Vertical : Vertical
+	Vertical = 1
+	// OnescreenLo : OnescreenLo
+	OnescreenLo = 2
+	// OnescreenHi : OnescreenHi
+	OnescreenHi = 3
+)
+
+// ErrNotINES : the file does not start with the iNES signature
+var ErrNotINES = errors.New("not an iNES file")
+
+type header struct {
+	name         [4]byte
+	PGRRomBlocks byte
+	CHARomBlocks byte
+	mapper1      byte
+	mapper2      byte
+	PRGRamSize   byte
+	TVSystem1    byte
+	TVSystem2    byte
+	// nes20 : bytes 11 to 15, only used by NES 2.0 headers
+	nes20 [5]byte
+}
+
+// Cartridge : struct that defines the Cart object
+type Cartridge struct {
+	bus       *Bus
+	header    *header
+	mapperID  byte
+	mapper    Mapper
+	PRGMemory []byte
+	CHAMemory []byte
+	PRGRam    []byte
+	PRGBanks  byte
+	CHABanks  byte
+	Mirror    int
+	romHash   [sha1.Size]byte
+}
+
+// TestCartridge : handmade cart for testing
+func TestCartridge(rom string, offset Word) *Cartridge {
+
+	cartHeader := &header{
+		[4]byte{'t', 'e', 's', 't'},
+		1,
+		1,
+
+		0,
+		0,
+		0,
+
+		0,
+		0,
+		[5]byte{}}
+
+	mapperID := ((cartHeader.mapper2 >> 4) << 4) | (cartHeader.mapper1 >> 4)
+	mapper, _ := createMapper(mapperID, cartHeader.PGRRomBlocks, cartHeader.CHARomBlocks)
+
+	// Discover what kind of iNes file, hardcoded 1 for now
+	var PRGMemory, CHAMemory []byte
+
+	buf := make([]byte, int(cartHeader.PGRRomBlocks)*16384)
+	PRGMemory = buf
+
+	nOffset := 0
+	for i := 0; i < len(rom); i += 2 {
+		PRGMemory[nOffset] = ByteToHex(rom[i])<<4 | ByteToHex(rom[i+1])
+		if i+2 < len(rom) && rom[i+2] == byte(' ') {
+			i++
+		}
+		nOffset++
+	}
+
+	PRGMemory[0xFFFC&0x3FFF] = byte(offset)
+	PRGMemory[0xFFFD&0x3FFF] = byte(offset >> 8)
+
+	buf = make([]byte, int(cartHeader.CHARomBlocks)*8192)
+	CHAMemory = buf
+
+	cart := &Cartridge{nil, cartHeader, mapperID,
+		mapper, PRGMemory, CHAMemory, make([]byte, 8192), cartHeader.PGRRomBlocks, cartHeader.CHARomBlocks, Horizontal, hashROM(PRGMemory, CHAMemory, cartHeader.CHARomBlocks)}
+
+	return cart
+}
+
+// LoadCartridge : loads the cart after giving a filepath, exits the program
+// when the file can not be read
+func LoadCartridge(filepath string) *Cartridge {
+	cart, err := OpenCartridge(filepath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cart
+}
+
+// OpenCartridge : loads the cart from an iNES file
+func OpenCartridge(filepath string) (*Cartridge, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCartridge(bufio.NewReader(file))
+}
+
+// ReadCartridge : reads a cart in the iNES format
+func ReadCartridge(r io.Reader) (*Cartridge, error) {
+	bh := make([]byte, 16) // define buffer header
+	if _, err := io.ReadFull(r, bh); err != nil {
+		return nil, fmt.Errorf("reading the iNES header: %v", err)
+	}
+	if string(bh[:4]) != "NES\x1A" {
+		return nil, ErrNotINES
+	}
+
+	cartHeader := &header{
+		[4]byte{bh[0], bh[1], bh[2], bh[3]},
+		bh[4],
+		bh[5],
+
+		bh[6],
+		bh[7],
+		bh[8],
+
+		bh[9],
+		bh[10],
+		[5]byte{bh[11], bh[12], bh[13], bh[14], bh[15]}}
+
+	if cartHeader.mapper1&0x04 != 0 {
+		// skip the trainer
+		if _, err := io.CopyN(ioutil.Discard, r, 512); err != nil {
+			return nil, fmt.Errorf("reading the trainer: %v", err)
+		}
+	}
+
+	mapperID := ((cartHeader.mapper2 >> 4) << 4) | (cartHeader.mapper1 >> 4)
+	mapper, _ := createMapper(mapperID, cartHeader.PGRRomBlocks, cartHeader.CHARomBlocks)
+	mirror := Horizontal
+	if cartHeader.mapper1&0x01 > 0 {
+		mirror = Vertical
+	}
+
+	PRGMemory := make([]byte, int(cartHeader.PGRRomBlocks)*16384)
+	if _, err := io.ReadFull(r, PRGMemory); err != nil {
+		return nil, fmt.Errorf("reading the PRG ROM: %v", err)
+	}
+	var CHAMemory []byte
+	if cartHeader.CHARomBlocks == 0 {
+		// CHR RAM
+		CHAMemory = make([]byte, 8192)
+	} else {
+		CHAMemory = make([]byte, int(cartHeader.CHARomBlocks)*8192)
+		if _, err := io.ReadFull(r, CHAMemory); err != nil {
+			return nil, fmt.Errorf("reading the CHR ROM: %v", err)
+		}
+	}
+
+	cart := &Cartridge{nil, cartHeader, mapperID, mapper, PRGMemory, CHAMemory, prgRAM(cartHeader), cartHeader.PGRRomBlocks, cartHeader.CHARomBlocks, mirror, hashROM(PRGMemory, CHAMemory, cartHeader.CHARomBlocks)}
+
+	return cart, nil
+}
+
+// prgRAM : the PRG RAM at $6000-$7FFF, NES 2.0 headers give its size and can
+// say there is none, iNES ones always get 8KB like most boards had
+func prgRAM(h *header) []byte {
+	if h.mapper2&0x0C != 0x08 {
+		return make([]byte, 8192)
+	}
+	size := 0
+	for _, shift := range []byte{h.TVSystem2 & 0x0F, h.TVSystem2 >> 4} {
+		if shift != 0 {
+			size += 64 << shift
+		}
+	}
+	if size == 0 {
+		return nil
+	}
+	return make([]byte, size)
+}
+
+// hashROM : identifies the ROM a save state belongs to, CHR RAM is left out
+// because its content changes while the game runs
+func hashROM(prg, chr []byte, chrBanks byte) [sha1.Size]byte {
+	h := sha1.New()
+	h.Write(prg)
+	if chrBanks > 0 {
+		h.Write(chr)
+	}
+	var hash [sha1.Size]byte
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// TVSystem : the region the header asks for, from the timing byte of NES 2.0
+// headers or the TV system flag of iNES ones. Games that run on any region
+// get NTSC
+func (c *Cartridge) TVSystem() TVSystem {
+	if c.header.mapper2&0x0C == 0x08 {
+		switch c.header.nes20[1] & 0x03 {
+		case 1:
+			return PAL
+		case 3:
+			return Dendy
+		}
+		return NTSC
+	}
+	if c.header.TVSystem1&0x01 != 0 {
+		return PAL
+	}
+	return NTSC
+}
+
+// VsSystem : true when the header marks the game as a Vs. System one, which
+// runs on an RGB PPU
+func (c *Cartridge) VsSystem() bool {
+	return c.header.mapper2&0x01 != 0
+}
+
+// CPURead : allows the reading of data by the CPU
+func (c *Cartridge) CPURead(address Word) (byte, bool) {
+	if address >= 0x6000 && address <= 0x7FFF && len(c.PRGRam) > 0 {
+		return c.PRGRam[int(address&0x1FFF)%len(c.PRGRam)], true
+	}
+	if mappedAddress, ok := c.mapper.CPUMapRead(address); ok {
+		return c.PRGMemory[mappedAddress], true
+	}
+	return 0, false
+}
+
+// CPUWrite : allows the CPU to write data
+func (c *Cartridge) CPUWrite(address Word, data byte) bool {
+	if address >= 0x6000 && address <= 0x7FFF && len(c.PRGRam) > 0 {
+		c.PRGRam[int(address&0x1FFF)%len(c.PRGRam)] = data
+		return true
+	}
+	if mappedAddress, ok := c.mapper.CPUMapWrite(address, data); ok {
+		c.PRGMemory[mappedAddress] = data
+		return true
+	}
+	return false
+}
+
+// PPURead : reads PPU data
+func (c *Cartridge) PPURead(address Word) (byte, bool) {
+	if mappedAddress, ok := c.mapper.PPUMapRead(address); ok {
+		return c.CHAMemory[mappedAddress], true
+	}
+	return 0, false
+}
+
+// PPUWrite : writes data to PPU
+func (c *Cartridge) PPUWrite(address Word, data byte) bool {
+	if mappedAddress, ok := c.mapper.PPUMapWrite(address); ok {
+		c.CHAMemory[mappedAddress] = data
+		return true
+	}
+	return false
+}
+
+// MapperSupported : false when the board of the cartridge is not emulated and
+// it runs as NROM instead
+func (c *Cartridge) MapperSupported() bool {
+	_, ok := createMapper(c.mapperID, c.PRGBanks, c.CHABanks)
+	return ok
+}
+
+// mirroring : the nametable arrangement, the one of the header unless the
+// mapper switches it
+func (c *Cartridge) mirroring() int {
+	return c.mapper.mirroring(c.Mirror)
+}
+
+// Reset : reset process
+func (c *Cartridge) Reset() {
+	if c != nil && c.mapper != nil {
+		c.mapper.Reset()
+	}
+}
@@ -0,0 +1,155 @@
+package nes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheatComparison : how a search keeps the addresses whose value it compares
+type CheatComparison int
+
+const (
+	// CheatEqual : the value did not change since the last search
+	CheatEqual CheatComparison = iota
+	// CheatChanged : the value changed since the last search
+	CheatChanged
+	// CheatGreater : the value went up since the last search
+	CheatGreater
+	// CheatLess : the value went down since the last search
+	CheatLess
+	// CheatValue : the value is the one given
+	CheatValue
+)
+
+var cheatComparisonNames = [...]string{"eq", "changed", "gt", "lt", "value"}
+
+// String : short name of the comparison
+func (c CheatComparison) String() string {
+	if c < 0 || int(c) >= len(cheatComparisonNames) {
+		return fmt.Sprintf("CheatComparison(%d)", int(c))
+	}
+	return cheatComparisonNames[c]
+}
+
+// ParseCheatComparison : reads eq, changed, gt, lt or value, and the symbols
+// =, !=, > and <
+func ParseCheatComparison(s string) (CheatComparison, error) {
+	switch strings.ToLower(s) {
+	case "eq", "=", "==":
+		return CheatEqual, nil
+	case "changed", "ne", "!=":
+		return CheatChanged, nil
+	case "gt", ">":
+		return CheatGreater, nil
+	case "lt", "<":
+		return CheatLess, nil
+	case "value":
+		return CheatValue, nil
+	}
+	return 0, fmt.Errorf("unknown comparison %q, expected eq, changed, gt, lt or value", s)
+}
+
+// CheatCandidate : an address still matching the search
+type CheatCandidate struct {
+	Address Word
+	// Previous : the value at the search before the last one
+	Previous byte
+	// Value : the value at the last search
+	Value byte
+}
+
+// CheatSearch : finds the address a game keeps something in, like the lives,
+// by snapshotting the work RAM and PRG RAM and keeping the addresses whose
+// value changed the way the player saw it change
+type CheatSearch struct {
+	console    *Console
+	candidates []CheatCandidate
+}
+
+// CreateCheatSearch : starts a search with every address of the work RAM and
+// PRG RAM as a candidate
+func CreateCheatSearch(c *Console) *CheatSearch {
+	s := &CheatSearch{console: c}
+	s.Reset()
+	return s
+}
+
+// Reset : starts over with every address as a candidate
+func (s *CheatSearch) Reset() {
+	s.candidates = s.candidates[:0]
+	add := func(first Word, size int) {
+		for i := 0; i < size; i++ {
+			v := s.peek(first + Word(i))
+			s.candidates = append(s.candidates, CheatCandidate{first + Word(i), v, v})
+		}
+	}
+	add(0x0000, len(s.console.bus.ram))
+	if cart := s.console.bus.cart; cart != nil && len(cart.PRGRam) > 0 {
+		// the rest of a bigger PRG RAM is not in the CPU address space
+		size := len(cart.PRGRam)
+		if size > 0x2000 {
+			size = 0x2000
+		}
+		add(0x6000, size)
+	}
+}
+
+// peek : the searched memory without going through the bus, the candidates
+// keep the PRG RAM of the cartridge in at the last reset so a smaller one
+// inserted since reads as 0 where it has no byte
+func (s *CheatSearch) peek(address Word) byte {
+	if address < 0x6000 {
+		return s.console.bus.ram[address&0x07FF]
+	}
+	cart := s.console.bus.cart
+	if cart == nil || int(address-0x6000) >= len(cart.PRGRam) {
+		return 0
+	}
+	return cart.PRGRam[address-0x6000]
+}
+
+// Filter : keeps the candidates whose value now compares to their value at
+// the last search, or to value for CheatValue, and returns how many are left
+func (s *CheatSearch) Filter(comparison CheatComparison, value byte) int {
+	kept := s.candidates[:0]
+	for _, c := range s.candidates {
+		now := s.peek(c.Address)
+		var keep bool
+		switch comparison {
+		case CheatEqual:
+			keep = now == c.Value
+		case CheatChanged:
+			keep = now != c.Value
+		case CheatGreater:
+			keep = now > c.Value
+		case CheatLess:
+			keep = now < c.Value
+		case CheatValue:
+			keep = now == value
+		}
+		if keep {
+			kept = append(kept, CheatCandidate{c.Address, c.Value, now})
+		}
+	}
+	s.candidates = kept
+	return len(kept)
+}
+
+// Candidates : the addresses still matching
+func (s *CheatSearch) Candidates() []CheatCandidate {
+	return append([]CheatCandidate(nil), s.candidates...)
+}
+
+// Freeze : an address the bus rewrites at the end of every frame
+type Freeze struct {
+	Address Word
+	Value   byte
+}
+
+// applyFreezes : writes the frozen values, the way a Pro Action Replay does
+// once a frame
+func (b *Bus) applyFreezes() {
+	for _, f := range b.freezes {
+		b.memory.Write(f.Address, f.Value)
+	}
+}
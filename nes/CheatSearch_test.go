@@ -0,0 +1,129 @@
+package nes
+
+import (
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// cheatConsole : counts up in $10 and down in $11 once a frame, $6000 keeps
+// the count of $10 too
+func cheatConsole() *Console {
+	program := asm.MustAssemble(`
+		.org $8000
+reset:  LDA #$80
+        STA $11
+@wait:  BIT $2002
+        BPL @wait
+        INC $10
+        DEC $11
+        LDA $10
+        STA $6000
+        JMP @wait
+`)
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge(program.Hex(), 0x8000))
+	nes.RunFrame()
+	return nes
+}
+
+func hasCandidate(s *CheatSearch, address Word) bool {
+	for _, c := range s.Candidates() {
+		if c.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheatSearch(t *testing.T) {
+	nes := cheatConsole()
+	s := CreateCheatSearch(nes)
+	assertTrue(t, len(s.Candidates()) == 2048+8192)
+
+	nes.RunFrame()
+	s.Filter(CheatGreater, 0)
+	assertTrue(t, hasCandidate(s, 0x0010))
+	assertTrue(t, hasCandidate(s, 0x6000))
+	assertFalse(t, hasCandidate(s, 0x0011))
+
+	nes.RunFrame()
+	nes.RunFrame()
+	s.Filter(CheatChanged, 0)
+	s.Filter(CheatValue, nes.RAM()[0x10])
+	for _, c := range s.Candidates() {
+		assertEqualsB(t, nes.RAM()[0x10], c.Value)
+	}
+	assertTrue(t, hasCandidate(s, 0x0010))
+
+	nes.RunFrame()
+	assertTrue(t, s.Filter(CheatEqual, 0) == 0)
+
+	s.Reset()
+	nes.RunFrame()
+	s.Filter(CheatLess, 0)
+	assertTrue(t, hasCandidate(s, 0x0011))
+	assertFalse(t, hasCandidate(s, 0x0010))
+
+	for _, name := range []string{"eq", "changed", "gt", "lt", "value"} {
+		c, err := ParseCheatComparison(name)
+		assertNil(t, err)
+		assertTrue(t, c.String() == name)
+	}
+	_, err := ParseCheatComparison("about")
+	assertTrue(t, err != nil)
+}
+
+func TestCheatSearchCartridgeChanged(t *testing.T) {
+	nes := cheatConsole()
+	s := CreateCheatSearch(nes)
+
+	smaller := TestCartridge("EA", 0x8000)
+	smaller.PRGRam = make([]byte, 0x800)
+	nes.InsertCartridge(smaller)
+	s.Filter(CheatEqual, 0)
+	assertTrue(t, hasCandidate(s, 0x7FFF))
+
+	nes.InsertCartridge(TestCartridge("EA", 0x8000))
+	nes.Cartridge().PRGRam = nil
+	s.Filter(CheatValue, 0)
+	assertTrue(t, hasCandidate(s, 0x7FFF))
+
+	s.Reset()
+	assertTrue(t, len(s.Candidates()) == 2048)
+}
+
+func TestFreeze(t *testing.T) {
+	nes := cheatConsole()
+	assertNil(t, nes.Freeze(0x0010, 0x05))
+	assertEqualsB(t, 0x05, nes.RAM()[0x10])
+	for i := 0; i < 3; i++ {
+		nes.RunFrame()
+		assertEqualsB(t, 0x05, nes.RAM()[0x10])
+	}
+
+	assertNil(t, nes.Freeze(0x0010, 0x20))
+	assertNil(t, nes.Freeze(0x6000, 0x00))
+	assertTrue(t, len(nes.Frozen()) == 2)
+	nes.RunFrame()
+	assertEqualsB(t, 0x20, nes.RAM()[0x10])
+	assertEqualsB(t, 0x00, nes.Cartridge().PRGRam[0])
+
+	nes.Unfreeze(0x0010)
+	nes.RunFrame()
+	nes.RunFrame()
+	assertTrue(t, nes.RAM()[0x10] > 0x20)
+	assertTrue(t, len(nes.Frozen()) == 1)
+
+	// the ROM, the registers and a missing PRG RAM are refused
+	rom, _ := nes.Cartridge().CPURead(0x8000)
+	for _, address := range []Word{0x8000, 0xFFFC, 0x2000, 0x4016} {
+		assertTrue(t, nes.Freeze(address, rom+1) != nil)
+	}
+	after, _ := nes.Cartridge().CPURead(0x8000)
+	assertEqualsB(t, rom, after)
+	nes.InsertCartridge(TestCartridge("EA", 0x8000))
+	nes.Cartridge().PRGRam = nil
+	assertTrue(t, nes.Freeze(0x6000, 0x01) != nil)
+	assertTrue(t, len(nes.Frozen()) == 0)
+}
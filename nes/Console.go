@@ -0,0 +1,458 @@
+// Package nes : a NES emulator that can be embedded in other programs.
+//
+// A Console is the whole machine, load a cartridge into it and run it a
+// frame at a time:
+//
+//	console := nes.CreateConsole()
+//	if err := console.LoadROM("game.nes"); err != nil {
+//		log.Fatal(err)
+//	}
+//	for {
+//		console.SetController(0, nes.ButtonStart)
+//		console.RunFrame()
+//		draw(console.Frame())
+//	}
+package nes
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+)
+
+const (
+	// AudioSampleRate : default samples per second of the audio output
+	AudioSampleRate = 44100
+)
+
+// Console : a whole NES, the bus and everything connected to it.
+// A console owns all of its state, nothing is shared with other consoles, so
+// any number of them can run in the same process, each one in its own
+// goroutine. A single console is not safe for concurrent use.
+type Console struct {
+	bus   *Bus
+	frame *image.RGBA
+	trace io.Writer
+	ntsc  *NTSCFilter
+
+	audio         []float32
+	audioRate     int
+	audioClock    int
+	audioFraction int
+
+	// movie : input recorded or played back a frame at a time
+	movie          *Movie
+	movieRecording bool
+	movieFrame     int
+	movieCommands  MovieCommand
+}
+
+// CreateConsole : creates a console with no cartridge inserted
+func CreateConsole() *Console {
+	return &Console{
+		bus:       CreateBus(CreateCPU(), CreatePPU()),
+		frame:     image.NewRGBA(image.Rect(0, 0, FrameWidth, FrameHeight)),
+		audioRate: AudioSampleRate,
+	}
+}
+
+// InsertCartridge : inserts the cartridge, switches to the region its header
+// asks for and resets the console
+func (c *Console) InsertCartridge(cart *Cartridge) {
+	c.bus.InsertCartridge(cart)
+	c.bus.SetTVSystem(cart.TVSystem())
+	c.Reset()
+}
+
+// SetTVSystem : overrides the region of the cartridge, the timing changes
+// right away so it is best done before the game starts
+func (c *Console) SetTVSystem(r TVSystem) {
+	c.bus.SetTVSystem(r)
+	c.audioFraction = 0
+}
+
+// TVSystem : the region the console runs as
+func (c *Console) TVSystem() TVSystem {
+	return c.bus.tvSystem
+}
+
+// LoadROM : loads the iNES file and inserts it
+func (c *Console) LoadROM(path string) error {
+	cart, err := OpenCartridge(path)
+	if err != nil {
+		return err
+	}
+	c.InsertCartridge(cart)
+	return nil
+}
+
+// Cartridge : the inserted cartridge, nil when there is none
+func (c *Console) Cartridge() *Cartridge {
+	return c.bus.cart
+}
+
+// Reset : presses the reset button
+func (c *Console) Reset() {
+	if c.movieRecording {
+		c.movieCommands |= MovieSoftReset
+	}
+	c.bus.Reset()
+	c.audioClock = 0
+}
+
+// PowerCycle : turns the console off and on, unlike Reset the RAM is cleared
+func (c *Console) PowerCycle() {
+	if c.movieRecording {
+		c.movieCommands |= MovieHardReset
+	}
+	c.bus.PowerCycle()
+	c.audioClock = 0
+}
+
+// RecordMovie : turns the console off and on and records the controllers,
+// resets and power cycles of every frame run from then on into m
+func (c *Console) RecordMovie(m *Movie) {
+	c.StopMovie()
+	c.PowerCycle()
+	m.Frames = m.Frames[:0]
+	c.movie, c.movieRecording, c.movieCommands = m, true, 0
+}
+
+// PlayMovie : turns the console off and on and plays the input of m back, a
+// frame of the movie for every frame run. Check the ROM with m.CheckROM first
+func (c *Console) PlayMovie(m *Movie) {
+	c.StopMovie()
+	if m.PAL {
+		c.SetTVSystem(PAL)
+	} else if c.TVSystem() == PAL {
+		c.SetTVSystem(NTSC)
+	}
+	c.PowerCycle()
+	if len(m.Frames) > 0 {
+		c.movie, c.movieFrame = m, 0
+	}
+}
+
+// StopMovie : stops recording or playing, the movie stays as it is
+func (c *Console) StopMovie() {
+	c.movie, c.movieRecording = nil, false
+}
+
+// MoviePlaying : true while a movie has frames left to play
+func (c *Console) MoviePlaying() bool {
+	return c.movie != nil && !c.movieRecording
+}
+
+// movieInput : records the input of the frame about to run, or sets it from
+// the movie
+func (c *Console) movieInput() {
+	if c.movieRecording {
+		c.movie.Frames = append(c.movie.Frames, MovieFrame{c.movieCommands, [2]Buttons{c.bus.controller[0].buttons, c.bus.controller[1].buttons}})
+		c.movieCommands = 0
+		return
+	}
+	frame := c.movie.Frames[c.movieFrame]
+	if frame.Commands&MovieHardReset != 0 {
+		c.PowerCycle()
+	} else if frame.Commands&MovieSoftReset != 0 {
+		c.Reset()
+	}
+	c.SetController(0, frame.Buttons[0])
+	c.SetController(1, frame.Buttons[1])
+	if c.movieFrame++; c.movieFrame == len(c.movie.Frames) {
+		c.StopMovie()
+	}
+}
+
+// Step : runs a single CPU instruction, nothing happens when there is no
+// cartridge
+func (c *Console) Step() {
+	if c.bus.cart == nil {
+		return
+	}
+	if c.trace == nil {
+		c.bus.ExecuteOperation()
+	} else {
+		for c.bus.cpu.Complete() {
+			c.traceClock()
+		}
+		for !c.bus.cpu.Complete() || !c.bus.cpuClockDue() {
+			c.traceClock()
+		}
+	}
+	c.runAudio()
+}
+
+// RunFrame : runs until the PPU finishes the current frame, nothing happens
+// when there is no cartridge
+func (c *Console) RunFrame() {
+	if c.bus.cart == nil {
+		return
+	}
+	if c.movie != nil {
+		c.movieInput()
+	}
+	if c.trace == nil {
+		c.bus.ExecuteFrame()
+	} else {
+		for !c.bus.ppu.Complete() {
+			c.traceClock()
+		}
+		c.bus.ppu.frameComplete = false
+	}
+	c.runAudio()
+}
+
+// SetTrace : writes the trace line of every instruction to w before running
+// it, nil turns the trace off. Write errors are left to w to report
+func (c *Console) SetTrace(w io.Writer) {
+	c.trace = w
+}
+
+// traceClock : clocks the bus, tracing the instruction the CPU is about to run
+func (c *Console) traceClock() {
+	if c.bus.cpu.Complete() && c.bus.cpuClockDue() {
+		io.WriteString(c.trace, c.bus.TraceLine()+"\n")
+	}
+	c.bus.Clock()
+}
+
+// Jammed : true when the CPU ran into a KIL opcode and halted, until the
+// next reset
+func (c *Console) Jammed() bool {
+	return c.bus.cpu.jammed
+}
+
+// Satisfies : true when the check holds on the console right now, CYC
+// counts the CPU cycles since the last reset
+func (c *Console) Satisfies(e Expectation) bool {
+	return c.bus.expectationValue(e, c.bus.cpu.clockCount) == e.Value
+}
+
+// SetController : sets the buttons held on the controller in port 0 or 1,
+// they stay held until the next call
+func (c *Console) SetController(port int, buttons Buttons) {
+	c.bus.SetController(port, buttons)
+}
+
+// Controller : the buttons held on the controller in port 0 or 1, the ones
+// a movie set while it plays
+func (c *Console) Controller(port int) Buttons {
+	return c.bus.controller[port].buttons
+}
+
+// FrameCount : frames run since power on
+func (c *Console) FrameCount() int {
+	return c.bus.ppu.frameCount
+}
+
+// LagCount : frames since power on during which the game did not read the
+// controllers, the input of those frames was lost
+func (c *Console) LagCount() int {
+	return c.bus.lagCount
+}
+
+// Lagged : true when the last frame was a lag frame
+func (c *Console) Lagged() bool {
+	return c.bus.lagged
+}
+
+// Frame : picture of the last frame. The image belongs to the console and is
+// drawn again on every call
+func (c *Console) Frame() *image.RGBA {
+	if c.ntsc != nil {
+		c.ntsc.Apply(c.bus.ppu.Screen(), c.bus.ppu.frameCount, c.frame)
+	} else {
+		c.bus.ppu.DrawFrame(c.frame)
+	}
+	return c.frame
+}
+
+// SetNTSCFilter : draws Frame through the filter instead of the palette,
+// nil turns it off
+func (c *Console) SetNTSCFilter(f *NTSCFilter) {
+	c.ntsc = f
+}
+
+// SetPalette : changes the colors the picture is drawn with, PaletteFor
+// picks the one that suits the inserted cartridge
+func (c *Console) SetPalette(palette *Palette) {
+	c.bus.ppu.SetPalette(palette)
+}
+
+// SetAudioSampleRate : samples per second returned by ReadAudio
+func (c *Console) SetAudioSampleRate(rate int) {
+	c.audioRate = rate
+	c.audioFraction = 0
+}
+
+// ReadAudio : moves the samples produced so far into samples, mono in the
+// range [-1, 1], and returns how many were copied.
+// The APU is not emulated yet so the samples are silence, they still come at
+// the sample rate so frontends can keep their audio in sync.
+func (c *Console) ReadAudio(samples []float32) int {
+	n := copy(samples, c.audio)
+	c.audio = c.audio[:copy(c.audio, c.audio[n:])]
+	return n
+}
+
+// runAudio : produces the samples for the CPU cycles run since the last call,
+// keeping at most a second of them
+func (c *Console) runAudio() {
+	clocks := c.bus.clockCount - c.audioClock
+	if clocks < 0 {
+		// the bus was reset behind the console's back
+		clocks = c.bus.clockCount
+	}
+	c.audioClock = c.bus.clockCount
+
+	// the bus runs ppuClocks dots every cpuClocks CPU cycles
+	t := c.bus.tvSystem.timing()
+	c.audioFraction += clocks * c.audioRate * t.cpuClocks
+	n := c.audioFraction / (t.ppuClocks * t.cpuClockRate)
+	c.audioFraction %= t.ppuClocks * t.cpuClockRate
+	for i := 0; i < n; i++ {
+		c.audio = append(c.audio, 0)
+	}
+	if len(c.audio) > c.audioRate {
+		c.audio = c.audio[:copy(c.audio, c.audio[len(c.audio)-c.audioRate:])]
+	}
+}
+
+// RAM : the 2KB of internal RAM, changes made to it reach the console
+func (c *Console) RAM() []byte {
+	return c.bus.ram[:]
+}
+
+// PPU : the picture processing unit, for the pattern table, nametable,
+// palette and sprite viewers
+func (c *Console) PPU() *PPU2C02 {
+	return c.bus.ppu
+}
+
+// MemoryMap : the devices on the CPU bus
+func (c *Console) MemoryMap() *MemoryMap {
+	return c.bus.MemoryMap()
+}
+
+// Bus : the bus of the console, to reach the memory and the chips directly
+func (c *Console) Bus() *Bus {
+	return c.bus
+}
+
+// ClockCount : bus clocks since the last reset
+func (c *Console) ClockCount() int {
+	return c.bus.clockCount
+}
+
+// OperationCount : CPU instructions executed since the last reset
+func (c *Console) OperationCount() int {
+	return c.bus.operationCount
+}
+
+// TraceLine : nestest style trace of the next instruction
+func (c *Console) TraceLine() string {
+	return c.bus.TraceLine()
+}
+
+// Disassemble : disassembly of the CPU address space between start and end
+func (c *Console) Disassemble(start, end Word) map[Word]string {
+	return c.bus.cpu.Disassemble(start, end)
+}
+
+// SaveState : writes the state of the console
+func (c *Console) SaveState(w io.Writer) error {
+	return c.bus.SaveState(w)
+}
+
+// LoadState : restores a state written by SaveState
+func (c *Console) LoadState(r io.Reader) error {
+	return c.bus.LoadState(r)
+}
+
+// LoadGameGenie : adds the codes saved for the inserted ROM in the directory,
+// nothing happens when there are none
+func (c *Console) LoadGameGenie(dir string) error {
+	if c.bus.cart == nil {
+		return errors.New("cannot load Game Genie codes without a cartridge")
+	}
+	file, err := os.Open(gameGeniePath(dir, c.bus.cart))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := c.bus.genie.Read(file); err != nil {
+		return fmt.Errorf("%s: %v", file.Name(), err)
+	}
+	return nil
+}
+
+// SaveGameGenie : saves the codes of the inserted ROM in the directory, in a
+// file named after its hash so other ROMs keep their own
+func (c *Console) SaveGameGenie(dir string) error {
+	if c.bus.cart == nil {
+		return errors.New("cannot save Game Genie codes without a cartridge")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(gameGeniePath(dir, c.bus.cart))
+	if err != nil {
+		return err
+	}
+	if err := c.bus.genie.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// GameGenie : the codes patching the inserted cartridge
+func (c *Console) GameGenie() *GameGenie {
+	return &c.bus.genie
+}
+
+// Freeze : keeps an address at a value, it is written right away and again
+// after every frame, freezing an address again changes its value. Only the
+// work RAM and the PRG RAM can be frozen, writing the ROM would patch it for
+// good, Game Genie codes change what the CPU reads from it instead
+func (c *Console) Freeze(address Word, value byte) error {
+	b := c.bus
+	switch {
+	case address < 0x2000:
+	case address >= 0x6000 && address < 0x8000 && b.cart != nil && len(b.cart.PRGRam) > 0:
+	case address >= 0x8000:
+		return fmt.Errorf("cannot freeze $%04X in the ROM, use a Game Genie code instead", address)
+	default:
+		return fmt.Errorf("cannot freeze $%04X, only the RAM and the PRG RAM can be frozen", address)
+	}
+	b.memory.Write(address, value)
+	for i := range b.freezes {
+		if b.freezes[i].Address == address {
+			b.freezes[i].Value = value
+			return nil
+		}
+	}
+	b.freezes = append(b.freezes, Freeze{address, value})
+	return nil
+}
+
+// Unfreeze : lets the game change the address again
+func (c *Console) Unfreeze(address Word) {
+	b := c.bus
+	for i := range b.freezes {
+		if b.freezes[i].Address == address {
+			b.freezes = append(b.freezes[:i], b.freezes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Frozen : the addresses kept at a value
+func (c *Console) Frozen() []Freeze {
+	return append([]Freeze(nil), c.bus.freezes...)
+}
@@ -0,0 +1,231 @@
+package nes
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+func TestConsolesRunConcurrently(t *testing.T) {
+	run := func() *Console {
+		nes := CreateConsole()
+		nes.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+		for i := 0; i < 3; i++ {
+			nes.RunFrame()
+		}
+		return nes
+	}
+	want := run()
+
+	consoles := make([]*Console, 4)
+	var wg sync.WaitGroup
+	for i := range consoles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			consoles[i] = run()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, nes := range consoles {
+		assertTrue(t, nes.ClockCount() == want.ClockCount())
+		assertTrue(t, nes.OperationCount() == want.OperationCount())
+		if nes.TraceLine() != want.TraceLine() || nes.bus.ram != want.bus.ram {
+			t.Errorf("Expected: %q, got: %q", want.TraceLine(), nes.TraceLine())
+		}
+	}
+
+	// the counters belong to each console
+	other := CreateConsole()
+	other.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+	// the first step finishes the reset
+	other.Step()
+	other.Step()
+	assertTrue(t, other.OperationCount() == 1)
+	assertTrue(t, want.OperationCount() > 1)
+}
+
+func TestConsoleFrame(t *testing.T) {
+	program := asm.MustAssemble(`
+		.org $8000
+reset:  LDA #$3F
+        STA $2006
+        LDA #$00
+        STA $2006
+        LDA #$21
+        STA $2007       ; backdrop
+        LDA #$16
+        STA $2007       ; color 1 of the first palette
+        LDA #$00
+        STA $2000
+        STA $2005
+        STA $2005
+        LDA #$08        ; background on, hidden in the left 8 pixels
+        STA $2001
+@loop:  JMP @loop
+`)
+	cart := TestCartridge(program.Hex(), 0x8000)
+	// tile 0 is made of color 1 only, the nametables are all tile 0
+	for i := 0; i < 8; i++ {
+		cart.CHAMemory[i] = 0xFF
+	}
+
+	nes := CreateConsole()
+	nes.InsertCartridge(cart)
+	for i := 0; i < 3; i++ {
+		nes.RunFrame()
+	}
+	frame := nes.Frame()
+	assertTrue(t, frame.Bounds().Dx() == FrameWidth && frame.Bounds().Dy() == FrameHeight)
+
+	backdrop, tile := *defaultColors[0x21], *defaultColors[0x16]
+	for _, p := range []struct {
+		x, y int
+		want color.RGBA
+	}{{0, 0, backdrop}, {7, 120, backdrop}, {8, 120, tile}, {128, 0, tile}, {255, 239, tile}} {
+		if got := frame.RGBAAt(p.x, p.y); got != p.want {
+			t.Errorf("Expected %v at %d,%d, got: %v", p.want, p.x, p.y, got)
+		}
+	}
+}
+
+func TestConsoleAudio(t *testing.T) {
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+	nes.RunFrame()
+	nes.RunFrame()
+
+	// about 735 samples a frame at 44100Hz
+	samples := make([]float32, 4096)
+	n := nes.ReadAudio(samples)
+	assertTrue(t, n > 2*700 && n < 2*760)
+	for _, s := range samples[:n] {
+		assertTrue(t, s == 0)
+	}
+	assertTrue(t, nes.ReadAudio(samples) == 0)
+
+	nes.SetAudioSampleRate(22050)
+	nes.RunFrame()
+	n = nes.ReadAudio(samples)
+	assertTrue(t, n > 350 && n < 380)
+}
+
+func TestConsoleLoadROM(t *testing.T) {
+	nes := CreateConsole()
+	assertTrue(t, nes.LoadROM("../test/roms/missing.nes") != nil)
+	assertTrue(t, nes.Cartridge() == nil)
+
+	_, err := ReadCartridge(bytes.NewReader([]byte("PK\x03\x04 not a rom at all")))
+	assertTrue(t, errors.Is(err, ErrNotINES))
+
+	header := []byte{'N', 'E', 'S', 0x1A, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	_, err = ReadCartridge(bytes.NewReader(append(header, make([]byte, 16384)...)))
+	assertTrue(t, err != nil)
+
+	cart, err := ReadCartridge(bytes.NewReader(append(header, make([]byte, 2*16384+8192)...)))
+	assertNil(t, err)
+	assertTrue(t, len(cart.PRGMemory) == 2*16384 && len(cart.CHAMemory) == 8192)
+}
+
+func TestConsoleWithoutCartridge(t *testing.T) {
+	nes := CreateConsole()
+	nes.Reset()
+	nes.Step()
+	nes.RunFrame()
+	assertTrue(t, nes.FrameCount() == 0)
+	assertTrue(t, nes.SaveGameGenie("cheats") != nil)
+	assertTrue(t, nes.LoadGameGenie("cheats") != nil)
+}
+
+func TestConsoleJammed(t *testing.T) {
+	program := asm.MustAssemble(`
+		.org $8000
+reset:  LDA #$80
+        STA $2000       ; NMI on, the jammed CPU ignores it
+        LDX #$05
+        .byte $02       ; KIL
+        INX
+`)
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge(program.Hex(), 0x8000))
+	nes.RunFrame()
+	nes.RunFrame()
+	assertTrue(t, nes.Jammed())
+	assertEqualsW(t, 0x8007, nes.bus.cpu.pc)
+	assertEqualsB(t, 0x05, nes.bus.cpu.x)
+	assertTrue(t, nes.Satisfies(Expectation{"PC", 0x8007}))
+	assertTrue(t, nes.Satisfies(Expectation{"X", 0x05}))
+	assertFalse(t, nes.Satisfies(Expectation{"X", 0x06}))
+
+	nes.Reset()
+	assertFalse(t, nes.Jammed())
+}
+
+func TestConsoleTrace(t *testing.T) {
+	traced := CreateConsole()
+	traced.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+	plain := CreateConsole()
+	plain.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+
+	var trace bytes.Buffer
+	traced.SetTrace(&trace)
+	// the first step only finishes the reset
+	traced.Step()
+	plain.Step()
+	var want []string
+	for i := 0; i < 5; i++ {
+		want = append(want, plain.TraceLine())
+		traced.Step()
+		plain.Step()
+	}
+	if got := strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n"); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected: %q, got: %q", want, got)
+	}
+
+	traced.RunFrame()
+	plain.RunFrame()
+	assertTrue(t, traced.TraceLine() == plain.TraceLine())
+	assertTrue(t, strings.Count(trace.String(), "\n") == plain.OperationCount())
+}
+
+func TestConsoleLagFrames(t *testing.T) {
+	// reads the controller on odd frames only
+	program := asm.MustAssemble(`
+		.org $8000
+reset:  INC $10
+        LDA $10
+        AND #$01
+        BEQ @wait
+        LDA $4016
+@wait:  BIT $2002
+        BPL @wait
+        JMP reset
+`)
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge(program.Hex(), 0x8000))
+	nes.RunFrame()
+	frames, lag := nes.FrameCount(), nes.LagCount()
+	for i := 0; i < 10; i++ {
+		nes.RunFrame()
+		assertTrue(t, nes.LagCount()-lag == (i+1)/2 || nes.LagCount()-lag == (i+2)/2)
+	}
+	assertTrue(t, nes.FrameCount() == frames+10)
+	assertTrue(t, nes.LagCount()-lag == 5)
+
+	// a save state brings the counter back, a power cycle clears it
+	var state bytes.Buffer
+	assertNil(t, nes.SaveState(&state))
+	count := nes.LagCount()
+	nes.RunFrame()
+	nes.RunFrame()
+	assertNil(t, nes.LoadState(&state))
+	assertTrue(t, nes.LagCount() == count)
+	nes.PowerCycle()
+	assertTrue(t, nes.LagCount() == 0 && nes.FrameCount() == 0 && !nes.Lagged())
+}
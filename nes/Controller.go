@@ -0,0 +1,117 @@
+package nes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Buttons : state of the buttons of a standard controller, one bit per button
+// in the order they are shifted out of $4016/$4017
+type Buttons byte
+
+const (
+	// ButtonA : A button
+	ButtonA Buttons = 0x80
+	// ButtonB : B button
+	ButtonB Buttons = 0x40
+	// ButtonSelect : Select button
+	ButtonSelect Buttons = 0x20
+	// ButtonStart : Start button
+	ButtonStart Buttons = 0x10
+	// ButtonUp : up on the d-pad
+	ButtonUp Buttons = 0x08
+	// ButtonDown : down on the d-pad
+	ButtonDown Buttons = 0x04
+	// ButtonLeft : left on the d-pad
+	ButtonLeft Buttons = 0x02
+	// ButtonRight : right on the d-pad
+	ButtonRight Buttons = 0x01
+)
+
+// buttonNames : names of the buttons from bit 7 down to bit 0
+var buttonNames = [8]string{"a", "b", "select", "start", "up", "down", "left", "right"}
+
+// ParseButtons : reads buttons written as names joined by "+", like
+// "a+right", "-" is no button at all
+func ParseButtons(s string) (Buttons, error) {
+	var buttons Buttons
+	if s == "-" || s == "" {
+		return buttons, nil
+	}
+	for _, name := range strings.Split(strings.ToLower(s), "+") {
+		found := false
+		for i, button := range buttonNames {
+			if name == button {
+				buttons |= 0x80 >> uint(i)
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown button %q", name)
+		}
+	}
+	return buttons, nil
+}
+
+// String : the buttons in the format read by ParseButtons
+func (b Buttons) String() string {
+	var names []string
+	for i, button := range buttonNames {
+		if b&(0x80>>uint(i)) != 0 {
+			names = append(names, button)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, "+")
+}
+
+// Display : the buttons held as the fixed columns of an input display, like
+// "<  v  BA" for down-left with B and A held. The columns are left, up, right,
+// down, Select, Start, B and A
+func (b Buttons) Display() string {
+	columns := [8]struct {
+		button Buttons
+		symbol byte
+	}{
+		{ButtonLeft, '<'}, {ButtonUp, '^'}, {ButtonRight, '>'}, {ButtonDown, 'v'},
+		{ButtonSelect, 's'}, {ButtonStart, 'S'}, {ButtonB, 'B'}, {ButtonA, 'A'},
+	}
+	display := []byte("        ")
+	for i, c := range columns {
+		if b&c.button != 0 {
+			display[i] = c.symbol
+		}
+	}
+	return string(display)
+}
+
+// controllerPort : a standard controller plugged into $4016 or $4017
+type controllerPort struct {
+	// buttons : buttons held right now
+	buttons Buttons
+	// shifter : buttons latched by the last strobe, shifted out a bit per read
+	shifter byte
+	strobe  bool
+}
+
+// write : bit 0 of $4016 reloads the shifter while it is set
+func (c *controllerPort) write(data byte) {
+	c.strobe = data&0x01 != 0
+	if c.strobe {
+		c.shifter = byte(c.buttons)
+	}
+}
+
+// read : next button of the report, 1 once all eight were read
+func (c *controllerPort) read(readOnly bool) byte {
+	if c.strobe {
+		c.shifter = byte(c.buttons)
+	}
+	d := c.shifter >> 7
+	if !readOnly {
+		c.shifter = c.shifter<<1 | 0x01
+	}
+	return d
+}
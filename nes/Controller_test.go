@@ -0,0 +1,62 @@
+package nes
+
+import (
+	"testing"
+)
+
+func TestControllerReport(t *testing.T) {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.SetController(0, ButtonA|ButtonStart|ButtonLeft)
+	nes.SetController(1, ButtonB)
+
+	nes.CPUWrite(0x4016, 0x01)
+	nes.CPUWrite(0x4016, 0x00)
+	// A, B, Select, Start, Up, Down, Left, Right and then only ones
+	one := []byte{1, 0, 0, 1, 0, 0, 1, 0, 1, 1}
+	two := []byte{0, 1, 0, 0, 0, 0, 0, 0, 1, 1}
+	for i := range one {
+		peek, _ := nes.CPURead(0x4016, true)
+		d, _ := nes.CPURead(0x4016, false)
+		assertEqualsB(t, d, peek)
+		assertEqualsB(t, one[i], d)
+		d, _ = nes.CPURead(0x4017, false)
+		assertEqualsB(t, two[i], d)
+	}
+
+	// while the strobe is held the report keeps restarting
+	nes.CPUWrite(0x4016, 0x01)
+	for i := 0; i < 3; i++ {
+		d, _ := nes.CPURead(0x4016, false)
+		assertEqualsB(t, 1, d)
+	}
+	nes.SetController(0, ButtonB)
+	d, _ := nes.CPURead(0x4016, false)
+	assertEqualsB(t, 0, d)
+}
+
+func TestParseButtons(t *testing.T) {
+	for text, want := range map[string]Buttons{
+		"-":             0,
+		"a":             ButtonA,
+		"Start":         ButtonStart,
+		"a+b+up":        ButtonA | ButtonB | ButtonUp,
+		"select+down":   ButtonSelect | ButtonDown,
+		"left+right+up": ButtonLeft | ButtonRight | ButtonUp,
+	} {
+		got, err := ParseButtons(text)
+		assertNil(t, err)
+		assertEqualsB(t, byte(want), byte(got))
+		again, _ := ParseButtons(got.String())
+		assertEqualsB(t, byte(want), byte(again))
+	}
+	assertTrue(t, (ButtonA|ButtonRight).String() == "a+right")
+
+	_, err := ParseButtons("a+turbo")
+	assertTrue(t, err != nil)
+}
+
+func TestButtonsDisplay(t *testing.T) {
+	assertTrue(t, Buttons(0).Display() == "        ")
+	assertTrue(t, (ButtonLeft|ButtonDown|ButtonB|ButtonA).Display() == "<  v  BA")
+	assertTrue(t, Buttons(0xFF).Display() == "<^>vsSBA")
+}
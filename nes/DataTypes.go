@@ -1,9 +1,4 @@
-package main
-
-import (
-	"reflect"
-	"runtime"
-)
+package nes
 
 // Flag : int that defines which register flag is active
 type Flag int
@@ -63,21 +58,10 @@ type Operate func(c *CPU6502) byte
 // Addressing : opcode addressing mode
 type Addressing func(c *CPU6502) byte
 
-// FnEquals : checks if both functions have the same name
-func FnEquals(fn interface{}, target interface{}) bool {
-	return fnName(fn) == fnName(target)
-}
-
-// fnName : returns the name of the function
-func fnName(fn interface{}) string {
-	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
-	return name
-}
-
 // Instruction : definition of an opcode/instruction
 type Instruction struct {
-	name        string
-	operate     Operate
-	addressmode Addressing
-	cycles      byte
+	name    string
+	operate Operate
+	mode    AddressMode
+	cycles  byte
 }
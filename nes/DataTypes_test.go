@@ -1,17 +1,9 @@
-package main
+package nes
 
 import (
 	"testing"
 )
 
-func TestFnEquals(t *testing.T) {
-	fn := IMM
-
-	if got := FnEquals(fn, IMM); got != true {
-		t.Errorf("Expected: %t, got: %t", true, got)
-	}
-}
-
 func TestLoopyRegister(t *testing.T) {
 
 	l := CreateLoopyRegister()
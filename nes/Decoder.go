@@ -0,0 +1,206 @@
+package nes
+
+import (
+	"bytes"
+	"strings"
+)
+
+// AddressMode : addressing mode used by an instruction
+type AddressMode byte
+
+const (
+	// ModeIMP : Implied, no operand
+	ModeIMP AddressMode = iota
+	// ModeACC : Accumulator, works on register A
+	ModeACC
+	// ModeIMM : Immediate, #$nn
+	ModeIMM
+	// ModeZP0 : Zero page, $nn
+	ModeZP0
+	// ModeZPX : Zero page indexed by X, $nn,X
+	ModeZPX
+	// ModeZPY : Zero page indexed by Y, $nn,Y
+	ModeZPY
+	// ModeREL : Relative, used by branches
+	ModeREL
+	// ModeABS : Absolute, $nnnn
+	ModeABS
+	// ModeABX : Absolute indexed by X, $nnnn,X
+	ModeABX
+	// ModeABY : Absolute indexed by Y, $nnnn,Y
+	ModeABY
+	// ModeIND : Indirect, ($nnnn), only used by JMP
+	ModeIND
+	// ModeIZX : Indexed indirect, ($nn,X)
+	ModeIZX
+	// ModeIZY : Indirect indexed, ($nn),Y
+	ModeIZY
+)
+
+// addressModeInfo : how an addressing mode is executed and encoded
+type addressModeInfo struct {
+	name     string
+	address  Addressing
+	operands Word
+}
+
+var (
+	addressModes = [...]addressModeInfo{
+		ModeIMP: {"IMP", IMP, 0},
+		ModeACC: {"ACC", IMP, 0},
+		ModeIMM: {"IMM", IMM, 1},
+		ModeZP0: {"ZP0", ZP0, 1},
+		ModeZPX: {"ZPX", ZPX, 1},
+		ModeZPY: {"ZPY", ZPY, 1},
+		ModeREL: {"REL", REL, 1},
+		ModeABS: {"ABS", ABS, 2},
+		ModeABX: {"ABX", ABX, 2},
+		ModeABY: {"ABY", ABY, 2},
+		ModeIND: {"IND", IND, 2},
+		ModeIZX: {"IZX", IZX, 1},
+		ModeIZY: {"IZY", IZY, 1},
+	}
+)
+
+// String : short name of the addressing mode, e.g. "ZPX"
+func (m AddressMode) String() string {
+	return addressModes[m].name
+}
+
+// Operands : number of operand bytes that follow the opcode
+func (m AddressMode) Operands() Word {
+	return addressModes[m].operands
+}
+
+// Decoded : an instruction decoded from memory
+type Decoded struct {
+	Address  Word
+	Opcode   byte
+	Mnemonic string
+	Mode     AddressMode
+	Operands []byte
+	// Illegal : the opcode is not part of the official 6502 instruction set
+	Illegal bool
+	// Operand : operand bytes as a single value
+	Operand Word
+	// Pointer : intermediate address read by IZX and IZY
+	Pointer Word
+	// Target : effective address, branch destination or jump destination
+	Target Word
+	// HasTarget : false for modes that do not touch memory (IMP, ACC, IMM)
+	HasTarget bool
+}
+
+// Length : total bytes used by the instruction, opcode included
+func (d *Decoded) Length() Word {
+	return 1 + d.Mode.Operands()
+}
+
+// Decode : decodes the instruction at the given address without side effects,
+// indexed targets are computed with the current value of the registers
+func (c *CPU6502) Decode(address Word) Decoded {
+	return decode(address, c.bus.peek, c.x, c.y)
+}
+
+// decode : decodes the instruction at the given address reading memory through
+// read, x and y are only used to compute indexed targets
+func decode(address Word, read func(Word) byte, x, y byte) Decoded {
+	opcode := read(address)
+	inst := opCodes[opcode]
+
+	// pointers wrap inside their page like the 6502 does
+	readWord := func(address Word) Word {
+		return Word(read(address&0xFF00|(address+1)&0x00FF))<<8 | Word(read(address))
+	}
+
+	d := Decoded{
+		Address:  address,
+		Opcode:   opcode,
+		Mnemonic: strings.TrimPrefix(inst.name, "*"),
+		Mode:     inst.mode,
+		Illegal:  strings.HasPrefix(inst.name, "*"),
+	}
+	d.Operands = make([]byte, inst.mode.Operands())
+	for i := range d.Operands {
+		d.Operands[i] = read(address + 1 + Word(i))
+		d.Operand |= Word(d.Operands[i]) << (8 * uint(i))
+	}
+
+	d.HasTarget = true
+	switch d.Mode {
+	case ModeIMP, ModeACC, ModeIMM:
+		d.HasTarget = false
+	case ModeZP0, ModeABS:
+		d.Target = d.Operand
+	case ModeZPX:
+		d.Target = (d.Operand + Word(x)) & 0x00FF
+	case ModeZPY:
+		d.Target = (d.Operand + Word(y)) & 0x00FF
+	case ModeABX:
+		d.Target = d.Operand + Word(x)
+	case ModeABY:
+		d.Target = d.Operand + Word(y)
+	case ModeREL:
+		d.Target = address + 2 + Word(int8(d.Operand))
+	case ModeIND:
+		d.Pointer = d.Operand
+		d.Target = readWord(d.Operand)
+	case ModeIZX:
+		d.Pointer = (d.Operand + Word(x)) & 0x00FF
+		d.Target = readWord(d.Pointer)
+	case ModeIZY:
+		d.Pointer = readWord(d.Operand)
+		d.Target = d.Pointer + Word(y)
+	}
+	return d
+}
+
+// IsJump : instructions that change the program counter to the target
+// instead of accessing memory there
+func (d *Decoded) IsJump() bool {
+	return d.Opcode == 0x20 || d.Opcode == 0x4C || d.Opcode == 0x6C || d.Mode == ModeREL
+}
+
+// String : human readable form of the instruction, prefixed by its address
+// example: "$C000: JMP $C5F5 {ABS}"
+func (d *Decoded) String() string {
+	var sInst bytes.Buffer
+	sInst.WriteString("$")
+	sInst.WriteString(Hex(uint32(d.Address), 4))
+	sInst.WriteString(": ")
+	if d.Illegal {
+		sInst.WriteByte('*')
+	}
+	sInst.WriteString(d.Mnemonic)
+	sInst.WriteByte(' ')
+
+	switch d.Mode {
+	case ModeACC:
+		sInst.WriteString("A ")
+	case ModeIMM:
+		sInst.WriteString("#$" + Hex(uint32(d.Operand), 2) + " ")
+	case ModeZP0:
+		sInst.WriteString("$" + Hex(uint32(d.Operand), 2) + " ")
+	case ModeZPX:
+		sInst.WriteString("$" + Hex(uint32(d.Operand), 2) + ", X ")
+	case ModeZPY:
+		sInst.WriteString("$" + Hex(uint32(d.Operand), 2) + ", Y ")
+	case ModeIZX:
+		sInst.WriteString("($" + Hex(uint32(d.Operand), 2) + ", X) ")
+	case ModeIZY:
+		sInst.WriteString("($" + Hex(uint32(d.Operand), 2) + "), Y ")
+	case ModeABS:
+		sInst.WriteString("$" + Hex(uint32(d.Operand), 4) + " ")
+	case ModeABX:
+		sInst.WriteString("$" + Hex(uint32(d.Operand), 4) + ", X ")
+	case ModeABY:
+		sInst.WriteString("$" + Hex(uint32(d.Operand), 4) + ", Y ")
+	case ModeIND:
+		sInst.WriteString("($" + Hex(uint32(d.Operand), 4) + ") ")
+	case ModeREL:
+		sInst.WriteString("$" + Hex(uint32(d.Operand), 4) + " [$" + Hex(uint32(d.Target), 4) + "] ")
+	}
+
+	sInst.WriteString("{" + d.Mode.String() + "}")
+	return sInst.String()
+}
@@ -0,0 +1,79 @@
+package nes
+
+import (
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	// LDA ($80),Y | JMP ($0200) | BNE -4 | ASL A | NOP $44 (illegal) | LDA $10,X
+	nes.InsertCartridge(TestCartridge("B1 80 6C 00 02 D0 FC 0A 04 44 B5 10", 0x8000))
+	nes.Reset()
+	nes.cpu.x = 0x03
+	nes.cpu.y = 0x10
+	nes.ram[0x80] = 0x00
+	nes.ram[0x81] = 0x03
+	nes.ram[0x0200] = 0x34
+	nes.ram[0x0201] = 0x12
+
+	d := nes.cpu.Decode(0x8000)
+	if d.Mnemonic != "LDA" || d.Mode != ModeIZY || d.Length() != 2 {
+		t.Errorf("unexpected decode %+v", d)
+	}
+	assertEqualsW(t, 0x0300, d.Pointer)
+	assertEqualsW(t, 0x0310, d.Target)
+
+	d = nes.cpu.Decode(0x8002)
+	assertTrue(t, d.Mode == ModeIND)
+	assertTrue(t, d.IsJump())
+	assertEqualsW(t, 0x0200, d.Operand)
+	assertEqualsW(t, 0x1234, d.Target)
+
+	d = nes.cpu.Decode(0x8005)
+	assertTrue(t, d.Mode == ModeREL)
+	assertEqualsW(t, 0x8003, d.Target)
+
+	d = nes.cpu.Decode(0x8007)
+	assertTrue(t, d.Mode == ModeACC)
+	assertFalse(t, d.HasTarget)
+	assertEqualsW(t, 1, d.Length())
+
+	d = nes.cpu.Decode(0x8008)
+	assertTrue(t, d.Illegal)
+	assertTrue(t, d.Mode == ModeZP0)
+	if d.Mnemonic != "NOP" || len(d.Operands) != 1 || d.Operands[0] != 0x44 {
+		t.Errorf("unexpected decode %+v", d)
+	}
+
+	d = nes.cpu.Decode(0x800A)
+	assertEqualsW(t, 0x0013, d.Target)
+}
+
+func TestDisassemble(t *testing.T) {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(TestCartridge("A9 3F 8D 06 20 0A D0 F8", 0x8000))
+
+	asm := nes.cpu.Disassemble(0x8000, 0x8007)
+	want := map[Word]string{
+		0x8000: "$8000: LDA #$3F {IMM}",
+		0x8002: "$8002: STA $2006 {ABS}",
+		0x8005: "$8005: ASL A {ACC}",
+		0x8006: "$8006: BNE $00F8 [$8000] {REL}",
+	}
+	if len(asm) != len(want) {
+		t.Errorf("Expected %d lines, got: %v", len(want), asm)
+	}
+	for address, line := range want {
+		if asm[address] != line {
+			t.Errorf("Expected: %q, got: %q", line, asm[address])
+		}
+	}
+}
+
+func BenchmarkDisassemble(b *testing.B) {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(LoadCartridge(nestestROM))
+	for i := 0; i < b.N; i++ {
+		nes.cpu.Disassemble(0x0000, 0xFFFF)
+	}
+}
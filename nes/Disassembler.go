@@ -0,0 +1,303 @@
+package nes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// unknownByte : never reached by the program flow, written as data
+	unknownByte = iota
+	// opcodeByte : first byte of an instruction
+	opcodeByte
+	// operandByte : operand of the instruction before it
+	operandByte
+)
+
+// Listing : PRG bank split into code and data by following the program flow
+// from the interrupt vectors through every JSR, JMP and branch target
+type Listing struct {
+	Origin Word
+	PRG    []byte
+	Labels map[Word]string
+	kind   []byte
+}
+
+// Region : contiguous run of code or data inside a listing
+type Region struct {
+	Start, End Word // End is inclusive
+	Code       bool
+}
+
+// DisassembleCartridge : disassembles the PRG bank of a NROM cartridge, which is
+// mapped so its last byte sits at $FFFF
+func DisassembleCartridge(c *Cartridge) *Listing {
+	return DisassemblePRG(c.PRGMemory, Word(0x10000-len(c.PRGMemory)))
+}
+
+// DisassemblePRG : follows the program flow of a PRG bank loaded at origin,
+// seeded from the NMI, reset and IRQ vectors when the bank holds them
+func DisassemblePRG(prg []byte, origin Word) *Listing {
+	l := &Listing{
+		Origin: origin,
+		PRG:    prg,
+		Labels: make(map[Word]string),
+		kind:   make([]byte, len(prg)),
+	}
+
+	var queue []Word
+	visit := func(target Word, label string) {
+		target, ok := l.canonical(target)
+		if !ok {
+			return
+		}
+		if current, ok := l.Labels[target]; !ok || strings.HasPrefix(current, "L_") && label != "" && !strings.HasPrefix(label, "L_") {
+			l.Labels[target] = label
+		}
+		queue = append(queue, target)
+	}
+
+	if l.hasVectors() {
+		for i, name := range []string{"nmi", "reset", "irq"} {
+			vector := Word(0xFFFA + 2*i)
+			visit(Word(l.read(vector+1))<<8|Word(l.read(vector)), name)
+		}
+	}
+
+	for len(queue) > 0 {
+		pc := queue[0]
+		queue = queue[1:]
+
+		for {
+			offset, ok := l.offset(pc)
+			if !ok || l.kind[offset] != unknownByte {
+				break
+			}
+
+			d := decode(pc, l.read, 0, 0)
+			// BRK, KIL and the unofficial opcodes are almost always data
+			// reached by a wrong guess, so the path stops there
+			if d.Illegal || d.Opcode == 0x00 || !l.free(offset, int(d.Length())) {
+				break
+			}
+			l.kind[offset] = opcodeByte
+			for i := 1; i < int(d.Length()); i++ {
+				l.kind[offset+i] = operandByte
+			}
+
+			stop := false
+			switch {
+			case d.Opcode == 0x20: // JSR
+				visit(d.Target, fmt.Sprintf("sub_%s", Hex(uint32(d.Target), 4)))
+			case d.Opcode == 0x4C: // JMP absolute
+				visit(d.Target, fmt.Sprintf("L_%s", Hex(uint32(d.Target), 4)))
+				stop = true
+			case d.Mode == ModeREL:
+				visit(d.Target, fmt.Sprintf("L_%s", Hex(uint32(d.Target), 4)))
+			case d.Opcode == 0x6C || d.Opcode == 0x40 || d.Opcode == 0x60: // JMP indirect, RTI, RTS
+				stop = true
+			}
+			if stop {
+				break
+			}
+			pc += d.Length()
+		}
+	}
+
+	return l
+}
+
+// hasVectors : true when the bank ends at $FFFF
+func (l *Listing) hasVectors() bool {
+	return int(l.Origin)+len(l.PRG) == 0x10000 && len(l.PRG) >= 6
+}
+
+// offset : position of a CPU address inside the bank
+func (l *Listing) offset(address Word) (int, bool) {
+	offset := int(address) - int(l.Origin)
+	return offset, offset >= 0 && offset < len(l.PRG)
+}
+
+// canonical : maps a CPU address to the bank, following the mirroring of a
+// 16K bank over $8000-$FFFF
+func (l *Listing) canonical(address Word) (Word, bool) {
+	if _, ok := l.offset(address); ok {
+		return address, true
+	}
+	if address >= 0x8000 && len(l.PRG) > 0 && 0x8000%len(l.PRG) == 0 {
+		mirrored := Word(int(l.Origin) + int(address-0x8000)%len(l.PRG))
+		_, ok := l.offset(mirrored)
+		return mirrored, ok
+	}
+	return 0, false
+}
+
+// read : reads the bank using CPU addresses, anything outside of it is 0
+func (l *Listing) read(address Word) byte {
+	if offset, ok := l.offset(address); ok {
+		return l.PRG[offset]
+	}
+	return 0
+}
+
+// free : checks if n bytes starting at offset are inside the bank and not
+// already claimed by another instruction
+func (l *Listing) free(offset, n int) bool {
+	if offset+n > len(l.PRG) {
+		return false
+	}
+	for i := offset; i < offset+n; i++ {
+		if l.kind[i] != unknownByte {
+			return false
+		}
+	}
+	return true
+}
+
+// IsCode : checks if the address was reached by the program flow
+func (l *Listing) IsCode(address Word) bool {
+	offset, ok := l.offset(address)
+	return ok && l.kind[offset] != unknownByte
+}
+
+// Regions : the bank split in contiguous code and data runs
+func (l *Listing) Regions() []Region {
+	var regions []Region
+	for i := range l.PRG {
+		code := l.kind[i] != unknownByte
+		address := l.Origin + Word(i)
+		if n := len(regions); n > 0 && regions[n-1].Code == code {
+			regions[n-1].End = address
+		} else {
+			regions = append(regions, Region{address, address, code})
+		}
+	}
+	return regions
+}
+
+// Source : writes the listing as ca65 source that assembles back to the
+// very same bytes
+func (l *Listing) Source(w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, "; Disassembled by GoNES")
+	fmt.Fprintln(out, ".setcpu \"6502\"")
+	fmt.Fprintln(out)
+
+	// the vector table is written as words when nothing else claimed it
+	vectors := len(l.PRG)
+	if l.hasVectors() && l.free(len(l.PRG)-6, 6) {
+		vectors = len(l.PRG) - 6
+	}
+
+	// labels that do not start a line, because they point into the middle of
+	// an instruction or into the vector table, are written as equates
+	var equates []Word
+	for address := range l.Labels {
+		if offset, ok := l.offset(address); !ok || offset >= vectors || l.kind[offset] == operandByte {
+			equates = append(equates, address)
+		}
+	}
+	sort.Slice(equates, func(i, j int) bool { return equates[i] < equates[j] })
+	for _, address := range equates {
+		fmt.Fprintf(out, "%s = $%s\n", l.Labels[address], Hex(uint32(address), 4))
+	}
+	if len(equates) > 0 {
+		fmt.Fprintln(out)
+	}
+
+	fmt.Fprintf(out, ".org $%s\n", Hex(uint32(l.Origin), 4))
+
+	var data []string
+	flush := func() {
+		if len(data) > 0 {
+			fmt.Fprintf(out, "    .byte %s\n", strings.Join(data, ","))
+			data = data[:0]
+		}
+	}
+
+	for i := 0; i < vectors; {
+		address := l.Origin + Word(i)
+		if label, ok := l.Labels[address]; ok {
+			flush()
+			fmt.Fprintf(out, "\n%s:\n", label)
+		}
+
+		if l.kind[i] == opcodeByte {
+			flush()
+			d := decode(address, l.read, 0, 0)
+			fmt.Fprintf(out, "    %s\n", l.instruction(&d))
+			i += int(d.Length())
+			continue
+		}
+
+		data = append(data, "$"+Hex(uint32(l.PRG[i]), 2))
+		if len(data) == 16 {
+			flush()
+		}
+		i++
+	}
+	flush()
+
+	if vectors < len(l.PRG) {
+		var words []string
+		for vector := Word(0xFFFA); vector != 0x0000; vector += 2 {
+			words = append(words, l.operand(Word(l.read(vector+1))<<8|Word(l.read(vector)), 4))
+		}
+		fmt.Fprintf(out, "\n    .word %s\n", strings.Join(words, ", "))
+	}
+
+	return out.Flush()
+}
+
+// operand : label for the address when there is one, otherwise the number
+func (l *Listing) operand(address Word, digits int) string {
+	if label, ok := l.Labels[address]; ok {
+		return label
+	}
+	return "$" + Hex(uint32(address), digits)
+}
+
+// instruction : single instruction in ca65 syntax
+func (l *Listing) instruction(d *Decoded) string {
+	name := d.Mnemonic
+	// absolute operands below $0100 need the a: prefix or ca65 would pick
+	// the shorter zero page encoding
+	absolute := func() string {
+		if d.Operand < 0x0100 {
+			return "a:$" + Hex(uint32(d.Operand), 4)
+		}
+		return l.operand(d.Operand, 4)
+	}
+
+	switch d.Mode {
+	case ModeACC:
+		return name + " a"
+	case ModeIMM:
+		return fmt.Sprintf("%s #$%s", name, Hex(uint32(d.Operand), 2))
+	case ModeZP0:
+		return fmt.Sprintf("%s $%s", name, Hex(uint32(d.Operand), 2))
+	case ModeZPX:
+		return fmt.Sprintf("%s $%s,x", name, Hex(uint32(d.Operand), 2))
+	case ModeZPY:
+		return fmt.Sprintf("%s $%s,y", name, Hex(uint32(d.Operand), 2))
+	case ModeABS:
+		return fmt.Sprintf("%s %s", name, absolute())
+	case ModeABX:
+		return fmt.Sprintf("%s %s,x", name, absolute())
+	case ModeABY:
+		return fmt.Sprintf("%s %s,y", name, absolute())
+	case ModeIND:
+		return fmt.Sprintf("%s (%s)", name, l.operand(d.Operand, 4))
+	case ModeIZX:
+		return fmt.Sprintf("%s ($%s,x)", name, Hex(uint32(d.Operand), 2))
+	case ModeIZY:
+		return fmt.Sprintf("%s ($%s),y", name, Hex(uint32(d.Operand), 2))
+	case ModeREL:
+		return fmt.Sprintf("%s %s", name, l.operand(d.Target, 4))
+	}
+	return name
+}
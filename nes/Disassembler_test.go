@@ -0,0 +1,192 @@
+package nes
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// testBank : 16K bank with a small program, a data table and the vectors
+func testBank() []byte {
+	prg := make([]byte, 0x4000)
+	copy(prg, []byte{
+		0xA2, 0x00, // C000 reset: LDX #$00
+		0xBD, 0x11, 0xC0, // C002 L_C002: LDA $C011,X
+		0xF0, 0x06, // C005 BEQ L_C00D
+		0x20, 0x10, 0xC0, // C007 JSR sub_C010
+		0xE8,       // C00A INX
+		0xD0, 0xF5, // C00B BNE L_C002
+		0x4C, 0x0D, 0xC0, // C00D L_C00D: JMP L_C00D
+		0x60,             // C010 sub_C010: RTS
+		0x01, 0x02, 0x00, // C011 table
+		0x40, // C014 nmi: RTI
+	})
+	copy(prg[0x3FFA:], []byte{0x14, 0xC0, 0x00, 0xC0, 0x14, 0xC0})
+	return prg
+}
+
+func TestDisassemblePRG(t *testing.T) {
+	l := DisassemblePRG(testBank(), 0xC000)
+
+	labels := map[Word]string{
+		0xC000: "reset",
+		0xC002: "L_C002",
+		0xC00D: "L_C00D",
+		0xC010: "sub_C010",
+		0xC014: "nmi",
+	}
+	if len(l.Labels) != len(labels) {
+		t.Errorf("Expected labels %v, got: %v", labels, l.Labels)
+	}
+	for address, label := range labels {
+		if l.Labels[address] != label {
+			t.Errorf("Expected: %q at $%X, got: %q", label, address, l.Labels[address])
+		}
+	}
+
+	assertTrue(t, l.IsCode(0xC010))
+	assertFalse(t, l.IsCode(0xC011))
+	assertFalse(t, l.IsCode(0xFFFC))
+
+	regions := l.Regions()
+	want := []Region{
+		{0xC000, 0xC010, true},
+		{0xC011, 0xC013, false},
+		{0xC014, 0xC014, true},
+		{0xC015, 0xFFFF, false},
+	}
+	if len(regions) != len(want) {
+		t.Fatalf("Expected regions %v, got: %v", want, regions)
+	}
+	for i := range want {
+		if regions[i] != want[i] {
+			t.Errorf("Expected: %v, got: %v", want[i], regions[i])
+		}
+	}
+}
+
+func TestDisassembleSource(t *testing.T) {
+	l := DisassemblePRG(testBank(), 0xC000)
+
+	var source bytes.Buffer
+	assertNil(t, l.Source(&source))
+	s := source.String()
+
+	for _, line := range []string{
+		".org $C000\n",
+		"\nreset:\n    LDX #$00\n",
+		"    LDA $C011,x\n",
+		"    BEQ L_C00D\n",
+		"    JSR sub_C010\n",
+		"\nsub_C010:\n    RTS\n    .byte $01,$02,$00\n",
+		"\nnmi:\n    RTI\n",
+		"    .word nmi, reset, nmi\n",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("Expected source to contain %q, got:\n%s", line, s)
+		}
+	}
+}
+
+func TestDisassembleForcesAbsolute(t *testing.T) {
+	l := DisassemblePRG([]byte{0xAD, 0x10, 0x00, 0xB6, 0x10}, 0x8000)
+	d := decode(0x8000, l.read, 0, 0)
+	if got := l.instruction(&d); got != "LDA a:$0010" {
+		t.Errorf("Expected: %q, got: %q", "LDA a:$0010", got)
+	}
+	d = decode(0x8003, l.read, 0, 0)
+	if got := l.instruction(&d); got != "LDX $10,y" {
+		t.Errorf("Expected: %q, got: %q", "LDX $10,y", got)
+	}
+}
+
+func TestDisassembleRoundTripCA65(t *testing.T) {
+	for _, tool := range []string{"ca65", "ld65"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	assertCA65RoundTrip(t, DisassemblePRG(testBank(), 0xC000))
+	assertCA65RoundTrip(t, DisassemblePRG([]byte{0xAD, 0x10, 0x00, 0xB6, 0x10, 0x0A, 0x6C, 0xFF, 0x02}, 0x8000))
+
+	if _, err := os.Stat(nestestROM); err != nil {
+		t.Skipf("%s not found", nestestROM)
+	}
+	assertCA65RoundTrip(t, DisassembleCartridge(LoadCartridge(nestestROM)))
+}
+
+// assertCA65RoundTrip : the source written for a listing assembles and links
+// back to the very same bytes with ca65 and ld65
+func assertCA65RoundTrip(t *testing.T, l *Listing) {
+	dir, err := ioutil.TempDir("", "gones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var source bytes.Buffer
+	assertNil(t, l.Source(&source))
+	sourcePath := filepath.Join(dir, "listing.s")
+	if err := ioutil.WriteFile(sourcePath, source.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	object, binary := filepath.Join(dir, "listing.o"), filepath.Join(dir, "listing.bin")
+	for _, command := range [][]string{
+		{"ca65", "-o", object, sourcePath},
+		{"ld65", "-t", "none", "-o", binary, object},
+	} {
+		if out, err := exec.Command(command[0], command[1:]...).CombinedOutput(); err != nil {
+			t.Fatalf("%s: %v\n%s", command[0], err, out)
+		}
+	}
+
+	code, err := ioutil.ReadFile(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, l.PRG) {
+		for i := range l.PRG {
+			if i >= len(code) || code[i] != l.PRG[i] {
+				t.Fatalf("Expected the same bytes from ca65, first difference at $%s", Hex(uint32(l.Origin)+uint32(i), 4))
+			}
+		}
+		t.Fatalf("Expected %d bytes from ca65, got: %d", len(l.PRG), len(code))
+	}
+}
+
+// assertRoundTrip : the source written for a listing assembles back to the
+// very same bytes
+func assertRoundTrip(t *testing.T, l *Listing) {
+	var source bytes.Buffer
+	assertNil(t, l.Source(&source))
+
+	p, err := asm.Assemble(source.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqualsW(t, l.Origin, Word(p.Origin))
+	if !bytes.Equal(p.Code, l.PRG) {
+		for i := range l.PRG {
+			if i >= len(p.Code) || p.Code[i] != l.PRG[i] {
+				t.Fatalf("Expected the same bytes, first difference at $%s", Hex(uint32(l.Origin)+uint32(i), 4))
+			}
+		}
+		t.Fatalf("Expected %d bytes, got: %d", len(l.PRG), len(p.Code))
+	}
+}
+
+func TestDisassembleRoundTrip(t *testing.T) {
+	assertRoundTrip(t, DisassemblePRG(testBank(), 0xC000))
+	assertRoundTrip(t, DisassemblePRG([]byte{0xAD, 0x10, 0x00, 0xB6, 0x10, 0x0A, 0x6C, 0xFF, 0x02}, 0x8000))
+
+	if _, err := os.Stat(nestestROM); err != nil {
+		t.Skipf("%s not found", nestestROM)
+	}
+	assertRoundTrip(t, DisassembleCartridge(LoadCartridge(nestestROM)))
+}
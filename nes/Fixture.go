@@ -0,0 +1,236 @@
+package nes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// fixtureInstructionLimit : instructions a fixture may run before it is
+// considered stuck
+const fixtureInstructionLimit = 100000
+
+// Fixture : small CPU/PPU regression program from test/opcode
+//
+// The first line holds the load address and the second one the program as hex
+// bytes, the rest of the file is the assembly source. When the hex line is
+// left empty the program is assembled from the source instead.
+// The program runs from the load address until it reaches a BRK or a JMP to
+// itself, then the "expect" comments are checked:
+//
+//	// expect A=$02 X=0 C=1 Z=0 SP=$FD CYC=18
+//	// expect [$0200]=$05 PPU[$3F01]=$02
+//
+// Registers are A, X, Y, SP, PC and P, flags are C, Z, I, D, B, U, V and N,
+// CYC counts the CPU cycles after the reset, [addr] reads the CPU bus and
+// PPU[addr] reads the PPU bus (pattern tables, nametables and palette).
+type Fixture struct {
+	Name    string
+	Origin  Word
+	Program string
+	Expect  []Expectation
+}
+
+// Expectation : value checked once the fixture program stops
+type Expectation struct {
+	Name  string
+	Value int
+}
+
+// fixtureRun : state of the machine when the fixture program stopped
+type fixtureRun struct {
+	bus    *Bus
+	cycles int
+}
+
+// LoadFixture : reads a fixture file
+func LoadFixture(path string) (*Fixture, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return ParseFixture(name, string(text))
+}
+
+// ParseFixture : reads a fixture from its text
+func ParseFixture(name, text string) (*Fixture, error) {
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("%s: expected the load address and the program on the first two lines", name)
+	}
+
+	origin, err := strconv.ParseUint(strings.TrimSpace(lines[0]), 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%s: bad load address %q", name, lines[0])
+	}
+	f := &Fixture{Name: name, Origin: Word(origin), Program: strings.TrimSpace(lines[1])}
+
+	source := strings.Join(lines[2:], "\n")
+	if f.Program == "" {
+		p, err := asm.Assemble(source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		f.Program = p.Hex()
+	}
+
+	for _, line := range lines[2:] {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimLeft(line, "/;"))
+		if !strings.HasPrefix(line, "expect ") {
+			continue
+		}
+		for _, field := range strings.Fields(line[len("expect "):]) {
+			e, err := ParseExpectation(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			f.Expect = append(f.Expect, e)
+		}
+	}
+	return f, nil
+}
+
+// ParseExpectation : reads a single NAME=value check
+func ParseExpectation(field string) (Expectation, error) {
+	parts := strings.SplitN(field, "=", 2)
+	if len(parts) != 2 {
+		return Expectation{}, fmt.Errorf("bad expectation %q", field)
+	}
+	e := Expectation{Name: strings.ToUpper(parts[0])}
+
+	value, err := parseFixtureNumber(parts[1])
+	if err != nil {
+		return e, fmt.Errorf("bad value in %q", field)
+	}
+	e.Value = value
+
+	if _, _, ok := e.address(); ok {
+		return e, nil
+	}
+	switch e.Name {
+	case "A", "X", "Y", "SP", "PC", "P", "CYC", "C", "Z", "I", "D", "B", "U", "V", "N":
+		return e, nil
+	}
+	return e, fmt.Errorf("unknown expectation %q", field)
+}
+
+// parseFixtureNumber : $hex, %binary or decimal
+func parseFixtureNumber(s string) (int, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(s, "$"):
+		s, base = s[1:], 16
+	case strings.HasPrefix(s, "%"):
+		s, base = s[1:], 2
+	}
+	value, err := strconv.ParseInt(s, base, 32)
+	return int(value), err
+}
+
+// address : memory read by an expectation, ppu is true for PPU[addr]
+func (e Expectation) address() (address Word, ppu bool, ok bool) {
+	name := e.Name
+	if strings.HasPrefix(name, "PPU[") {
+		ppu = true
+		name = name[3:]
+	}
+	if !strings.HasPrefix(name, "[") || !strings.HasSuffix(name, "]") {
+		return 0, false, false
+	}
+	value, err := parseFixtureNumber(name[1 : len(name)-1])
+	if err != nil || value < 0 || value > 0xFFFF {
+		return 0, false, false
+	}
+	return Word(value), ppu, true
+}
+
+// Run : runs the program until it stops and returns the expectations that
+// did not match
+func (f *Fixture) Run() ([]string, error) {
+	r, err := f.run()
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []string
+	for _, e := range f.Expect {
+		if got := r.value(e); got != e.Value {
+			failures = append(failures, fmt.Sprintf("%s: expected $%X, got: $%X", e.Name, e.Value, got))
+		}
+	}
+	return failures, nil
+}
+
+// run : loads the program with TestCartridge and runs it up to the terminator
+func (f *Fixture) run() (*fixtureRun, error) {
+	bus := CreateBus(CreateCPU(), CreatePPU())
+	bus.InsertCartridge(TestCartridge(f.Program, f.Origin))
+	bus.Reset()
+	// the first operation is the reset sequence
+	bus.ExecuteOperation()
+	start := bus.cpu.clockCount
+
+	for i := 0; ; i++ {
+		pc := bus.cpu.pc
+		opcode := bus.peek(pc)
+		if opcode == 0x00 || opcode == 0x4C && Word(bus.peek(pc+2))<<8|Word(bus.peek(pc+1)) == pc {
+			break
+		}
+		if i == fixtureInstructionLimit {
+			return nil, fmt.Errorf("%s: no BRK or JMP * reached after %d instructions", f.Name, i)
+		}
+		bus.ExecuteOperation()
+	}
+
+	return &fixtureRun{bus, bus.cpu.clockCount - start}, nil
+}
+
+// value : current value of what the expectation checks
+func (r *fixtureRun) value(e Expectation) int {
+	return r.bus.expectationValue(e, r.cycles)
+}
+
+// expectationValue : current value of what the expectation checks, CYC is
+// given by the caller
+func (b *Bus) expectationValue(e Expectation, cycles int) int {
+	c := b.cpu
+	if address, ppu, ok := e.address(); ok {
+		if ppu {
+			data, _ := b.ppu.PPURead(address, true)
+			return int(data)
+		}
+		return int(b.peek(address))
+	}
+
+	flags := map[string]Flag{"C": C, "Z": Z, "I": I, "D": D, "B": B, "U": U, "V": V, "N": N}
+	if flag, ok := flags[e.Name]; ok {
+		if c.StatusRegister(flag) {
+			return 1
+		}
+		return 0
+	}
+
+	switch e.Name {
+	case "A":
+		return int(c.a)
+	case "X":
+		return int(c.x)
+	case "Y":
+		return int(c.y)
+	case "SP":
+		return int(c.stkp)
+	case "PC":
+		return int(c.pc)
+	case "P":
+		return int(c.status)
+	case "CYC":
+		return cycles
+	}
+	return -1
+}
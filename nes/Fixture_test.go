@@ -0,0 +1,69 @@
+package nes
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestOpcodeFixtures : every file in test/opcode is a regression case
+func TestOpcodeFixtures(t *testing.T) {
+	files, _ := filepath.Glob("../test/opcode/*.txt")
+	if len(files) == 0 {
+		t.Skip("no fixtures found")
+	}
+	for _, file := range files {
+		file := file
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			f, err := LoadFixture(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.Expect) == 0 {
+				t.Fatal("fixture has no expect line")
+			}
+			failures, err := f.Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, failure := range failures {
+				t.Error(failure)
+			}
+		})
+	}
+}
+
+func TestFixtureFailures(t *testing.T) {
+	f, err := ParseFixture("inline", "8000\nA9 3F 85 10 4C 04 80\n; expect A=$3F [$10]=$3F X=1 c=0 CYC=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqualsW(t, 0x8000, f.Origin)
+	if len(f.Expect) != 5 || f.Expect[3].Name != "C" {
+		t.Errorf("unexpected expectations %+v", f.Expect)
+	}
+
+	failures, err := f.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 1 || failures[0] != "X: expected $1, got: $0" {
+		t.Errorf("Expected only X to fail, got: %v", failures)
+	}
+
+	for _, text := range []string{
+		"zz\nEA",
+		"8000\nEA\n// expect Q=1",
+		"8000\nEA\n// expect A=$GG",
+		"8000\n\nLDA #$1000",
+	} {
+		if _, err := ParseFixture("bad", text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+
+	f, _ = ParseFixture("loop", "8000\nEA D0 FD")
+	if _, err := f.Run(); err == nil || !strings.Contains(err.Error(), "no BRK") {
+		t.Errorf("Expected the runner to give up, got: %v", err)
+	}
+}
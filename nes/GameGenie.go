@@ -0,0 +1,188 @@
+package nes
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// gameGenieLetters : the 16 letters of the codes, each one is 4 bits
+const gameGenieLetters = "APZLGITYEOXUKSVN"
+
+// GameGenieCode : a decoded Game Genie code, the value replaces what the
+// cartridge answers at the address, only when it answers the compare value
+// for the 8 letter codes
+type GameGenieCode struct {
+	Address    Word
+	Value      byte
+	Compare    byte
+	HasCompare bool
+}
+
+// DecodeGameGenie : decodes a 6 or 8 letter code like "SXIOPO"
+func DecodeGameGenie(code string) (GameGenieCode, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 6 && len(code) != 8 {
+		return GameGenieCode{}, fmt.Errorf("a Game Genie code is 6 or 8 letters, not %q", code)
+	}
+	n := make([]byte, len(code))
+	for i := range code {
+		v := strings.IndexByte(gameGenieLetters, code[i])
+		if v < 0 {
+			return GameGenieCode{}, fmt.Errorf("%q is not a Game Genie letter in %q", code[i], code)
+		}
+		n[i] = byte(v)
+	}
+
+	c := GameGenieCode{
+		Address: 0x8000 | Word(n[3]&7)<<12 | Word(n[5]&7)<<8 | Word(n[4]&8)<<8 |
+			Word(n[2]&7)<<4 | Word(n[1]&8)<<4 | Word(n[4]&7) | Word(n[3]&8),
+		Value: (n[1]&7)<<4 | (n[0]&8)<<4 | n[0]&7 | n[5]&8,
+	}
+	if len(n) == 8 {
+		c.Value = c.Value&^0x08 | n[7]&8
+		c.Compare = (n[7]&7)<<4 | (n[6]&8)<<4 | n[6]&7 | n[5]&8
+		c.HasCompare = true
+	}
+	return c, nil
+}
+
+// String : the letters of the code
+func (c GameGenieCode) String() string {
+	n := []byte{
+		c.Value&7 | c.Value>>4&8,
+		c.Value>>4&7 | byte(c.Address>>4)&8,
+		byte(c.Address>>4) & 7,
+		byte(c.Address>>12)&7 | byte(c.Address)&8,
+		byte(c.Address)&7 | byte(c.Address>>8)&8,
+		byte(c.Address>>8)&7 | c.Value&8,
+	}
+	if c.HasCompare {
+		// the third letter tells the Game Genie to read 8 of them
+		n[2] |= 8
+		n[5] = n[5]&7 | c.Compare&8
+		n = append(n, c.Compare&7|c.Compare>>4&8, c.Compare>>4&7|c.Value&8)
+	}
+	for i := range n {
+		n[i] = gameGenieLetters[n[i]]
+	}
+	return string(n)
+}
+
+// GameGenieCheat : a code and whether it is applied
+type GameGenieCheat struct {
+	Code    GameGenieCode
+	Enabled bool
+}
+
+// GameGenie : the codes entered for the cartridge, they patch the reads of
+// the CPU from the ROM
+type GameGenie struct {
+	cheats []GameGenieCheat
+}
+
+// Add : decodes a code and turns it on, entering a code again only turns it on
+func (g *GameGenie) Add(code string) error {
+	c, err := DecodeGameGenie(code)
+	if err != nil {
+		return err
+	}
+	if i := g.find(c); i >= 0 {
+		g.cheats[i].Enabled = true
+		return nil
+	}
+	g.cheats = append(g.cheats, GameGenieCheat{c, true})
+	return nil
+}
+
+// Remove : forgets a code
+func (g *GameGenie) Remove(code string) error {
+	c, err := DecodeGameGenie(code)
+	if err != nil {
+		return err
+	}
+	if i := g.find(c); i >= 0 {
+		g.cheats = append(g.cheats[:i], g.cheats[i+1:]...)
+	}
+	return nil
+}
+
+// SetEnabled : turns an entered code on or off
+func (g *GameGenie) SetEnabled(code string, enabled bool) error {
+	c, err := DecodeGameGenie(code)
+	if err != nil {
+		return err
+	}
+	i := g.find(c)
+	if i < 0 {
+		return fmt.Errorf("the code %s was not entered", c)
+	}
+	g.cheats[i].Enabled = enabled
+	return nil
+}
+
+// Cheats : the codes entered, in the order they were
+func (g *GameGenie) Cheats() []GameGenieCheat {
+	return append([]GameGenieCheat(nil), g.cheats...)
+}
+
+// find : index of the code, -1 when it was not entered
+func (g *GameGenie) find(c GameGenieCode) int {
+	for i, cheat := range g.cheats {
+		if cheat.Code == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// apply : what the CPU reads instead of the byte the cartridge answered
+func (g *GameGenie) apply(address Word, data byte) byte {
+	for _, cheat := range g.cheats {
+		c := cheat.Code
+		if cheat.Enabled && c.Address == address && (!c.HasCompare || c.Compare == data) {
+			return c.Value
+		}
+	}
+	return data
+}
+
+// Write : one code per line followed by on or off
+func (g *GameGenie) Write(w io.Writer) error {
+	for _, cheat := range g.cheats {
+		state := "off"
+		if cheat.Enabled {
+			state = "on"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s\n", cheat.Code, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read : adds the codes written by Write
+func (g *GameGenie) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+			return fmt.Errorf("line %d: expected a code followed by on or off", line)
+		}
+		if err := g.Add(fields[0]); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		g.SetEnabled(fields[0], fields[1] == "on")
+	}
+	return scanner.Err()
+}
+
+// gameGeniePath : file of the codes of a ROM in the cheats directory
+func gameGeniePath(dir string, cart *Cartridge) string {
+	return filepath.Join(dir, fmt.Sprintf("%x.gg", cart.romHash))
+}
@@ -0,0 +1,96 @@
+package nes
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDecodeGameGenie(t *testing.T) {
+	// Super Mario Bros., infinite lives
+	c, err := DecodeGameGenie("sxiopo")
+	assertNil(t, err)
+	assertEqualsW(t, 0x91D9, c.Address)
+	assertEqualsB(t, 0xAD, c.Value)
+	assertFalse(t, c.HasCompare)
+	assertTrue(t, c.String() == "SXIOPO")
+
+	for _, want := range []GameGenieCode{
+		{0x8000, 0x00, 0x00, false},
+		{0xFFFF, 0xFF, 0x00, false},
+		{0xA5C3, 0x5A, 0x3C, true},
+		{0xFFFF, 0xFF, 0xFF, true},
+		{0x8421, 0x08, 0x80, true},
+	} {
+		got, err := DecodeGameGenie(want.String())
+		assertNil(t, err)
+		if got != want {
+			t.Errorf("Expected %s to decode to %+v, got: %+v", want, want, got)
+		}
+	}
+
+	for _, bad := range []string{"", "SXIOP", "SXIOPOA", "SXIOPB"} {
+		_, err := DecodeGameGenie(bad)
+		assertTrue(t, err != nil)
+	}
+}
+
+func TestGameGenieCheats(t *testing.T) {
+	nes := stateBus()
+	rom, _ := nes.CPURead(0x8000, true)
+	patch := GameGenieCode{Address: 0x8000, Value: rom ^ 0xFF}.String()
+	compare := GameGenieCode{Address: 0x8001, Value: 0x12, Compare: 0x00, HasCompare: true}.String()
+	other, _ := nes.CPURead(0x8001, true)
+
+	assertNil(t, nes.genie.Add(patch))
+	assertNil(t, nes.genie.Add(compare))
+	d, _ := nes.CPURead(0x8000, false)
+	assertEqualsB(t, rom^0xFF, d)
+	// the compare value does not match what the ROM holds
+	d, _ = nes.CPURead(0x8001, false)
+	assertEqualsB(t, other, d)
+
+	assertNil(t, nes.genie.SetEnabled(patch, false))
+	d, _ = nes.CPURead(0x8000, false)
+	assertEqualsB(t, rom, d)
+	assertTrue(t, nes.genie.SetEnabled("SXIOPO", true) != nil)
+
+	assertNil(t, nes.genie.Remove(compare))
+	cheats := nes.genie.Cheats()
+	assertTrue(t, len(cheats) == 1 && !cheats[0].Enabled)
+}
+
+func TestGameGeniePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genie")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+	assertNil(t, nes.LoadGameGenie(dir))
+	assertTrue(t, len(nes.GameGenie().Cheats()) == 0)
+	nes.GameGenie().Add("SXIOPO")
+	nes.GameGenie().Add("ZEXPYGLA")
+	nes.GameGenie().SetEnabled("ZEXPYGLA", false)
+	assertNil(t, nes.SaveGameGenie(dir))
+
+	var out bytes.Buffer
+	nes.GameGenie().Write(&out)
+	assertTrue(t, out.String() == "SXIOPO on\nZEXPYGLA off\n")
+
+	// the same ROM gets the codes back, another one does not
+	again := CreateConsole()
+	again.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+	assertNil(t, again.LoadGameGenie(dir))
+	assertTrue(t, len(again.GameGenie().Cheats()) == 2)
+	assertFalse(t, again.GameGenie().Cheats()[1].Enabled)
+
+	other := CreateConsole()
+	other.InsertCartridge(TestCartridge("EA", 0x8000))
+	assertNil(t, other.LoadGameGenie(dir))
+	assertTrue(t, len(other.GameGenie().Cheats()) == 0)
+
+	var g GameGenie
+	assertTrue(t, g.Read(bytes.NewBufferString("SXIOPO maybe\n")) != nil)
+}
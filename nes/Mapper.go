@@ -0,0 +1,107 @@
+package nes
+
+// Mapper : interface to implement mappers. The Map functions return false for
+// the addresses the board does not drive, CPU reads from those see the open
+// bus instead
+type Mapper interface {
+	CPUMapRead(address Word) (uint32, bool)
+	CPUMapWrite(address Word, data byte) (uint32, bool)
+	PPUMapRead(address Word) (uint32, bool)
+	PPUMapWrite(address Word) (uint32, bool)
+	Reset()
+	// mirroring : the nametable arrangement, soldered is the one the header
+	// gives for boards that can not change it
+	mirroring(soldered int) int
+	// clone : a copy of the registers, for save states to load into
+	clone() Mapper
+	saveState(c *stateChunk)
+	loadState(l *stateLoader)
+}
+
+// createMapper : the board of the mapper number from the header, boards that
+// are not emulated get NROM and ok is false
+func createMapper(id, prgBanks, chrBanks byte) (m Mapper, ok bool) {
+	switch id {
+	case 0:
+		return &Mapper000{prgBanks, chrBanks}, true
+	case 1:
+		m := &Mapper001{PGRBanks: prgBanks, CHABanks: chrBanks}
+		m.Reset()
+		return m, true
+	}
+	return &Mapper000{prgBanks, chrBanks}, false
+}
+
+// Mapper000 : default mapper
+type Mapper000 struct {
+	PGRBanks byte
+	CHABanks byte
+}
+
+// CPUMapRead : Reads data from CPU
+func (m *Mapper000) CPUMapRead(address Word) (uint32, bool) {
+	if address >= 0x8000 && address <= 0xFFFF {
+		var mappedAddress uint32
+		if m.PGRBanks > 1 {
+			mappedAddress = 0x00007FFF
+		} else {
+			mappedAddress = 0x00003FFF
+		}
+		mappedAddress &= uint32(address)
+		return mappedAddress, true
+	}
+	return 0, false
+}
+
+// CPUMapWrite : map the write process to the correct address
+func (m *Mapper000) CPUMapWrite(address Word, data byte) (uint32, bool) {
+	if address >= 0x8000 && address <= 0xFFFF {
+		var mappedAddress uint32
+		if m.PGRBanks > 1 {
+			mappedAddress = 0x00007FFF
+		} else {
+			mappedAddress = 0x00003FFF
+		}
+		mappedAddress &= uint32(address)
+		return mappedAddress, true
+	}
+	return 0, false
+}
+
+// PPUMapRead : map the read process to the correct address
+func (m *Mapper000) PPUMapRead(address Word) (uint32, bool) {
+	if address >= 0x0000 && address <= 0x1FFF {
+
+		return uint32(address), true
+	}
+	return 0, false
+}
+
+// PPUMapWrite : map the write process to the correct address
+func (m *Mapper000) PPUMapWrite(address Word) (uint32, bool) {
+	return 0, false
+}
+
+// Reset : resets mapper - can implement things like change starting position to run other games
+func (m *Mapper000) Reset() {
+	// do nothing
+}
+
+// mirroring : NROM keeps the soldered mirroring
+func (m *Mapper000) mirroring(soldered int) int {
+	return soldered
+}
+
+// clone : a copy of the mapper
+func (m *Mapper000) clone() Mapper {
+	c := *m
+	return &c
+}
+
+// saveState : writes the mapper registers, NROM has none
+func (m *Mapper000) saveState(c *stateChunk) {
+}
+
+// loadState : restores the mapper registers, NROM has none
+func (m *Mapper000) loadState(l *stateLoader) {
+}
@@ -0,0 +1,135 @@
+package nes
+
+// Mapper001 : MMC1, the registers are written a bit at a time through a
+// serial port at $8000-$FFFF. Five writes fill the shift register and the
+// last one picks the register with bits 13 and 14 of its address
+type Mapper001 struct {
+	PGRBanks byte
+	CHABanks byte
+	// shift : the bits written so far, the 1 starting at bit 4 reaches bit 0
+	// on the fifth write
+	shift    byte
+	control  byte
+	chrBank0 byte
+	chrBank1 byte
+	prgBank  byte
+}
+
+// CPUMapRead : maps $8000-$FFFF to the PRG banks of the control mode
+func (m *Mapper001) CPUMapRead(address Word) (uint32, bool) {
+	if address < 0x8000 {
+		return 0, false
+	}
+	var bank uint32
+	switch m.control >> 2 & 0x03 {
+	case 0, 1:
+		// 32KB at once, the low bit of the bank is ignored
+		bank = uint32(m.prgBank&0x0E) | uint32(address>>14&0x01)
+	case 2:
+		// first bank fixed at $8000
+		if address >= 0xC000 {
+			bank = uint32(m.prgBank & 0x0F)
+		}
+	case 3:
+		// last bank fixed at $C000
+		bank = uint32(m.PGRBanks) - 1
+		if address < 0xC000 {
+			bank = uint32(m.prgBank & 0x0F)
+		}
+	}
+	return (bank*0x4000 + uint32(address&0x3FFF)) % (uint32(m.PGRBanks) * 0x4000), true
+}
+
+// CPUMapWrite : shifts a bit into the registers, the ROM is never written
+func (m *Mapper001) CPUMapWrite(address Word, data byte) (uint32, bool) {
+	if address < 0x8000 {
+		return 0, false
+	}
+	if data&0x80 != 0 {
+		m.shift = 0x10
+		m.control |= 0x0C
+		return 0, false
+	}
+	full := m.shift&0x01 != 0
+	m.shift = m.shift>>1 | (data&0x01)<<4
+	if !full {
+		return 0, false
+	}
+	switch address >> 13 & 0x03 {
+	case 0:
+		m.control = m.shift
+	case 1:
+		m.chrBank0 = m.shift
+	case 2:
+		m.chrBank1 = m.shift
+	case 3:
+		m.prgBank = m.shift
+	}
+	m.shift = 0x10
+	return 0, false
+}
+
+// PPUMapRead : maps the pattern tables to one 8KB or two 4KB CHR banks
+func (m *Mapper001) PPUMapRead(address Word) (uint32, bool) {
+	if address > 0x1FFF {
+		return 0, false
+	}
+	var mapped uint32
+	if m.control&0x10 == 0 {
+		mapped = uint32(m.chrBank0&0x1E)*0x1000 + uint32(address)
+	} else if address < 0x1000 {
+		mapped = uint32(m.chrBank0)*0x1000 + uint32(address)
+	} else {
+		mapped = uint32(m.chrBank1)*0x1000 + uint32(address&0x0FFF)
+	}
+	size := uint32(m.CHABanks) * 0x2000
+	if m.CHABanks == 0 {
+		// 8KB of CHR RAM
+		size = 0x2000
+	}
+	return mapped % size, true
+}
+
+// PPUMapWrite : only boards with CHR RAM can be written
+func (m *Mapper001) PPUMapWrite(address Word) (uint32, bool) {
+	if m.CHABanks != 0 {
+		return 0, false
+	}
+	return m.PPUMapRead(address)
+}
+
+// Reset : the last PRG bank is at $C000 after power on
+func (m *Mapper001) Reset() {
+	m.shift = 0x10
+	m.control = 0x0C
+	m.chrBank0, m.chrBank1, m.prgBank = 0, 0, 0
+}
+
+// mirroring : picked by the low bits of the control register
+func (m *Mapper001) mirroring(soldered int) int {
+	return [4]int{OnescreenLo, OnescreenHi, Vertical, Horizontal}[m.control&0x03]
+}
+
+// clone : a copy of the mapper
+func (m *Mapper001) clone() Mapper {
+	c := *m
+	return &c
+}
+
+// saveState : writes the shift register and the four registers
+func (m *Mapper001) saveState(c *stateChunk) {
+	c.putByte("shift", m.shift)
+	c.putByte("control", m.control)
+	c.putByte("chrBank0", m.chrBank0)
+	c.putByte("chrBank1", m.chrBank1)
+	c.putByte("prgBank", m.prgBank)
+}
+
+// loadState : restores the registers
+func (m *Mapper001) loadState(l *stateLoader) {
+	l.byte("shift", &m.shift)
+	l.byte("control", &m.control)
+	l.byte("chrBank0", &m.chrBank0)
+	l.byte("chrBank1", &m.chrBank1)
+	l.byte("prgBank", &m.prgBank)
+}
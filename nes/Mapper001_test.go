@@ -0,0 +1,127 @@
+package nes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// mmc1Cartridge : an MMC1 cartridge with CHR RAM whose 16KB PRG banks start
+// with their number, the program is put at the start of the last bank
+func mmc1Cartridge(t *testing.T, banks int, program []byte) *Cartridge {
+	header := []byte{'N', 'E', 'S', 0x1A, byte(banks), 0, 0x10, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	prg := make([]byte, banks*0x4000)
+	for i := 0; i < banks; i++ {
+		prg[i*0x4000] = byte(i)
+	}
+	last := prg[(banks-1)*0x4000:]
+	copy(last, program)
+	// reset vector to $C000
+	last[0x3FFC], last[0x3FFD] = 0x00, 0xC0
+	cart, err := ReadCartridge(bytes.NewReader(append(header, prg...)))
+	assertNil(t, err)
+	return cart
+}
+
+// writeMMC1 : writes a register a bit at a time, the way games do
+func writeMMC1(cart *Cartridge, address Word, value byte) {
+	for i := uint(0); i < 5; i++ {
+		cart.CPUWrite(address, value>>i&0x01)
+	}
+}
+
+func TestMapper001PRGBanks(t *testing.T) {
+	cart := mmc1Cartridge(t, 8, []byte{0x07})
+	assertTrue(t, cart.MapperSupported())
+	bank := func(address Word) byte {
+		d, ok := cart.CPURead(address)
+		assertTrue(t, ok)
+		return d
+	}
+
+	// the last bank is fixed at $C000 after power on
+	assertEqualsB(t, 0, bank(0x8000))
+	assertEqualsB(t, 7, bank(0xC000))
+	writeMMC1(cart, 0xE000, 3)
+	assertEqualsB(t, 3, bank(0x8000))
+	assertEqualsB(t, 7, bank(0xC000))
+
+	// the first bank fixed at $8000
+	writeMMC1(cart, 0x8000, 0x08)
+	assertEqualsB(t, 0, bank(0x8000))
+	assertEqualsB(t, 3, bank(0xC000))
+
+	// 32KB at once ignores the low bit
+	writeMMC1(cart, 0x8000, 0x00)
+	writeMMC1(cart, 0xE000, 5)
+	assertEqualsB(t, 4, bank(0x8000))
+	assertEqualsB(t, 5, bank(0xC000))
+
+	// bit 7 clears the shift register and fixes the last bank again
+	cart.CPUWrite(0x8000, 0x01)
+	cart.CPUWrite(0x8000, 0x80)
+	writeMMC1(cart, 0xE000, 2)
+	assertEqualsB(t, 2, bank(0x8000))
+	assertEqualsB(t, 7, bank(0xC000))
+
+	// the ROM is not written
+	assertEqualsB(t, 0, cart.PRGMemory[0])
+}
+
+func TestMapper001CHRAndMirroring(t *testing.T) {
+	cart := mmc1Cartridge(t, 2, nil)
+	for control, mirror := range []int{OnescreenLo, OnescreenHi, Vertical, Horizontal} {
+		writeMMC1(cart, 0x8000, byte(control))
+		assertTrue(t, cart.mirroring() == mirror)
+	}
+
+	// two 4KB banks of the 8KB CHR RAM
+	writeMMC1(cart, 0x8000, 0x10)
+	writeMMC1(cart, 0xA000, 1)
+	writeMMC1(cart, 0xC000, 0)
+	assertTrue(t, cart.PPUWrite(0x0010, 0x5A))
+	assertEqualsB(t, 0x5A, cart.CHAMemory[0x1010])
+	d, _ := cart.PPURead(0x0010)
+	assertEqualsB(t, 0x5A, d)
+	d, _ = cart.PPURead(0x1010)
+	assertEqualsB(t, 0x00, d)
+}
+
+func TestMapper001OneScreenMirroring(t *testing.T) {
+	nes := CreateConsole()
+	nes.InsertCartridge(mmc1Cartridge(t, 2, []byte{0x4C, 0x00, 0xC0}))
+	p := nes.bus.ppu
+
+	writeMMC1(nes.Cartridge(), 0x8000, 0x01)
+	for table := Word(0); table < 4; table++ {
+		assertNil(t, p.PPUWrite(0x2000+table*0x400, byte(table)))
+	}
+	assertEqualsB(t, 3, p.nameTable[1][0])
+	assertEqualsB(t, 0, p.nameTable[0][0])
+}
+
+func TestBlarggMapper001(t *testing.T) {
+	program := asm.MustAssemble(`
+		.org $C000
+reset:  LDA #$80
+        STA $6000
+        LDX #$00
+sig:    LDA signature,X
+        STA $6001,X
+        INX
+        CPX #$06
+        BNE sig
+        LDA #$00
+        STA $6000
+done:   JMP done
+signature:
+        .byte $DE, $B0, $61, $4F, $4B, $00
+`)
+	r := RunTestCartridge("mmc1", "", mmc1Cartridge(t, 2, program.Code), TestROMTimeout)
+
+	assertTrue(t, r.Outcome == TestROMPassed)
+	if r.Text != "OK" {
+		t.Errorf("Expected: %q, got: %q", "OK", r.Text)
+	}
+}
@@ -0,0 +1,106 @@
+package nes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// memoryPages : the CPU address space cut in pages of 256 bytes
+const memoryPages = 0x100
+
+// ReadHandler : answers a CPU read, ok is false when the device does not drive
+// the address and the read goes on to the device registered under it
+type ReadHandler func(address Word, readOnly bool) (data byte, ok bool, err error)
+
+// WriteHandler : takes a CPU write, ok is false when the device ignores the
+// address and the write goes on to the device registered under it
+type WriteHandler func(address Word, data byte) (ok bool, err error)
+
+// memoryDevice : something registered on a range of the CPU address space
+type memoryDevice struct {
+	name        string
+	first, last Word
+	read        ReadHandler
+	write       WriteHandler
+}
+
+// MemoryMap : the devices on the CPU bus, looked up by the page of the address.
+// Each page keeps its devices from the last registered to the first, so a
+// device registered over another one, a cheat or a breakpoint, sees the
+// accesses first and can pass them on
+type MemoryMap struct {
+	devices []*memoryDevice
+	pages   [memoryPages][]*memoryDevice
+}
+
+// Register : adds a device for the addresses from first to last, either
+// handler can be nil for a device that is only read or only written
+func (m *MemoryMap) Register(name string, first, last Word, read ReadHandler, write WriteHandler) {
+	d := &memoryDevice{name, first, last, read, write}
+	m.devices = append(m.devices, d)
+	for page := int(first >> 8); page <= int(last>>8); page++ {
+		m.pages[page] = append([]*memoryDevice{d}, m.pages[page]...)
+	}
+}
+
+// Unregister : removes every device registered with this name
+func (m *MemoryMap) Unregister(name string) {
+	keep := func(devices []*memoryDevice) []*memoryDevice {
+		kept := devices[:0:0]
+		for _, d := range devices {
+			if d.name != name {
+				kept = append(kept, d)
+			}
+		}
+		return kept
+	}
+	m.devices = keep(m.devices)
+	for page := range m.pages {
+		m.pages[page] = keep(m.pages[page])
+	}
+}
+
+// Read : the value of the first device driving the address, ok is false when
+// none does
+func (m *MemoryMap) Read(address Word, readOnly bool) (byte, bool, error) {
+	for _, d := range m.pages[address>>8] {
+		if d.read == nil || address < d.first || address > d.last {
+			continue
+		}
+		if data, ok, err := d.read(address, readOnly); ok || err != nil {
+			return data, ok, err
+		}
+	}
+	return 0, false, nil
+}
+
+// Write : hands the value to the first device taking the address, ok is false
+// when none does
+func (m *MemoryMap) Write(address Word, data byte) (bool, error) {
+	for _, d := range m.pages[address>>8] {
+		if d.write == nil || address < d.first || address > d.last {
+			continue
+		}
+		if ok, err := d.write(address, data); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// String : one line per device, the ones registered last first
+func (m *MemoryMap) String() string {
+	var sb strings.Builder
+	for i := len(m.devices) - 1; i >= 0; i-- {
+		d := m.devices[i]
+		access := []byte("--")
+		if d.read != nil {
+			access[0] = 'R'
+		}
+		if d.write != nil {
+			access[1] = 'W'
+		}
+		fmt.Fprintf(&sb, "$%04X-$%04X %s %s\n", d.first, d.last, access, d.name)
+	}
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package nes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMemoryMapLayers(t *testing.T) {
+	var m MemoryMap
+	var ram [0x100]byte
+	m.Register("RAM", 0x0000, 0x00FF,
+		func(address Word, readOnly bool) (byte, bool, error) { return ram[address], true, nil },
+		func(address Word, data byte) (bool, error) { ram[address] = data; return true, nil })
+	ram[0x10], ram[0x11] = 0x01, 0x02
+
+	// a layer on a single address, the rest of the page still reaches the RAM
+	m.Register("Cheat", 0x0010, 0x0010,
+		func(address Word, readOnly bool) (byte, bool, error) { return 0x99, true, nil }, nil)
+	d, ok, _ := m.Read(0x0010, false)
+	assertTrue(t, ok)
+	assertEqualsB(t, 0x99, d)
+	d, _, _ = m.Read(0x0011, false)
+	assertEqualsB(t, 0x02, d)
+	ok, _ = m.Write(0x0010, 0x42)
+	assertTrue(t, ok)
+	assertEqualsB(t, 0x42, ram[0x10])
+
+	// a layer that lets accesses through
+	reads := 0
+	m.Register("Watch", 0x0000, 0x00FF,
+		func(address Word, readOnly bool) (byte, bool, error) { reads++; return 0, false, nil }, nil)
+	d, _, _ = m.Read(0x0011, false)
+	assertEqualsB(t, 0x02, d)
+	assertTrue(t, reads == 1)
+
+	_, ok, _ = m.Read(0x0100, false)
+	assertFalse(t, ok)
+	ok, _ = m.Write(0x0100, 0x00)
+	assertFalse(t, ok)
+
+	lines := strings.Split(strings.TrimSpace(m.String()), "\n")
+	assertTrue(t, len(lines) == 3)
+	assertTrue(t, lines[0] == "$0000-$00FF R- Watch")
+	assertTrue(t, lines[1] == "$0010-$0010 R- Cheat")
+	assertTrue(t, lines[2] == "$0000-$00FF RW RAM")
+
+	m.Unregister("Cheat")
+	d, _, _ = m.Read(0x0010, false)
+	assertEqualsB(t, 0x42, d)
+	assertFalse(t, strings.Contains(m.String(), "Cheat"))
+}
+
+func TestBusMemoryMap(t *testing.T) {
+	nes := stateBus()
+	described := nes.MemoryMap().String()
+	for _, device := range []string{"RAM", "PPU registers", "OAM DMA", "Controllers", "Cartridge"} {
+		assertTrue(t, strings.Contains(described, device))
+	}
+
+	// a layer over the cartridge changes what the CPU reads from the ROM
+	rom, _ := nes.CPURead(0x8000, true)
+	nes.MemoryMap().Register("Patch", 0x8000, 0x8000,
+		func(address Word, readOnly bool) (byte, bool, error) { return rom ^ 0xFF, true, nil }, nil)
+	d, _ := nes.CPURead(0x8000, true)
+	assertEqualsB(t, rom^0xFF, d)
+}
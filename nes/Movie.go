@@ -0,0 +1,238 @@
+package nes
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MovieCommand : what happens to the console at the start of a movie frame,
+// the bits of the commands field of FM2
+type MovieCommand byte
+
+const (
+	// MovieSoftReset : the reset button is pressed
+	MovieSoftReset MovieCommand = 0x01
+	// MovieHardReset : the console is turned off and on
+	MovieHardReset MovieCommand = 0x02
+)
+
+const (
+	// movieVersion : the FM2 version written and the only one read
+	movieVersion = 3
+	// movieEmuVersion : FCEUX writes its own version there, players only
+	// show it
+	movieEmuVersion = 0
+	// movieGamepad : port type of a standard controller
+	movieGamepad = 1
+)
+
+// movieButtons : the buttons in the order of the FM2 columns, RLDUTSBA
+var movieButtons = [8]Buttons{ButtonRight, ButtonLeft, ButtonDown, ButtonUp, ButtonStart, ButtonSelect, ButtonB, ButtonA}
+
+// MovieFrame : the input of a frame
+type MovieFrame struct {
+	Commands MovieCommand
+	Buttons  [2]Buttons
+}
+
+// Movie : the input of a run from power on, a frame at a time, read and
+// written in the FM2 text format of FCEUX
+type Movie struct {
+	ROMFilename string
+	// ROMChecksum : MovieChecksum of the ROM the movie was recorded with
+	ROMChecksum   string
+	GUID          string
+	PAL           bool
+	RerecordCount int
+	Comments      []string
+	Frames        []MovieFrame
+}
+
+// CreateMovie : an empty movie for the cartridge, with a new GUID
+func CreateMovie(romFilename string, cart *Cartridge) *Movie {
+	var id [16]byte
+	rand.Read(id[:])
+	return &Movie{
+		ROMFilename: romFilename,
+		ROMChecksum: MovieChecksum(cart),
+		GUID:        fmt.Sprintf("%X-%X-%X-%X-%X", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16]),
+		PAL:         cart.TVSystem() == PAL,
+	}
+}
+
+// MovieChecksum : the checksum FM2 keeps of the ROM, the MD5 of the PRG and
+// CHR ROM in base64
+func MovieChecksum(cart *Cartridge) string {
+	h := md5.New()
+	h.Write(cart.PRGMemory)
+	if cart.CHABanks > 0 {
+		h.Write(cart.CHAMemory)
+	}
+	return "base64:" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// CheckROM : an error when the movie was recorded with another ROM, it can
+// still be played but is likely to go out of sync
+func (m *Movie) CheckROM(cart *Cartridge) error {
+	if sum := MovieChecksum(cart); m.ROMChecksum != sum {
+		return fmt.Errorf("the movie was recorded with %s (%s), this ROM is %s", m.ROMFilename, m.ROMChecksum, sum)
+	}
+	return nil
+}
+
+// OpenMovie : loads a .fm2 file
+func OpenMovie(path string) (*Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	m, err := ReadMovie(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// ReadMovie : reads a movie in the FM2 text format, only standard controllers
+// in the first two ports are supported
+func ReadMovie(r io.Reader) (*Movie, error) {
+	m := &Movie{}
+	ports := [3]int{movieGamepad, movieGamepad, 0}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(text, "|") {
+			frame, err := readMovieFrame(text, ports)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			m.Frames = append(m.Frames, frame)
+			continue
+		}
+
+		parts := strings.SplitN(text, " ", 2)
+		key, value := parts[0], ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+		number := func() (int, error) {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: %s is not a number", line, key)
+			}
+			return n, nil
+		}
+		var n int
+		var err error
+		switch key {
+		case "":
+			continue
+		case "romFilename":
+			m.ROMFilename = value
+		case "romChecksum":
+			m.ROMChecksum = value
+		case "guid":
+			m.GUID = value
+		case "comment":
+			m.Comments = append(m.Comments, value)
+		case "version":
+			if n, err = number(); err == nil && n != movieVersion {
+				err = fmt.Errorf("line %d: FM2 version %d is not supported", line, n)
+			}
+		case "binary", "fourscore", "FDS":
+			if n, err = number(); err == nil && n != 0 {
+				err = fmt.Errorf("line %d: %s movies are not supported", line, key)
+			}
+		case "palFlag":
+			n, err = number()
+			m.PAL = n != 0
+		case "rerecordCount":
+			m.RerecordCount, err = number()
+		case "port0", "port1", "port2":
+			n, err = number()
+			if err == nil && n != 0 && n != movieGamepad {
+				err = fmt.Errorf("line %d: only standard controllers are supported", line)
+			}
+			ports[key[4]-'0'] = n
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readMovieFrame : a line like "|0|R......A|........||", a button is held
+// when its column is neither a dot nor a space
+func readMovieFrame(text string, ports [3]int) (MovieFrame, error) {
+	var frame MovieFrame
+	fields := strings.Split(text, "|")
+	if len(fields) < 6 {
+		return frame, fmt.Errorf("expected |commands|port0|port1|port2|")
+	}
+	commands, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return frame, fmt.Errorf("bad commands %q", fields[1])
+	}
+	frame.Commands = MovieCommand(commands)
+	for port := 0; port < 2; port++ {
+		field := fields[2+port]
+		if ports[port] != movieGamepad {
+			continue
+		}
+		if len(field) != len(movieButtons) {
+			return frame, fmt.Errorf("expected %d buttons for port %d, got %q", len(movieButtons), port, field)
+		}
+		for i, button := range movieButtons {
+			if field[i] != '.' && field[i] != ' ' {
+				frame.Buttons[port] |= button
+			}
+		}
+	}
+	return frame, nil
+}
+
+// Write : writes the movie as FM2 with both ports as standard controllers
+func (m *Movie) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	pal := 0
+	if m.PAL {
+		pal = 1
+	}
+	fmt.Fprintf(bw, "version %d\nemuVersion %d\nrerecordCount %d\npalFlag %d\n", movieVersion, movieEmuVersion, m.RerecordCount, pal)
+	fmt.Fprintf(bw, "romFilename %s\nromChecksum %s\nguid %s\n", m.ROMFilename, m.ROMChecksum, m.GUID)
+	fmt.Fprintf(bw, "fourscore 0\nmicrophone 0\nport0 %d\nport1 %d\nport2 0\nFDS 0\nNewPPU 0\n", movieGamepad, movieGamepad)
+	for _, comment := range m.Comments {
+		fmt.Fprintf(bw, "comment %s\n", comment)
+	}
+
+	line := make([]byte, 0, 32)
+	for _, frame := range m.Frames {
+		line = append(line[:0], '|')
+		line = strconv.AppendInt(line, int64(frame.Commands), 10)
+		for _, buttons := range frame.Buttons {
+			line = append(line, '|')
+			for i, button := range movieButtons {
+				c := byte('.')
+				if buttons&button != 0 {
+					c = "RLDUTSBA"[i]
+				}
+				line = append(line, c)
+			}
+		}
+		line = append(line, "||\n"...)
+		bw.Write(line)
+	}
+	return bw.Flush()
+}
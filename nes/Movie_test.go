@@ -0,0 +1,138 @@
+package nes
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+const fm2Sample = `version 3
+emuVersion 22020
+rerecordCount 12
+palFlag 1
+romFilename game
+romChecksum base64:jm9sp2CzwzTxzdfgqs6o7w==
+guid 452DE2C3-EF43-2FA9-77AC-0677FC51543B
+fourscore 0
+microphone 0
+port0 1
+port1 1
+port2 0
+FDS 0
+NewPPU 0
+comment author someone
+|0|........|........||
+|1|R......A|  D T   ||
+|2|RLDUTSBA|........||
+`
+
+func TestReadMovie(t *testing.T) {
+	m, err := ReadMovie(strings.NewReader(fm2Sample))
+	assertNil(t, err)
+	assertTrue(t, m.PAL)
+	assertTrue(t, m.RerecordCount == 12)
+	assertTrue(t, m.ROMFilename == "game")
+	assertTrue(t, m.ROMChecksum == "base64:jm9sp2CzwzTxzdfgqs6o7w==")
+	assertTrue(t, len(m.Comments) == 1 && m.Comments[0] == "author someone")
+	assertTrue(t, len(m.Frames) == 3)
+	assertTrue(t, m.Frames[1] == MovieFrame{MovieSoftReset, [2]Buttons{ButtonRight | ButtonA, ButtonDown | ButtonStart}})
+	assertTrue(t, m.Frames[2] == MovieFrame{MovieHardReset, [2]Buttons{0xFF, 0}})
+
+	var out bytes.Buffer
+	assertNil(t, m.Write(&out))
+	again, err := ReadMovie(&out)
+	assertNil(t, err)
+	assertTrue(t, again.PAL && again.GUID == m.GUID && again.RerecordCount == 12)
+	assertTrue(t, len(again.Frames) == 3)
+	for i := range m.Frames {
+		assertTrue(t, again.Frames[i] == m.Frames[i])
+	}
+
+	for _, bad := range []string{
+		"version 2\n",
+		"fourscore 1\n",
+		"port1 2\n",
+		"rerecordCount many\n",
+		"|0|........|\n",
+		"|x|........|........||\n",
+		"|0|.....|........||\n",
+	} {
+		_, err := ReadMovie(strings.NewReader(bad))
+		assertTrue(t, err != nil)
+	}
+}
+
+// movieProgram : counts frames in $10 and adds controller 1 to $11 every
+// frame, so the RAM depends on every input and on when the resets happen
+const movieProgram = `
+		.org $8000
+reset:  LDA #$00
+        STA $10
+@frame: LDA #$01
+        STA $4016
+        LDA #$00
+        STA $4016
+        LDX #$08
+@read:  LDA $4016
+        LSR A
+        ROL $12
+        DEX
+        BNE @read
+        LDA $12
+        CLC
+        ADC $11
+        STA $11
+        INC $10
+@wait:  BIT $2002
+        BPL @wait
+        JMP @frame
+`
+
+func TestMovieRecordAndPlay(t *testing.T) {
+	cart := TestCartridge(asm.MustAssemble(movieProgram).Hex(), 0x8000)
+	nes := CreateConsole()
+	nes.InsertCartridge(cart)
+	nes.RunFrame()
+
+	m := CreateMovie("test.nes", cart)
+	assertNil(t, m.CheckROM(cart))
+	nes.RecordMovie(m)
+	var recorded [][]byte
+	for frame := 0; frame < 30; frame++ {
+		nes.SetController(0, Buttons(frame*7))
+		switch frame {
+		case 10:
+			nes.Reset()
+		case 20:
+			nes.PowerCycle()
+		}
+		nes.RunFrame()
+		recorded = append(recorded, append([]byte(nil), nes.RAM()[0x10:0x13]...))
+	}
+	nes.StopMovie()
+	assertTrue(t, len(m.Frames) == 30)
+	assertTrue(t, m.Frames[10].Commands == MovieSoftReset)
+	assertTrue(t, m.Frames[20].Commands == MovieHardReset)
+	assertTrue(t, m.Frames[29].Buttons[0] == Buttons(29*7))
+
+	// played on another console, from whatever state it was in
+	other := CreateConsole()
+	other.InsertCartridge(cart)
+	for i := 0; i < 5; i++ {
+		other.SetController(0, ButtonStart)
+		other.RunFrame()
+	}
+	other.PlayMovie(m)
+	for frame := 0; frame < 30; frame++ {
+		assertTrue(t, other.MoviePlaying())
+		other.RunFrame()
+		if !bytes.Equal(recorded[frame], other.RAM()[0x10:0x13]) {
+			t.Fatalf("Expected frame %d to be % X, got: % X", frame, recorded[frame], other.RAM()[0x10:0x13])
+		}
+	}
+	assertFalse(t, other.MoviePlaying())
+
+	assertTrue(t, m.CheckROM(TestCartridge("EA", 0x8000)) != nil)
+}
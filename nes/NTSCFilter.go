@@ -0,0 +1,132 @@
+package nes
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// ntscSamplesPerDot : samples of the composite signal for each dot
+	ntscSamplesPerDot = 12
+	// ntscSteps : the color clock cut in 36 steps. The PPU changes the signal
+	// on both edges of the master clock, 12 times a color clock and 8 times a
+	// dot, so a dot is 24 steps and a sample 2 of them
+	ntscSteps = 36
+	// ntscStepsPerSample : steps of the color clock a sample covers
+	ntscStepsPerSample = 2
+	// ntscLinePhase : how far the color clock moves from a line to the next,
+	// 341 dots of 24 steps
+	ntscLinePhase = 341 * ntscSamplesPerDot * ntscStepsPerSample % ntscSteps
+	// ntscWindow : samples decoded for each dot, a whole color clock
+	ntscWindow = ntscSteps / ntscStepsPerSample
+	// ntscGammaSteps : entries of the table that applies the gamma
+	ntscGammaSteps = 1024
+)
+
+// NTSCFilter : draws the picture the way a TV shows it. The colors the PPU
+// outputs are turned into the composite signal, 12 samples a dot, and each
+// dot is decoded back from the color clock of samples around it, so colors
+// bleed into their neighbours and fringe at sharp edges. The signal starts the
+// frame on one of 3 phases of the color clock, which makes the artifacts crawl
+// from frame to frame like they do on the console
+type NTSCFilter struct {
+	params NTSCParams
+	// signal : level of the 512 colors for a sample starting at each step of
+	// the color clock, the mean of the levels the PPU outputs during it
+	signal [8 * paletteColors][ntscSteps]float32
+	// cos, sin : the color subcarrier in the middle of a sample starting at
+	// each step
+	cos, sin [ntscSteps]float32
+	// gamma : 8 bit channel for a level between 0 and 1
+	gamma [ntscGammaSteps + 1]uint8
+	// line : the samples of the line being decoded, with black on both sides
+	line [FrameWidth*ntscSamplesPerDot + ntscWindow]float32
+}
+
+// CreateNTSCFilter : creates a filter with the knobs of the decoder
+func CreateNTSCFilter(params NTSCParams) *NTSCFilter {
+	const stepsPerPhase = ntscSteps / 12
+	f := &NTSCFilter{params: params}
+	for color := range f.signal {
+		for step := range f.signal[color] {
+			var level float64
+			for s := step; s < step+ntscStepsPerSample; s++ {
+				level += ntscSignal(color&0x3F, color>>6, s%ntscSteps/stepsPerPhase)
+			}
+			f.signal[color][step] = float32(level / ntscStepsPerSample)
+		}
+	}
+	for step := range f.cos {
+		// the palette pairs each level with the carrier at its start, the
+		// sample with the one at its middle
+		phase := (float64(step)+ntscStepsPerSample/2)/stepsPerPhase - 0.5
+		cos, sin := params.carrier(phase)
+		f.cos[step], f.sin[step] = float32(cos), float32(sin)
+	}
+	for i := range f.gamma {
+		f.gamma[i] = uint8(math.Round(255 * math.Pow(float64(i)/ntscGammaSteps, 1/params.Gamma)))
+	}
+	return f
+}
+
+// Apply : draws the screen of the PPU into img, which must be FrameWidth by
+// FrameHeight pixels, frame picks the phase the signal starts on
+func (f *NTSCFilter) Apply(screen []uint16, frame int, img *image.RGBA) {
+	const margin = ntscWindow / 2
+	const dotSteps = ntscSamplesPerDot * ntscStepsPerSample
+	yScale := float32(f.params.Contrast / ntscWindow)
+	iqScale := float32(f.params.Saturation * f.params.Contrast / (ntscWindow / 2))
+	brightness := float32(f.params.Brightness)
+
+	for y := 0; y < FrameHeight; y++ {
+		start := (frame%3*ntscLinePhase + y*ntscLinePhase) % ntscSteps
+
+		// encode
+		samples := f.line[margin : margin+FrameWidth*ntscSamplesPerDot]
+		for x, pixel := range screen[y*FrameWidth : (y+1)*FrameWidth] {
+			levels := &f.signal[pixel&0x1FF]
+			step := (start + x*dotSteps) % ntscSteps
+			for s := 0; s < ntscSamplesPerDot; s++ {
+				samples[x*ntscSamplesPerDot+s] = levels[step]
+				if step += ntscStepsPerSample; step >= ntscSteps {
+					step -= ntscSteps
+				}
+			}
+		}
+
+		// decode the color clock centered on each dot
+		out := img.Pix[y*img.Stride:]
+		for x := 0; x < FrameWidth; x++ {
+			first := x*ntscSamplesPerDot + ntscSamplesPerDot/2
+			step := (start + (first-margin)*ntscStepsPerSample + ntscSteps) % ntscSteps
+			var luma, i, q float32
+			for _, signal := range f.line[first : first+ntscWindow] {
+				luma += signal
+				i += signal * f.cos[step]
+				q += signal * f.sin[step]
+				if step += ntscStepsPerSample; step >= ntscSteps {
+					step -= ntscSteps
+				}
+			}
+			luma = luma*yScale + brightness
+			i *= iqScale
+			q *= iqScale
+
+			out[4*x] = f.channel(luma + 0.946882*i + 0.623557*q)
+			out[4*x+1] = f.channel(luma - 0.274788*i - 0.635691*q)
+			out[4*x+2] = f.channel(luma - 1.108545*i + 1.709007*q)
+			out[4*x+3] = 255
+		}
+	}
+}
+
+// channel : a level clamped between 0 and 1 as an 8 bit channel
+func (f *NTSCFilter) channel(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return f.gamma[ntscGammaSteps]
+	}
+	return f.gamma[int(v*ntscGammaSteps+0.5)]
+}
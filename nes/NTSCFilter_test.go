@@ -0,0 +1,100 @@
+package nes
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// filterScreen : a screen of the first color with the second one on the
+// right half
+func filterScreen(left, right uint16) []uint16 {
+	screen := make([]uint16, FrameWidth*FrameHeight)
+	for i := range screen {
+		screen[i] = left
+		if i%FrameWidth >= FrameWidth/2 {
+			screen[i] = right
+		}
+	}
+	return screen
+}
+
+// near : true when no channel differs by more than 2
+func near(a, b color.RGBA) bool {
+	d := func(x, y uint8) bool {
+		diff := int(x) - int(y)
+		return diff >= -2 && diff <= 2
+	}
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B)
+}
+
+func TestNTSCFilterFlatColors(t *testing.T) {
+	filter := CreateNTSCFilter(DefaultNTSCParams())
+	palette := GeneratePalette(DefaultNTSCParams())
+	img := image.NewRGBA(image.Rect(0, 0, FrameWidth, FrameHeight))
+	assertFalse(t, near(color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}))
+
+	// away from edges a flat color decodes to the generated palette
+	for _, pixel := range []uint16{0x0F, 0x16, 0x2A, 0x30, 1<<6 | 0x21, 7<<6 | 0x12} {
+		for frame := 0; frame < 3; frame++ {
+			filter.Apply(filterScreen(pixel, pixel), frame, img)
+			want := palette[pixel>>6][pixel&0x3F]
+			if got := img.RGBAAt(100, 50+frame); !near(got, want) {
+				t.Errorf("Expected $%03X on frame %d to be %v, got: %v", pixel, frame, want, got)
+			}
+		}
+	}
+}
+
+func TestNTSCFilterArtifacts(t *testing.T) {
+	filter := CreateNTSCFilter(DefaultNTSCParams())
+	palette := GeneratePalette(DefaultNTSCParams())
+	screen := filterScreen(0x0F, 0x30)
+	frames := make([]*image.RGBA, 4)
+	for frame := range frames {
+		frames[frame] = image.NewRGBA(image.Rect(0, 0, FrameWidth, FrameHeight))
+		filter.Apply(screen, frame, frames[frame])
+	}
+
+	// the edge between black and white fringes with color
+	edge := frames[0].RGBAAt(FrameWidth/2, 10)
+	if edge == palette[0][0x0F] || edge == palette[0][0x30] || (edge.R == edge.G && edge.G == edge.B) {
+		t.Errorf("Expected color fringing at the edge, got: %v", edge)
+	}
+
+	// the fringes move from line to line and frame to frame, every 3 frames
+	// they are back where they started
+	assertFalse(t, frames[0].RGBAAt(FrameWidth/2, 10) == frames[0].RGBAAt(FrameWidth/2, 11))
+	assertFalse(t, frames[0].RGBAAt(FrameWidth/2, 10) == frames[1].RGBAAt(FrameWidth/2, 10))
+	assertTrue(t, string(frames[0].Pix) == string(frames[3].Pix))
+}
+
+func TestConsoleNTSCFilter(t *testing.T) {
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+	nes.RunFrame()
+	plain := nes.Frame().RGBAAt(10, 10)
+
+	nes.SetNTSCFilter(CreateNTSCFilter(DefaultNTSCParams()))
+	filtered := nes.Frame().RGBAAt(10, 10)
+	assertTrue(t, near(filtered, GeneratePalette(DefaultNTSCParams())[0][0]))
+	assertFalse(t, filtered == plain)
+
+	nes.SetNTSCFilter(nil)
+	assertTrue(t, nes.Frame().RGBAAt(10, 10) == plain)
+}
+
+// BenchmarkNTSCFilter : a frame has to take less than 16ms to keep up with
+// the console on one core
+func BenchmarkNTSCFilter(b *testing.B) {
+	filter := CreateNTSCFilter(DefaultNTSCParams())
+	screen := make([]uint16, FrameWidth*FrameHeight)
+	for i := range screen {
+		screen[i] = uint16(i*7) & 0x1FF
+	}
+	img := image.NewRGBA(image.Rect(0, 0, FrameWidth, FrameHeight))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter.Apply(screen, i, img)
+	}
+}
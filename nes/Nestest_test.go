@@ -0,0 +1,436 @@
+package nes
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+const (
+	nestestROM = "../test/roms/nestest.nes"
+	nestestLog = "../test/roms/nestest.log"
+	// nestestEnd : where the automated run of nestest ends, after copying the
+	// error codes of its tests to $02 and $03
+	nestestEnd = 0xC66E
+	// nestestMaxLines : the golden log has 8991 lines, a run going past
+	// this is lost
+	nestestMaxLines = 10000
+	// lines of context shown around the first divergence
+	nestestContext = 5
+)
+
+// loadNestestGolden : reads the golden log of Nintendulator, or returns the
+// embedded first lines of it when it is not in test/roms. complete is false
+// for the embedded lines
+func loadNestestGolden(t *testing.T) (golden []string, complete bool) {
+	data, err := ioutil.ReadFile(nestestLog)
+	if os.IsNotExist(err) {
+		t.Logf("%s not found, checking the embedded first %d lines only", nestestLog, len(nestestGolden))
+		return nestestGolden, false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, true
+}
+
+// TestNestest : runs nestest from $C000 until it ends. The codes of the
+// first failed test of the official and of the unofficial opcodes are at $02
+// and $03, both are 0 when every test passes. The trace is also compared
+// to the golden log, or to its embedded first lines, up to the first line
+// that differs
+func TestNestest(t *testing.T) {
+	if _, err := os.Stat(nestestROM); err != nil {
+		t.Skipf("%s not found", nestestROM)
+	}
+
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(LoadCartridge(nestestROM))
+	nes.StartAutomation(0xC000)
+
+	golden, complete := loadNestestGolden(t)
+	trace := make([]string, 0, nestestMaxLines)
+	context := func(i int) string {
+		from := i - nestestContext
+		if from < 0 {
+			from = 0
+		}
+		var sb strings.Builder
+		for j := from; j < i; j++ {
+			sb.WriteString("\n    ")
+			sb.WriteString(trace[j])
+		}
+		return sb.String()
+	}
+
+	// the RTS at nestestEnd is the last line of the golden log
+	for i := 0; ; i++ {
+		if i == nestestMaxLines {
+			t.Fatalf("nestest did not end after %d instructions%s", i, context(i))
+		}
+		got := nes.TraceLine()
+		trace = append(trace, got)
+		if i < len(golden) {
+			if got != golden[i] {
+				t.Fatalf("trace diverges from golden log at line %d%s\n got %s\nwant %s",
+					i+1, context(i), got, golden[i])
+			}
+		} else if complete {
+			t.Fatalf("trace goes on after the %d lines of the golden log%s", len(golden), context(i+1))
+		}
+		if nes.cpu.pc == nestestEnd {
+			break
+		}
+		nes.ExecuteOperation()
+	}
+	if complete && len(trace) != len(golden) {
+		t.Errorf("nestest ended after %d lines, the golden log has %d", len(trace), len(golden))
+	}
+	if official := nes.ram[0x02]; official != 0 {
+		t.Errorf("official opcodes failed with error code $%02X", official)
+	}
+	if unofficial := nes.ram[0x03]; unofficial != 0 {
+		t.Errorf("unofficial opcodes failed with error code $%02X", unofficial)
+	}
+}
+
+func TestTraceLineOperands(t *testing.T) {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(TestCartridge("A2 03 B5 10 6C 00 02 B1 80 0A", 0x8000))
+	nes.StartAutomation(0x8000)
+	nes.ram[0x13] = 0x5A
+	nes.ram[0x80] = 0x00
+	nes.ram[0x81] = 0x03
+	nes.ram[0x0200] = 0x34
+	nes.ram[0x0201] = 0x12
+
+	cases := []struct {
+		pc   Word
+		want string
+	}{
+		{0x8000, "8000  A2 03     LDX #$03"},
+		{0x8002, "8002  B5 10     LDA $10,X @ 13 = 5A"},
+		{0x8004, "8004  6C 00 02  JMP ($0200) = 1234"},
+		{0x8007, "8007  B1 80     LDA ($80),Y = 0300 @ 0300 = 00"},
+		{0x8009, "8009  0A        ASL A"},
+	}
+	nes.ExecuteOperation()
+	for _, tc := range cases {
+		nes.cpu.pc = tc.pc
+		got := strings.TrimRight(nes.TraceLine()[:48], " ")
+		if got != tc.want {
+			t.Errorf("Expected: %q, got: %q", tc.want, got)
+		}
+	}
+}
+
+// nestestGolden : first lines of the nestest.log golden file, always checked
+// even when the complete log is not present in test/roms. Line 104 is the PLP
+// pulling $FF, which leaves P at $EF because B is not a flag of the CPU
+var nestestGolden = []string{
+	"C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7",
+	"C5F5  A2 00     LDX #$00                        A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 30 CYC:10",
+	"C5F7  86 00     STX $00 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 36 CYC:12",
+	"C5F9  86 10     STX $10 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 45 CYC:15",
+	"C5FB  86 11     STX $11 = 00                    A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 54 CYC:18",
+	"C5FD  20 2D C7  JSR $C72D                       A:00 X:00 Y:00 P:26 SP:FD PPU:  0, 63 CYC:21",
+	"C72D  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0, 81 CYC:27",
+	"C72E  38        SEC                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0, 87 CYC:29",
+	"C72F  B0 04     BCS $C735                       A:00 X:00 Y:00 P:27 SP:FB PPU:  0, 93 CYC:31",
+	"C735  EA        NOP                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,102 CYC:34",
+	"C736  18        CLC                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,108 CYC:36",
+	"C737  B0 03     BCS $C73C                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,114 CYC:38",
+	"C739  4C 40 C7  JMP $C740                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,120 CYC:40",
+	"C740  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,129 CYC:43",
+	"C741  38        SEC                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,135 CYC:45",
+	"C742  90 03     BCC $C747                       A:00 X:00 Y:00 P:27 SP:FB PPU:  0,141 CYC:47",
+	"C744  4C 4B C7  JMP $C74B                       A:00 X:00 Y:00 P:27 SP:FB PPU:  0,147 CYC:49",
+	"C74B  EA        NOP                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,156 CYC:52",
+	"C74C  18        CLC                             A:00 X:00 Y:00 P:27 SP:FB PPU:  0,162 CYC:54",
+	"C74D  90 04     BCC $C753                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,168 CYC:56",
+	"C753  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,177 CYC:59",
+	"C754  A9 00     LDA #$00                        A:00 X:00 Y:00 P:26 SP:FB PPU:  0,183 CYC:61",
+	"C756  F0 04     BEQ $C75C                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,189 CYC:63",
+	"C75C  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,198 CYC:66",
+	"C75D  A9 40     LDA #$40                        A:00 X:00 Y:00 P:26 SP:FB PPU:  0,204 CYC:68",
+	"C75F  F0 03     BEQ $C764                       A:40 X:00 Y:00 P:24 SP:FB PPU:  0,210 CYC:70",
+	"C761  4C 68 C7  JMP $C768                       A:40 X:00 Y:00 P:24 SP:FB PPU:  0,216 CYC:72",
+	"C768  EA        NOP                             A:40 X:00 Y:00 P:24 SP:FB PPU:  0,225 CYC:75",
+	"C769  A9 40     LDA #$40                        A:40 X:00 Y:00 P:24 SP:FB PPU:  0,231 CYC:77",
+	"C76B  D0 04     BNE $C771                       A:40 X:00 Y:00 P:24 SP:FB PPU:  0,237 CYC:79",
+	"C771  EA        NOP                             A:40 X:00 Y:00 P:24 SP:FB PPU:  0,246 CYC:82",
+	"C772  A9 00     LDA #$00                        A:40 X:00 Y:00 P:24 SP:FB PPU:  0,252 CYC:84",
+	"C774  D0 03     BNE $C779                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,258 CYC:86",
+	"C776  4C 7D C7  JMP $C77D                       A:00 X:00 Y:00 P:26 SP:FB PPU:  0,264 CYC:88",
+	"C77D  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  0,273 CYC:91",
+	"C77E  A9 FF     LDA #$FF                        A:00 X:00 Y:00 P:26 SP:FB PPU:  0,279 CYC:93",
+	"C780  85 01     STA $01 = 00                    A:FF X:00 Y:00 P:A4 SP:FB PPU:  0,285 CYC:95",
+	"C782  24 01     BIT $01 = FF                    A:FF X:00 Y:00 P:A4 SP:FB PPU:  0,294 CYC:98",
+	"C784  70 04     BVS $C78A                       A:FF X:00 Y:00 P:E4 SP:FB PPU:  0,303 CYC:101",
+	"C78A  EA        NOP                             A:FF X:00 Y:00 P:E4 SP:FB PPU:  0,312 CYC:104",
+	"C78B  24 01     BIT $01 = FF                    A:FF X:00 Y:00 P:E4 SP:FB PPU:  0,318 CYC:106",
+	"C78D  50 03     BVC $C792                       A:FF X:00 Y:00 P:E4 SP:FB PPU:  0,327 CYC:109",
+	"C78F  4C 96 C7  JMP $C796                       A:FF X:00 Y:00 P:E4 SP:FB PPU:  0,333 CYC:111",
+	"C796  EA        NOP                             A:FF X:00 Y:00 P:E4 SP:FB PPU:  1,  1 CYC:114",
+	"C797  A9 00     LDA #$00                        A:FF X:00 Y:00 P:E4 SP:FB PPU:  1,  7 CYC:116",
+	"C799  85 01     STA $01 = FF                    A:00 X:00 Y:00 P:66 SP:FB PPU:  1, 13 CYC:118",
+	"C79B  24 01     BIT $01 = 00                    A:00 X:00 Y:00 P:66 SP:FB PPU:  1, 22 CYC:121",
+	"C79D  50 04     BVC $C7A3                       A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 31 CYC:124",
+	"C7A3  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 40 CYC:127",
+	"C7A4  24 01     BIT $01 = 00                    A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 46 CYC:129",
+	"C7A6  70 03     BVS $C7AB                       A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 55 CYC:132",
+	"C7A8  4C AF C7  JMP $C7AF                       A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 61 CYC:134",
+	"C7AF  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 70 CYC:137",
+	"C7B0  A9 00     LDA #$00                        A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 76 CYC:139",
+	"C7B2  10 04     BPL $C7B8                       A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 82 CYC:141",
+	"C7B8  EA        NOP                             A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 91 CYC:144",
+	"C7B9  A9 80     LDA #$80                        A:00 X:00 Y:00 P:26 SP:FB PPU:  1, 97 CYC:146",
+	"C7BB  10 03     BPL $C7C0                       A:80 X:00 Y:00 P:A4 SP:FB PPU:  1,103 CYC:148",
+	"C7BD  4C D9 C7  JMP $C7D9                       A:80 X:00 Y:00 P:A4 SP:FB PPU:  1,109 CYC:150",
+	"C7D9  EA        NOP                             A:80 X:00 Y:00 P:A4 SP:FB PPU:  1,118 CYC:153",
+	"C7DA  60        RTS                             A:80 X:00 Y:00 P:A4 SP:FB PPU:  1,124 CYC:155",
+	"C600  20 DB C7  JSR $C7DB                       A:80 X:00 Y:00 P:A4 SP:FD PPU:  1,142 CYC:161",
+	"C7DB  EA        NOP                             A:80 X:00 Y:00 P:A4 SP:FB PPU:  1,160 CYC:167",
+	"C7DC  A9 FF     LDA #$FF                        A:80 X:00 Y:00 P:A4 SP:FB PPU:  1,166 CYC:169",
+	"C7DE  85 01     STA $01 = 00                    A:FF X:00 Y:00 P:A4 SP:FB PPU:  1,172 CYC:171",
+	"C7E0  24 01     BIT $01 = FF                    A:FF X:00 Y:00 P:A4 SP:FB PPU:  1,181 CYC:174",
+	"C7E2  A9 00     LDA #$00                        A:FF X:00 Y:00 P:E4 SP:FB PPU:  1,190 CYC:177",
+	"C7E4  38        SEC                             A:00 X:00 Y:00 P:66 SP:FB PPU:  1,196 CYC:179",
+	"C7E5  78        SEI                             A:00 X:00 Y:00 P:67 SP:FB PPU:  1,202 CYC:181",
+	"C7E6  F8        SED                             A:00 X:00 Y:00 P:67 SP:FB PPU:  1,208 CYC:183",
+	"C7E7  08        PHP                             A:00 X:00 Y:00 P:6F SP:FB PPU:  1,214 CYC:185",
+	"C7E8  68        PLA                             A:00 X:00 Y:00 P:6F SP:FA PPU:  1,223 CYC:188",
+	"C7E9  29 EF     AND #$EF                        A:7F X:00 Y:00 P:6D SP:FB PPU:  1,235 CYC:192",
+	"C7EB  C9 6F     CMP #$6F                        A:6F X:00 Y:00 P:6D SP:FB PPU:  1,241 CYC:194",
+	"C7ED  F0 04     BEQ $C7F3                       A:6F X:00 Y:00 P:6F SP:FB PPU:  1,247 CYC:196",
+	"C7F3  EA        NOP                             A:6F X:00 Y:00 P:6F SP:FB PPU:  1,256 CYC:199",
+	"C7F4  A9 40     LDA #$40                        A:6F X:00 Y:00 P:6F SP:FB PPU:  1,262 CYC:201",
+	"C7F6  85 01     STA $01 = FF                    A:40 X:00 Y:00 P:6D SP:FB PPU:  1,268 CYC:203",
+	"C7F8  24 01     BIT $01 = 40                    A:40 X:00 Y:00 P:6D SP:FB PPU:  1,277 CYC:206",
+	"C7FA  D8        CLD                             A:40 X:00 Y:00 P:6D SP:FB PPU:  1,286 CYC:209",
+	"C7FB  A9 10     LDA #$10                        A:40 X:00 Y:00 P:65 SP:FB PPU:  1,292 CYC:211",
+	"C7FD  18        CLC                             A:10 X:00 Y:00 P:65 SP:FB PPU:  1,298 CYC:213",
+	"C7FE  08        PHP                             A:10 X:00 Y:00 P:64 SP:FB PPU:  1,304 CYC:215",
+	"C7FF  68        PLA                             A:10 X:00 Y:00 P:64 SP:FA PPU:  1,313 CYC:218",
+	"C800  29 EF     AND #$EF                        A:74 X:00 Y:00 P:64 SP:FB PPU:  1,325 CYC:222",
+	"C802  C9 64     CMP #$64                        A:64 X:00 Y:00 P:64 SP:FB PPU:  1,331 CYC:224",
+	"C804  F0 04     BEQ $C80A                       A:64 X:00 Y:00 P:67 SP:FB PPU:  1,337 CYC:226",
+	"C80A  EA        NOP                             A:64 X:00 Y:00 P:67 SP:FB PPU:  2,  5 CYC:229",
+	"C80B  A9 80     LDA #$80                        A:64 X:00 Y:00 P:67 SP:FB PPU:  2, 11 CYC:231",
+	"C80D  85 01     STA $01 = 40                    A:80 X:00 Y:00 P:E5 SP:FB PPU:  2, 17 CYC:233",
+	"C80F  24 01     BIT $01 = 80                    A:80 X:00 Y:00 P:E5 SP:FB PPU:  2, 26 CYC:236",
+	"C811  F8        SED                             A:80 X:00 Y:00 P:A5 SP:FB PPU:  2, 35 CYC:239",
+	"C812  A9 00     LDA #$00                        A:80 X:00 Y:00 P:AD SP:FB PPU:  2, 41 CYC:241",
+	"C814  38        SEC                             A:00 X:00 Y:00 P:2F SP:FB PPU:  2, 47 CYC:243",
+	"C815  08        PHP                             A:00 X:00 Y:00 P:2F SP:FB PPU:  2, 53 CYC:245",
+	"C816  68        PLA                             A:00 X:00 Y:00 P:2F SP:FA PPU:  2, 62 CYC:248",
+	"C817  29 EF     AND #$EF                        A:3F X:00 Y:00 P:2D SP:FB PPU:  2, 74 CYC:252",
+	"C819  C9 2F     CMP #$2F                        A:2F X:00 Y:00 P:2D SP:FB PPU:  2, 80 CYC:254",
+	"C81B  F0 04     BEQ $C821                       A:2F X:00 Y:00 P:2F SP:FB PPU:  2, 86 CYC:256",
+	"C821  EA        NOP                             A:2F X:00 Y:00 P:2F SP:FB PPU:  2, 95 CYC:259",
+	"C822  A9 FF     LDA #$FF                        A:2F X:00 Y:00 P:2F SP:FB PPU:  2,101 CYC:261",
+	"C824  48        PHA                             A:FF X:00 Y:00 P:AD SP:FB PPU:  2,107 CYC:263",
+	"C825  28        PLP                             A:FF X:00 Y:00 P:AD SP:FA PPU:  2,116 CYC:266",
+	"C826  D0 09     BNE $C831                       A:FF X:00 Y:00 P:EF SP:FB PPU:  2,128 CYC:270",
+	"C828  10 07     BPL $C831                       A:FF X:00 Y:00 P:EF SP:FB PPU:  2,134 CYC:272",
+	"C82A  50 05     BVC $C831                       A:FF X:00 Y:00 P:EF SP:FB PPU:  2,140 CYC:274",
+	"C82C  90 03     BCC $C831                       A:FF X:00 Y:00 P:EF SP:FB PPU:  2,146 CYC:276",
+	"C82E  4C 35 C8  JMP $C835                       A:FF X:00 Y:00 P:EF SP:FB PPU:  2,152 CYC:278",
+	"C835  EA        NOP                             A:FF X:00 Y:00 P:EF SP:FB PPU:  2,161 CYC:281",
+	"C836  A9 04     LDA #$04                        A:FF X:00 Y:00 P:EF SP:FB PPU:  2,167 CYC:283",
+	"C838  48        PHA                             A:04 X:00 Y:00 P:6D SP:FB PPU:  2,173 CYC:285",
+	"C839  28        PLP                             A:04 X:00 Y:00 P:6D SP:FA PPU:  2,182 CYC:288",
+	"C83A  F0 09     BEQ $C845                       A:04 X:00 Y:00 P:24 SP:FB PPU:  2,194 CYC:292",
+	"C83C  30 07     BMI $C845                       A:04 X:00 Y:00 P:24 SP:FB PPU:  2,200 CYC:294",
+	"C83E  70 05     BVS $C845                       A:04 X:00 Y:00 P:24 SP:FB PPU:  2,206 CYC:296",
+	"C840  B0 03     BCS $C845                       A:04 X:00 Y:00 P:24 SP:FB PPU:  2,212 CYC:298",
+	"C842  4C 49 C8  JMP $C849                       A:04 X:00 Y:00 P:24 SP:FB PPU:  2,218 CYC:300",
+	"C849  EA        NOP                             A:04 X:00 Y:00 P:24 SP:FB PPU:  2,227 CYC:303",
+	"C84A  F8        SED                             A:04 X:00 Y:00 P:24 SP:FB PPU:  2,233 CYC:305",
+	"C84B  A9 FF     LDA #$FF                        A:04 X:00 Y:00 P:2C SP:FB PPU:  2,239 CYC:307",
+	"C84D  85 01     STA $01 = 80                    A:FF X:00 Y:00 P:AC SP:FB PPU:  2,245 CYC:309",
+	"C84F  24 01     BIT $01 = FF                    A:FF X:00 Y:00 P:AC SP:FB PPU:  2,254 CYC:312",
+	"C851  18        CLC                             A:FF X:00 Y:00 P:EC SP:FB PPU:  2,263 CYC:315",
+	"C852  A9 00     LDA #$00                        A:FF X:00 Y:00 P:EC SP:FB PPU:  2,269 CYC:317",
+	"C854  48        PHA                             A:00 X:00 Y:00 P:6E SP:FB PPU:  2,275 CYC:319",
+	"C855  A9 FF     LDA #$FF                        A:00 X:00 Y:00 P:6E SP:FA PPU:  2,284 CYC:322",
+	"C857  68        PLA                             A:FF X:00 Y:00 P:EC SP:FA PPU:  2,290 CYC:324",
+	"C858  D0 09     BNE $C863                       A:00 X:00 Y:00 P:6E SP:FB PPU:  2,302 CYC:328",
+	"C85A  30 07     BMI $C863                       A:00 X:00 Y:00 P:6E SP:FB PPU:  2,308 CYC:330",
+	"C85C  50 05     BVC $C863                       A:00 X:00 Y:00 P:6E SP:FB PPU:  2,314 CYC:332",
+	"C85E  B0 03     BCS $C863                       A:00 X:00 Y:00 P:6E SP:FB PPU:  2,320 CYC:334",
+	"C860  4C 67 C8  JMP $C867                       A:00 X:00 Y:00 P:6E SP:FB PPU:  2,326 CYC:336",
+	"C867  EA        NOP                             A:00 X:00 Y:00 P:6E SP:FB PPU:  2,335 CYC:339",
+	"C868  A9 00     LDA #$00                        A:00 X:00 Y:00 P:6E SP:FB PPU:  3,  0 CYC:341",
+	"C86A  85 01     STA $01 = FF                    A:00 X:00 Y:00 P:6E SP:FB PPU:  3,  6 CYC:343",
+	"C86C  24 01     BIT $01 = 00                    A:00 X:00 Y:00 P:6E SP:FB PPU:  3, 15 CYC:346",
+	"C86E  38        SEC                             A:00 X:00 Y:00 P:2E SP:FB PPU:  3, 24 CYC:349",
+	"C86F  A9 FF     LDA #$FF                        A:00 X:00 Y:00 P:2F SP:FB PPU:  3, 30 CYC:351",
+	"C871  48        PHA                             A:FF X:00 Y:00 P:AD SP:FB PPU:  3, 36 CYC:353",
+	"C872  A9 00     LDA #$00                        A:FF X:00 Y:00 P:AD SP:FA PPU:  3, 45 CYC:356",
+	"C874  68        PLA                             A:00 X:00 Y:00 P:2F SP:FA PPU:  3, 51 CYC:358",
+	"C875  F0 09     BEQ $C880                       A:FF X:00 Y:00 P:AD SP:FB PPU:  3, 63 CYC:362",
+	"C877  10 07     BPL $C880                       A:FF X:00 Y:00 P:AD SP:FB PPU:  3, 69 CYC:364",
+	"C879  70 05     BVS $C880                       A:FF X:00 Y:00 P:AD SP:FB PPU:  3, 75 CYC:366",
+	"C87B  90 03     BCC $C880                       A:FF X:00 Y:00 P:AD SP:FB PPU:  3, 81 CYC:368",
+	"C87D  4C 84 C8  JMP $C884                       A:FF X:00 Y:00 P:AD SP:FB PPU:  3, 87 CYC:370",
+	"C884  60        RTS                             A:FF X:00 Y:00 P:AD SP:FB PPU:  3, 96 CYC:373",
+	"C603  20 85 C8  JSR $C885                       A:FF X:00 Y:00 P:AD SP:FD PPU:  3,114 CYC:379",
+	"C885  EA        NOP                             A:FF X:00 Y:00 P:AD SP:FB PPU:  3,132 CYC:385",
+	"C886  18        CLC                             A:FF X:00 Y:00 P:AD SP:FB PPU:  3,138 CYC:387",
+	"C887  A9 FF     LDA #$FF                        A:FF X:00 Y:00 P:AC SP:FB PPU:  3,144 CYC:389",
+	"C889  85 01     STA $01 = 00                    A:FF X:00 Y:00 P:AC SP:FB PPU:  3,150 CYC:391",
+	"C88B  24 01     BIT $01 = FF                    A:FF X:00 Y:00 P:AC SP:FB PPU:  3,159 CYC:394",
+	"C88D  A9 55     LDA #$55                        A:FF X:00 Y:00 P:EC SP:FB PPU:  3,168 CYC:397",
+	"C88F  09 AA     ORA #$AA                        A:55 X:00 Y:00 P:6C SP:FB PPU:  3,174 CYC:399",
+	"C891  B0 0B     BCS $C89E                       A:FF X:00 Y:00 P:EC SP:FB PPU:  3,180 CYC:401",
+	"C893  10 09     BPL $C89E                       A:FF X:00 Y:00 P:EC SP:FB PPU:  3,186 CYC:403",
+	"C895  C9 FF     CMP #$FF                        A:FF X:00 Y:00 P:EC SP:FB PPU:  3,192 CYC:405",
+	"C897  D0 05     BNE $C89E                       A:FF X:00 Y:00 P:6F SP:FB PPU:  3,198 CYC:407",
+	"C899  50 03     BVC $C89E                       A:FF X:00 Y:00 P:6F SP:FB PPU:  3,204 CYC:409",
+	"C89B  4C A2 C8  JMP $C8A2                       A:FF X:00 Y:00 P:6F SP:FB PPU:  3,210 CYC:411",
+	"C8A2  EA        NOP                             A:FF X:00 Y:00 P:6F SP:FB PPU:  3,219 CYC:414",
+	"C8A3  38        SEC                             A:FF X:00 Y:00 P:6F SP:FB PPU:  3,225 CYC:416",
+	"C8A4  B8        CLV                             A:FF X:00 Y:00 P:6F SP:FB PPU:  3,231 CYC:418",
+	"C8A5  A9 00     LDA #$00                        A:FF X:00 Y:00 P:2F SP:FB PPU:  3,237 CYC:420",
+	"C8A7  09 00     ORA #$00                        A:00 X:00 Y:00 P:2F SP:FB PPU:  3,243 CYC:422",
+	"C8A9  D0 09     BNE $C8B4                       A:00 X:00 Y:00 P:2F SP:FB PPU:  3,249 CYC:424",
+	"C8AB  70 07     BVS $C8B4                       A:00 X:00 Y:00 P:2F SP:FB PPU:  3,255 CYC:426",
+	"C8AD  90 05     BCC $C8B4                       A:00 X:00 Y:00 P:2F SP:FB PPU:  3,261 CYC:428",
+	"C8AF  30 03     BMI $C8B4                       A:00 X:00 Y:00 P:2F SP:FB PPU:  3,267 CYC:430",
+	"C8B1  4C B8 C8  JMP $C8B8                       A:00 X:00 Y:00 P:2F SP:FB PPU:  3,273 CYC:432",
+	"C8B8  EA        NOP                             A:00 X:00 Y:00 P:2F SP:FB PPU:  3,282 CYC:435",
+	"C8B9  18        CLC                             A:00 X:00 Y:00 P:2F SP:FB PPU:  3,288 CYC:437",
+	"C8BA  24 01     BIT $01 = FF                    A:00 X:00 Y:00 P:2E SP:FB PPU:  3,294 CYC:439",
+	"C8BC  A9 55     LDA #$55                        A:00 X:00 Y:00 P:EE SP:FB PPU:  3,303 CYC:442",
+	"C8BE  29 AA     AND #$AA                        A:55 X:00 Y:00 P:6C SP:FB PPU:  3,309 CYC:444",
+	"C8C0  D0 09     BNE $C8CB                       A:00 X:00 Y:00 P:6E SP:FB PPU:  3,315 CYC:446",
+	"C8C2  50 07     BVC $C8CB                       A:00 X:00 Y:00 P:6E SP:FB PPU:  3,321 CYC:448",
+	"C8C4  B0 05     BCS $C8CB                       A:00 X:00 Y:00 P:6E SP:FB PPU:  3,327 CYC:450",
+	"C8C6  30 03     BMI $C8CB                       A:00 X:00 Y:00 P:6E SP:FB PPU:  3,333 CYC:452",
+	"C8C8  4C CF C8  JMP $C8CF                       A:00 X:00 Y:00 P:6E SP:FB PPU:  3,339 CYC:454",
+	"C8CF  EA        NOP                             A:00 X:00 Y:00 P:6E SP:FB PPU:  4,  7 CYC:457",
+	"C8D0  38        SEC                             A:00 X:00 Y:00 P:6E SP:FB PPU:  4, 13 CYC:459",
+	"C8D1  B8        CLV                             A:00 X:00 Y:00 P:6F SP:FB PPU:  4, 19 CYC:461",
+	"C8D2  A9 F8     LDA #$F8                        A:00 X:00 Y:00 P:2F SP:FB PPU:  4, 25 CYC:463",
+	"C8D4  29 EF     AND #$EF                        A:F8 X:00 Y:00 P:AD SP:FB PPU:  4, 31 CYC:465",
+	"C8D6  90 0B     BCC $C8E3                       A:E8 X:00 Y:00 P:AD SP:FB PPU:  4, 37 CYC:467",
+	"C8D8  10 09     BPL $C8E3                       A:E8 X:00 Y:00 P:AD SP:FB PPU:  4, 43 CYC:469",
+	"C8DA  C9 E8     CMP #$E8                        A:E8 X:00 Y:00 P:AD SP:FB PPU:  4, 49 CYC:471",
+	"C8DC  D0 05     BNE $C8E3                       A:E8 X:00 Y:00 P:2F SP:FB PPU:  4, 55 CYC:473",
+	"C8DE  70 03     BVS $C8E3                       A:E8 X:00 Y:00 P:2F SP:FB PPU:  4, 61 CYC:475",
+	"C8E0  4C E7 C8  JMP $C8E7                       A:E8 X:00 Y:00 P:2F SP:FB PPU:  4, 67 CYC:477",
+	"C8E7  EA        NOP                             A:E8 X:00 Y:00 P:2F SP:FB PPU:  4, 76 CYC:480",
+	"C8E8  18        CLC                             A:E8 X:00 Y:00 P:2F SP:FB PPU:  4, 82 CYC:482",
+	"C8E9  24 01     BIT $01 = FF                    A:E8 X:00 Y:00 P:2E SP:FB PPU:  4, 88 CYC:484",
+	"C8EB  A9 5F     LDA #$5F                        A:E8 X:00 Y:00 P:EC SP:FB PPU:  4, 97 CYC:487",
+	"C8ED  49 AA     EOR #$AA                        A:5F X:00 Y:00 P:6C SP:FB PPU:  4,103 CYC:489",
+	"C8EF  B0 0B     BCS $C8FC                       A:F5 X:00 Y:00 P:EC SP:FB PPU:  4,109 CYC:491",
+	"C8F1  10 09     BPL $C8FC                       A:F5 X:00 Y:00 P:EC SP:FB PPU:  4,115 CYC:493",
+	"C8F3  C9 F5     CMP #$F5                        A:F5 X:00 Y:00 P:EC SP:FB PPU:  4,121 CYC:495",
+	"C8F5  D0 05     BNE $C8FC                       A:F5 X:00 Y:00 P:6F SP:FB PPU:  4,127 CYC:497",
+	"C8F7  50 03     BVC $C8FC                       A:F5 X:00 Y:00 P:6F SP:FB PPU:  4,133 CYC:499",
+	"C8F9  4C 00 C9  JMP $C900                       A:F5 X:00 Y:00 P:6F SP:FB PPU:  4,139 CYC:501",
+	"C900  EA        NOP                             A:F5 X:00 Y:00 P:6F SP:FB PPU:  4,148 CYC:504",
+	"C901  38        SEC                             A:F5 X:00 Y:00 P:6F SP:FB PPU:  4,154 CYC:506",
+	"C902  B8        CLV                             A:F5 X:00 Y:00 P:6F SP:FB PPU:  4,160 CYC:508",
+	"C903  A9 70     LDA #$70                        A:F5 X:00 Y:00 P:2F SP:FB PPU:  4,166 CYC:510",
+	"C905  49 70     EOR #$70                        A:70 X:00 Y:00 P:2D SP:FB PPU:  4,172 CYC:512",
+	"C907  D0 09     BNE $C912                       A:00 X:00 Y:00 P:2F SP:FB PPU:  4,178 CYC:514",
+	"C909  70 07     BVS $C912                       A:00 X:00 Y:00 P:2F SP:FB PPU:  4,184 CYC:516",
+	"C90B  90 05     BCC $C912                       A:00 X:00 Y:00 P:2F SP:FB PPU:  4,190 CYC:518",
+	"C90D  30 03     BMI $C912                       A:00 X:00 Y:00 P:2F SP:FB PPU:  4,196 CYC:520",
+	"C90F  4C 16 C9  JMP $C916                       A:00 X:00 Y:00 P:2F SP:FB PPU:  4,202 CYC:522",
+	"C916  EA        NOP                             A:00 X:00 Y:00 P:2F SP:FB PPU:  4,211 CYC:525",
+	"C917  18        CLC                             A:00 X:00 Y:00 P:2F SP:FB PPU:  4,217 CYC:527",
+	"C918  24 01     BIT $01 = FF                    A:00 X:00 Y:00 P:2E SP:FB PPU:  4,223 CYC:529",
+	"C91A  A9 00     LDA #$00                        A:00 X:00 Y:00 P:EE SP:FB PPU:  4,232 CYC:532",
+	"C91C  69 69     ADC #$69                        A:00 X:00 Y:00 P:6E SP:FB PPU:  4,238 CYC:534",
+	"C91E  30 0B     BMI $C92B                       A:69 X:00 Y:00 P:2C SP:FB PPU:  4,244 CYC:536",
+	"C920  B0 09     BCS $C92B                       A:69 X:00 Y:00 P:2C SP:FB PPU:  4,250 CYC:538",
+	"C922  C9 69     CMP #$69                        A:69 X:00 Y:00 P:2C SP:FB PPU:  4,256 CYC:540",
+	"C924  D0 05     BNE $C92B                       A:69 X:00 Y:00 P:2F SP:FB PPU:  4,262 CYC:542",
+	"C926  70 03     BVS $C92B                       A:69 X:00 Y:00 P:2F SP:FB PPU:  4,268 CYC:544",
+	"C928  4C 2F C9  JMP $C92F                       A:69 X:00 Y:00 P:2F SP:FB PPU:  4,274 CYC:546",
+	"C92F  EA        NOP                             A:69 X:00 Y:00 P:2F SP:FB PPU:  4,283 CYC:549",
+	"C930  38        SEC                             A:69 X:00 Y:00 P:2F SP:FB PPU:  4,289 CYC:551",
+	"C931  F8        SED                             A:69 X:00 Y:00 P:2F SP:FB PPU:  4,295 CYC:553",
+	"C932  24 01     BIT $01 = FF                    A:69 X:00 Y:00 P:2F SP:FB PPU:  4,301 CYC:555",
+	"C934  A9 01     LDA #$01                        A:69 X:00 Y:00 P:ED SP:FB PPU:  4,310 CYC:558",
+	"C936  69 69     ADC #$69                        A:01 X:00 Y:00 P:6D SP:FB PPU:  4,316 CYC:560",
+	"C938  30 0B     BMI $C945                       A:6B X:00 Y:00 P:2C SP:FB PPU:  4,322 CYC:562",
+	"C93A  B0 09     BCS $C945                       A:6B X:00 Y:00 P:2C SP:FB PPU:  4,328 CYC:564",
+	"C93C  C9 6B     CMP #$6B                        A:6B X:00 Y:00 P:2C SP:FB PPU:  4,334 CYC:566",
+	"C93E  D0 05     BNE $C945                       A:6B X:00 Y:00 P:2F SP:FB PPU:  4,340 CYC:568",
+	"C940  70 03     BVS $C945                       A:6B X:00 Y:00 P:2F SP:FB PPU:  5,  5 CYC:570",
+	"C942  4C 49 C9  JMP $C949                       A:6B X:00 Y:00 P:2F SP:FB PPU:  5, 11 CYC:572",
+	"C949  EA        NOP                             A:6B X:00 Y:00 P:2F SP:FB PPU:  5, 20 CYC:575",
+	"C94A  D8        CLD                             A:6B X:00 Y:00 P:2F SP:FB PPU:  5, 26 CYC:577",
+	"C94B  38        SEC                             A:6B X:00 Y:00 P:27 SP:FB PPU:  5, 32 CYC:579",
+	"C94C  B8        CLV                             A:6B X:00 Y:00 P:27 SP:FB PPU:  5, 38 CYC:581",
+	"C94D  A9 7F     LDA #$7F                        A:6B X:00 Y:00 P:27 SP:FB PPU:  5, 44 CYC:583",
+	"C94F  69 7F     ADC #$7F                        A:7F X:00 Y:00 P:25 SP:FB PPU:  5, 50 CYC:585",
+	"C951  10 0B     BPL $C95E                       A:FF X:00 Y:00 P:E4 SP:FB PPU:  5, 56 CYC:587",
+	"C953  B0 09     BCS $C95E                       A:FF X:00 Y:00 P:E4 SP:FB PPU:  5, 62 CYC:589",
+	"C955  C9 FF     CMP #$FF                        A:FF X:00 Y:00 P:E4 SP:FB PPU:  5, 68 CYC:591",
+	"C957  D0 05     BNE $C95E                       A:FF X:00 Y:00 P:67 SP:FB PPU:  5, 74 CYC:593",
+	"C959  50 03     BVC $C95E                       A:FF X:00 Y:00 P:67 SP:FB PPU:  5, 80 CYC:595",
+	"C95B  4C 62 C9  JMP $C962                       A:FF X:00 Y:00 P:67 SP:FB PPU:  5, 86 CYC:597",
+	"C962  EA        NOP                             A:FF X:00 Y:00 P:67 SP:FB PPU:  5, 95 CYC:600",
+	"C963  18        CLC                             A:FF X:00 Y:00 P:67 SP:FB PPU:  5,101 CYC:602",
+	"C964  24 01     BIT $01 = FF                    A:FF X:00 Y:00 P:66 SP:FB PPU:  5,107 CYC:604",
+	"C966  A9 7F     LDA #$7F                        A:FF X:00 Y:00 P:E4 SP:FB PPU:  5,116 CYC:607",
+	"C968  69 80     ADC #$80                        A:7F X:00 Y:00 P:64 SP:FB PPU:  5,122 CYC:609",
+	"C96A  10 0B     BPL $C977                       A:FF X:00 Y:00 P:A4 SP:FB PPU:  5,128 CYC:611",
+	"C96C  B0 09     BCS $C977                       A:FF X:00 Y:00 P:A4 SP:FB PPU:  5,134 CYC:613",
+	"C96E  C9 FF     CMP #$FF                        A:FF X:00 Y:00 P:A4 SP:FB PPU:  5,140 CYC:615",
+	"C970  D0 05     BNE $C977                       A:FF X:00 Y:00 P:27 SP:FB PPU:  5,146 CYC:617",
+	"C972  70 03     BVS $C977                       A:FF X:00 Y:00 P:27 SP:FB PPU:  5,152 CYC:619",
+	"C974  4C 7B C9  JMP $C97B                       A:FF X:00 Y:00 P:27 SP:FB PPU:  5,158 CYC:621",
+	"C97B  EA        NOP                             A:FF X:00 Y:00 P:27 SP:FB PPU:  5,167 CYC:624",
+	"C97C  38        SEC                             A:FF X:00 Y:00 P:27 SP:FB PPU:  5,173 CYC:626",
+	"C97D  B8        CLV                             A:FF X:00 Y:00 P:27 SP:FB PPU:  5,179 CYC:628",
+	"C97E  A9 7F     LDA #$7F                        A:FF X:00 Y:00 P:27 SP:FB PPU:  5,185 CYC:630",
+	"C980  69 80     ADC #$80                        A:7F X:00 Y:00 P:25 SP:FB PPU:  5,191 CYC:632",
+	"C982  D0 09     BNE $C98D                       A:00 X:00 Y:00 P:27 SP:FB PPU:  5,197 CYC:634",
+	"C984  30 07     BMI $C98D                       A:00 X:00 Y:00 P:27 SP:FB PPU:  5,203 CYC:636",
+	"C986  70 05     BVS $C98D                       A:00 X:00 Y:00 P:27 SP:FB PPU:  5,209 CYC:638",
+	"C988  90 03     BCC $C98D                       A:00 X:00 Y:00 P:27 SP:FB PPU:  5,215 CYC:640",
+	"C98A  4C 91 C9  JMP $C991                       A:00 X:00 Y:00 P:27 SP:FB PPU:  5,221 CYC:642",
+	"C991  EA        NOP                             A:00 X:00 Y:00 P:27 SP:FB PPU:  5,230 CYC:645",
+	"C992  38        SEC                             A:00 X:00 Y:00 P:27 SP:FB PPU:  5,236 CYC:647",
+	"C993  B8        CLV                             A:00 X:00 Y:00 P:27 SP:FB PPU:  5,242 CYC:649",
+	"C994  A9 9F     LDA #$9F                        A:00 X:00 Y:00 P:27 SP:FB PPU:  5,248 CYC:651",
+	"C996  F0 09     BEQ $C9A1                       A:9F X:00 Y:00 P:A5 SP:FB PPU:  5,254 CYC:653",
+	"C998  10 07     BPL $C9A1                       A:9F X:00 Y:00 P:A5 SP:FB PPU:  5,260 CYC:655",
+	"C99A  70 05     BVS $C9A1                       A:9F X:00 Y:00 P:A5 SP:FB PPU:  5,266 CYC:657",
+	"C99C  90 03     BCC $C9A1                       A:9F X:00 Y:00 P:A5 SP:FB PPU:  5,272 CYC:659",
+	"C99E  4C A5 C9  JMP $C9A5                       A:9F X:00 Y:00 P:A5 SP:FB PPU:  5,278 CYC:661",
+	"C9A5  EA        NOP                             A:9F X:00 Y:00 P:A5 SP:FB PPU:  5,287 CYC:664",
+	"C9A6  18        CLC                             A:9F X:00 Y:00 P:A5 SP:FB PPU:  5,293 CYC:666",
+	"C9A7  24 01     BIT $01 = FF                    A:9F X:00 Y:00 P:A4 SP:FB PPU:  5,299 CYC:668",
+	"C9A9  A9 00     LDA #$00                        A:9F X:00 Y:00 P:E4 SP:FB PPU:  5,308 CYC:671",
+	"C9AB  D0 09     BNE $C9B6                       A:00 X:00 Y:00 P:66 SP:FB PPU:  5,314 CYC:673",
+	"C9AD  30 07     BMI $C9B6                       A:00 X:00 Y:00 P:66 SP:FB PPU:  5,320 CYC:675",
+	"C9AF  50 05     BVC $C9B6                       A:00 X:00 Y:00 P:66 SP:FB PPU:  5,326 CYC:677",
+	"C9B1  B0 03     BCS $C9B6                       A:00 X:00 Y:00 P:66 SP:FB PPU:  5,332 CYC:679",
+	"C9B3  4C BA C9  JMP $C9BA                       A:00 X:00 Y:00 P:66 SP:FB PPU:  5,338 CYC:681",
+	"C9BA  EA        NOP                             A:00 X:00 Y:00 P:66 SP:FB PPU:  6,  6 CYC:684",
+	"C9BB  24 01     BIT $01 = FF                    A:00 X:00 Y:00 P:66 SP:FB PPU:  6, 12 CYC:686",
+	"C9BD  A9 40     LDA #$40                        A:00 X:00 Y:00 P:E6 SP:FB PPU:  6, 21 CYC:689",
+	"C9BF  C9 40     CMP #$40                        A:40 X:00 Y:00 P:64 SP:FB PPU:  6, 27 CYC:691",
+	"C9C1  30 09     BMI $C9CC                       A:40 X:00 Y:00 P:67 SP:FB PPU:  6, 33 CYC:693",
+	"C9C3  90 07     BCC $C9CC                       A:40 X:00 Y:00 P:67 SP:FB PPU:  6, 39 CYC:695",
+	"C9C5  D0 05     BNE $C9CC                       A:40 X:00 Y:00 P:67 SP:FB PPU:  6, 45 CYC:697",
+	"C9C7  50 03     BVC $C9CC                       A:40 X:00 Y:00 P:67 SP:FB PPU:  6, 51 CYC:699",
+	"C9C9  4C D0 C9  JMP $C9D0                       A:40 X:00 Y:00 P:67 SP:FB PPU:  6, 57 CYC:701",
+	"C9D0  EA        NOP                             A:40 X:00 Y:00 P:67 SP:FB PPU:  6, 66 CYC:704",
+	"C9D1  B8        CLV                             A:40 X:00 Y:00 P:67 SP:FB PPU:  6, 72 CYC:706",
+	"C9D2  C9 3F     CMP #$3F                        A:40 X:00 Y:00 P:27 SP:FB PPU:  6, 78 CYC:708",
+	"C9D4  F0 09     BEQ $C9DF                       A:40 X:00 Y:00 P:25 SP:FB PPU:  6, 84 CYC:710",
+}
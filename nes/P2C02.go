@@ -1,9 +1,17 @@
-package main
+package nes
 
 import (
+	"image"
 	"image/color"
 )
 
+const (
+	// FrameWidth : pixels in a line of the picture
+	FrameWidth = 256
+	// FrameHeight : visible lines of the picture
+	FrameHeight = 240
+)
+
 const (
 	controlRegister = 0
 	maskRegister    = 1
@@ -39,6 +47,8 @@ const (
 )
 
 var (
+	// defaultColors : colors of the 2C02 palette, never changed, every PPU
+	// starts from a copy of its own, see DefaultPalette
 	defaultColors [64]*color.RGBA
 )
 
@@ -84,15 +94,15 @@ func CreateLoopyRegister() *LoopyRegister {
 
 // PPU2C02 : PPU
 type PPU2C02 struct {
-	bus           *Bus
-	cart          *Cartridge
-	nameTable     [2][1024]byte // VRAM
-	paletteTable  [32]byte
-	patternTable  [2][4096]byte // Pattern Memory
-	paletteScreen [64]*color.RGBA
-	// spriteScreen       *Sprite    // Sprite(256, 240)
-	// spriteNameTable    [2]*Sprite // Sprite(128, 128), Sprite(128, 128)
-	// spritePatternTable [2]*Sprite // Sprite(256, 240), Sprite(256, 240)
+	bus          *Bus
+	cart         *Cartridge
+	nameTable    [2][1024]byte // VRAM
+	paletteTable [32]byte
+	patternTable [2][4096]byte // Pattern Memory
+	palette      Palette
+	// screen : the picture, the color index in bits 0-5 and the emphasis bits
+	// of the mask register in bits 6-8, 512 colors in all
+	screen        [FrameWidth * FrameHeight]uint16
 	frameComplete bool
 	scanLine      int16
 	cycle         int16
@@ -124,6 +134,31 @@ type PPU2C02 struct {
 	bgShifterPatternHi Word
 	bgShifterAttribLo  Word
 	bgShifterAttribHi  Word
+
+	// oam : object attribute memory, 64 sprites of 4 bytes, y, tile,
+	// attributes and x
+	oam     [256]byte
+	oamAddr byte
+
+	// frameCount : frames finished since power on
+	frameCount int
+	// tvSystem : sets the scanlines of a frame and when the vertical blank starts
+	tvSystem TVSystem
+
+	// oddFrame : frame parity, odd frames are a dot shorter while rendering
+	oddFrame bool
+	// nmiLine : NMI output, high while in vertical blank with NMI enabled
+	nmiLine bool
+	// nmiDelay : dots left before a rising nmiLine reaches the CPU
+	nmiDelay byte
+	// suppressVBL : $2002 was read the dot before the vertical blank starts
+	suppressVBL bool
+
+	// openBus : the I/O latch between the CPU and the PPU, reading a write
+	// only register or the unused bits of one returns what is left in it
+	openBus byte
+	// openBusTimer : frames left before each bit of openBus fades to 0
+	openBusTimer [8]byte
 }
 
 func init() {
@@ -206,14 +241,8 @@ func CreatePPU() *PPU2C02 {
 		[2][1024]byte{}, // nameTable
 		[32]byte{},      //paletteTable
 		[2][4096]byte{}, //patternTable
-		defaultColors,
-		// CreateSprite(256, 240),
-		// [2]*Sprite{
-		// 	CreateSprite(256, 240),
-		// 	CreateSprite(256, 240)},
-		// [2]*Sprite{
-		// 	CreateSprite(128, 128),
-		// 	CreateSprite(128, 128)},
+		*DefaultPalette(),
+		[FrameWidth * FrameHeight]uint16{}, // screen
 		false,
 		0, 0, 0, 0, 0,
 		0, 0, 0, 0, 0,
@@ -224,7 +253,75 @@ func CreatePPU() *PPU2C02 {
 		0,
 
 		0, 0, 0, 0,
-		0, 0, 0, 0}
+		0, 0, 0, 0,
+
+		[256]byte{}, 0,
+		0, NTSC,
+
+		false, false, 0, false,
+
+		0, [8]byte{}}
+}
+
+// SetPalette : changes the RGB colors the picture is drawn with, the PPU
+// keeps a copy
+func (p *PPU2C02) SetPalette(palette *Palette) {
+	p.palette = *palette
+}
+
+// nmiDelayDots : dots between the NMI output rising and the CPU seeing it,
+// reading $2002 or disabling NMI in between cancels it
+const nmiDelayDots = 2
+
+// updateNMI : follows the NMI output after the vertical blank flag or the
+// NMI enable changed, the CPU only sees it rise
+func (p *PPU2C02) updateNMI() {
+	line := p.GetFlag(verticalBlank, statusRegister) && p.GetFlag(enableNMI, controlRegister)
+	if line && !p.nmiLine {
+		p.nmiDelay = nmiDelayDots
+	}
+	p.nmiLine = line
+}
+
+// openBusDecay : seconds a bit of the I/O latch holds without being driven
+const openBusDecay = 0.6
+
+// refreshOpenBus : drives the bits of the I/O latch in mask with data
+func (p *PPU2C02) refreshOpenBus(data, mask byte) {
+	p.openBus = p.openBus&^mask | data&mask
+	frames := byte(openBusDecay * p.tvSystem.timing().frameRate)
+	for bit := range p.openBusTimer {
+		if mask&(1<<uint(bit)) != 0 {
+			p.openBusTimer[bit] = frames
+		}
+	}
+}
+
+// decayOpenBus : once a frame, the bits of the I/O latch nothing drove for a
+// while fade to 0
+func (p *PPU2C02) decayOpenBus() {
+	for bit := range p.openBusTimer {
+		if p.openBusTimer[bit] > 0 {
+			p.openBusTimer[bit]--
+			if p.openBusTimer[bit] == 0 {
+				p.openBus &^= 1 << uint(bit)
+			}
+		}
+	}
+}
+
+// readOAM : $2004, the first 64 dots of a rendered line clear the secondary
+// OAM and reads see $FF, the unused bits of the attributes read as 0
+func (p *PPU2C02) readOAM() byte {
+	rendering := p.GetFlag(renderBackground, maskRegister) || p.GetFlag(renderSprites, maskRegister)
+	if rendering && p.scanLine >= -1 && p.scanLine < FrameHeight && p.cycle >= 1 && p.cycle <= 64 {
+		return 0xFF
+	}
+	data := p.oam[p.oamAddr]
+	if p.oamAddr&0x03 == 0x02 {
+		data &= 0xE3
+	}
+	return data
 }
 
 // InsertCartridge : sets the pointer to the cartridge in the PPU
@@ -255,10 +352,10 @@ func (p *PPU2C02) GetFlag(flag Flag, at Register) bool {
 		break
 	case addressRegister:
 		longRegister = p.addressRegister
-		return (longRegister >> Word(flag) & Word(0x0001)) == 0
+		return (longRegister >> Word(flag) & Word(0x0001)) != 0
 	}
 
-	return (shortRegister >> byte(flag) & byte(0x01)) == 0
+	return (shortRegister >> byte(flag) & byte(0x01)) != 0
 }
 
 // GetFlagByte : GetFlagByte
@@ -331,59 +428,62 @@ func (p *PPU2C02) Complete() bool {
 	return p.frameComplete
 }
 
-// // GetScreen : GetScreen
-// func (p *PPU2C02) GetScreen() *Sprite {
-// 	return p.spriteScreen
-// }
+// paletteIndex : color of a pixel drawn with one of the palettes, as an index
+// in palette. Transparent pixels show the backdrop color at $3F00
+func (p *PPU2C02) paletteIndex(palette, pixel byte) byte {
+	address := palette<<2 | pixel
+	if pixel == 0 {
+		address = 0
+	}
+	if p.GetFlag(grayScale, maskRegister) {
+		return p.paletteTable[address] & 0x30
+	}
+	return p.paletteTable[address] & 0x3F
+}
+
+// outputColor : the color the PPU outputs for a pixel, with grayscale and
+// the emphasis of the mask register applied
+func (p *PPU2C02) outputColor(palette, pixel byte) uint16 {
+	emphasis := p.maskRegister >> enhanceRed
+	if p.tvSystem != NTSC {
+		// the 2C07 of PAL consoles and Dendy clones has bit 5 on green and
+		// bit 6 on red
+		emphasis = emphasis&0x04 | emphasis&0x01<<1 | emphasis&0x02>>1
+	}
+	return uint16(emphasis)<<6 | uint16(p.paletteIndex(palette, pixel))
+}
+
+// DrawFrame : draws the last frame into img, which must be FrameWidth by
+// FrameHeight pixels
+func (p *PPU2C02) DrawFrame(img *image.RGBA) {
+	for y := 0; y < FrameHeight; y++ {
+		line := img.Pix[y*img.Stride:]
+		for x := 0; x < FrameWidth; x++ {
+			pixel := p.screen[y*FrameWidth+x]
+			c := p.palette[pixel>>6][pixel&0x3F]
+			line[4*x], line[4*x+1], line[4*x+2], line[4*x+3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+// Screen : the last frame as the PPU outputs it, FrameWidth by FrameHeight
+// colors with the emphasis bits of the mask register in bits 6-8
+func (p *PPU2C02) Screen() []uint16 {
+	return p.screen[:]
+}
 
-// // GetNameTable : GetNameTable
-// func (p *PPU2C02) GetNameTable(i int) *Sprite {
-// 	return p.spriteNameTable[i]
-// }
+// FrameCount : frames finished since power on
+func (p *PPU2C02) FrameCount() int {
+	return p.frameCount
+}
 
 // GetColorFromPaletteRAM : GetColorFromPaletteRAM
 func (p *PPU2C02) GetColorFromPaletteRAM(palette, pixelValue byte) *color.RGBA {
 	idx, _ := p.PPURead(0x3F00+Word(palette)<<2+Word(pixelValue), false)
 	idx &= 0x3F
-	c := p.paletteScreen[idx]
-	return c
+	return &p.palette[0][idx]
 }
 
-// GetPatternTable : GetPatternTable
-// func (p *PPU2C02) GetPatternTable(i, palette byte) *Sprite {
-// 	var tileY, tileX, row, col, offset int
-
-// 	for tileY = 0; tileY < 16; tileY++ {
-
-// 		for tileX = 0; tileX < 16; tileX++ {
-
-// 			offset = tileY*256 + tileX*16
-
-// 			for row = 0; row < 8; row++ {
-
-// 				pos := Word(i)*Word(0x1000) + Word(offset) + Word(row)
-// 				tileLSB, _ := p.PPURead(pos+0, true)
-// 				tileMSB, _ := p.PPURead(pos+8, true)
-
-// 				for col = 0; col < 8; col++ {
-
-// 					pixel := (tileMSB & 0x01) + (tileLSB & 0x01)
-// 					tileLSB = tileLSB >> 1
-// 					tileMSB = tileMSB >> 1
-
-// 					// p.spritePatternTable[i].SetPixel(
-// 					// 	tileX*8+(7-col),
-// 					// 	tileY*8+row,
-// 					// 	p.GetColorFromPaletteRAM(palette, pixel))
-
-// 				}
-// 			}
-// 		}
-// 	}
-
-// 	return p.spritePatternTable[i]
-// }
-
 // ConnectBus : connects the CPU to the Bus
 func (p *PPU2C02) ConnectBus(bus *Bus) {
 	p.bus = bus
@@ -394,33 +494,38 @@ func (p *PPU2C02) PPURead(address Word, readOnly bool) (byte, error) {
 	var data byte = 0
 	address &= 0x3FFF
 
-	if _, ok := p.cart.PPURead(address); ok {
-		// data = d
+	if d, ok := p.cart.PPURead(address); ok {
+		data = d
 	} else if address >= 0x0000 && address <= 0x1FFF {
 		data = p.patternTable[(address&0x1000)>>12][address&0x0FFF]
 	} else if address >= 0x2000 && address <= 0x3EFF {
 
 		address &= 0x0FFF
 
-		if p.cart.Mirror == Vertical {
+		mirror := p.cart.mirroring()
+		if mirror == OnescreenLo {
+			data = p.nameTable[0][address&0x03FF]
+		} else if mirror == OnescreenHi {
+			data = p.nameTable[1][address&0x03FF]
+		} else if mirror == Vertical {
 			if address >= 0x0000 && address <= 0x03FF {
-				p.nameTable[0][address&0x03FF] = data
+				data = p.nameTable[0][address&0x03FF]
 			} else if address >= 0x0400 && address <= 0x07FF {
-				p.nameTable[1][address&0x03FF] = data
+				data = p.nameTable[1][address&0x03FF]
 			} else if address >= 0x0800 && address <= 0x0BFF {
-				p.nameTable[0][address&0x03FF] = data
+				data = p.nameTable[0][address&0x03FF]
 			} else if address >= 0x0C00 && address <= 0x0FFF {
-				p.nameTable[1][address&0x03FF] = data
+				data = p.nameTable[1][address&0x03FF]
 			}
-		} else if p.cart.Mirror == Horizontal {
+		} else if mirror == Horizontal {
 			if address >= 0x0000 && address <= 0x03FF {
-				p.nameTable[0][address&0x03FF] = data
+				data = p.nameTable[0][address&0x03FF]
 			} else if address >= 0x0400 && address <= 0x07FF {
-				p.nameTable[0][address&0x03FF] = data
+				data = p.nameTable[0][address&0x03FF]
 			} else if address >= 0x0800 && address <= 0x0BFF {
-				p.nameTable[1][address&0x03FF] = data
+				data = p.nameTable[1][address&0x03FF]
 			} else if address >= 0x0C00 && address <= 0x0FFF {
-				p.nameTable[1][address&0x03FF] = data
+				data = p.nameTable[1][address&0x03FF]
 			}
 		}
 	} else if address >= 0x3F00 && address <= 0x3FFF {
@@ -457,25 +562,30 @@ func (p *PPU2C02) PPUWrite(address Word, data byte) error {
 
 		address &= 0x0FFF
 
-		if p.cart.Mirror == Vertical {
+		mirror := p.cart.mirroring()
+		if mirror == OnescreenLo {
+			p.nameTable[0][address&0x03FF] = data
+		} else if mirror == OnescreenHi {
+			p.nameTable[1][address&0x03FF] = data
+		} else if mirror == Vertical {
 			if address >= 0x0000 && address <= 0x03FF {
-				data = p.nameTable[0][address&0x03FF]
+				p.nameTable[0][address&0x03FF] = data
 			} else if address >= 0x0400 && address <= 0x07FF {
-				data = p.nameTable[1][address&0x03FF]
+				p.nameTable[1][address&0x03FF] = data
 			} else if address >= 0x0800 && address <= 0x0BFF {
-				data = p.nameTable[0][address&0x03FF]
+				p.nameTable[0][address&0x03FF] = data
 			} else if address >= 0x0C00 && address <= 0x0FFF {
-				data = p.nameTable[1][address&0x03FF]
+				p.nameTable[1][address&0x03FF] = data
 			}
-		} else if p.cart.Mirror == Horizontal {
+		} else if mirror == Horizontal {
 			if address >= 0x0000 && address <= 0x03FF {
-				data = p.nameTable[0][address&0x03FF]
+				p.nameTable[0][address&0x03FF] = data
 			} else if address >= 0x0400 && address <= 0x07FF {
-				data = p.nameTable[0][address&0x03FF]
+				p.nameTable[0][address&0x03FF] = data
 			} else if address >= 0x0800 && address <= 0x0BFF {
-				data = p.nameTable[1][address&0x03FF]
+				p.nameTable[1][address&0x03FF] = data
 			} else if address >= 0x0C00 && address <= 0x0FFF {
-				data = p.nameTable[1][address&0x03FF]
+				p.nameTable[1][address&0x03FF] = data
 			}
 		}
 	} else if address >= 0x3F00 && address <= 0x3FFF {
@@ -511,6 +621,7 @@ func (p *PPU2C02) CPURead(address Word, readOnly bool) (byte, error) {
 		case oamAddress:
 			break
 		case oamData:
+			data = p.oam[p.oamAddr]
 			break
 		case scrollRegister:
 			break
@@ -520,29 +631,47 @@ func (p *PPU2C02) CPURead(address Word, readOnly bool) (byte, error) {
 			break
 		}
 	} else {
+		// write only registers return the I/O latch
+		data = p.openBus
 		switch address {
 		case controlRegister:
 			break
 		case maskRegister:
 			break
 		case statusRegister:
-			data = (p.statusRegister & 0xE0) | (p.ppuDataBuffer & 0x1F)
+			data = (p.statusRegister & 0xE0) | (p.openBus & 0x1F)
+			p.refreshOpenBus(data, 0xE0)
+			if p.scanLine == p.tvSystem.timing().vblankLine && p.cycle == 1 {
+				// a dot early, the flag reads clear and stays clear this frame
+				p.suppressVBL = true
+			}
 			p.ClearFlag(verticalBlank, statusRegister)
+			p.updateNMI()
 			p.addressLatch = 0
 			break
 		case oamAddress:
 			break
 		case oamData:
+			data = p.readOAM()
+			p.refreshOpenBus(data, 0xFF)
 			break
 		case scrollRegister:
 			break
 		case addressRegister:
 			break
 		case dataRegister:
-			data = p.ppuDataBuffer
-			p.ppuDataBuffer, _ = p.PPURead(p.vRAM.getAddress(), false)
-			if p.vRAM.getAddress() >= 0x3F00 {
+			address := p.vRAM.getAddress() & 0x3FFF
+			if address >= 0x3F00 {
+				// palette reads are direct, the top 2 bits are open bus and
+				// the buffer gets the nametable byte under the palette
+				palette, _ := p.PPURead(address, false)
+				data = p.openBus&0xC0 | palette
+				p.ppuDataBuffer, _ = p.PPURead(address-0x1000, false)
+				p.refreshOpenBus(data, 0x3F)
+			} else {
 				data = p.ppuDataBuffer
+				p.ppuDataBuffer, _ = p.PPURead(address, false)
+				p.refreshOpenBus(data, 0xFF)
 			}
 			if p.GetFlag(incrementMode, controlRegister) {
 				p.vRAM.add(32)
@@ -558,10 +687,12 @@ func (p *PPU2C02) CPURead(address Word, readOnly bool) (byte, error) {
 
 // CPUWrite : write data to PPU
 func (p *PPU2C02) CPUWrite(address Word, data byte) error {
+	p.refreshOpenBus(data, 0xFF)
 
 	switch address {
 	case controlRegister:
 		p.controlRegister = data
+		p.updateNMI()
 		p.tRAM.nametableX = p.GetFlagByte(nametableX, controlRegister)
 		p.tRAM.nametableY = p.GetFlagByte(nametableY, controlRegister)
 		break
@@ -571,8 +702,11 @@ func (p *PPU2C02) CPUWrite(address Word, data byte) error {
 	case statusRegister:
 		break
 	case oamAddress:
+		p.oamAddr = data
 		break
 	case oamData:
+		p.oam[p.oamAddr] = data
+		p.oamAddr++
 		break
 	case scrollRegister:
 		if p.addressLatch == 0 {
@@ -631,10 +765,21 @@ func (p *PPU2C02) Reset() {
 	p.controlRegister = 0x00
 	p.vRAM.set(0x0000)
 	p.tRAM.set(0x0000)
+	p.oddFrame = false
+	p.nmiLine = false
+	p.nmiDelay = 0
+	p.suppressVBL = false
+	p.NonMaskableInterrupt = false
 }
 
 // Clock : Bus clock implementation pulses the clock to all things attached to it
 func (p *PPU2C02) Clock() {
+	if p.nmiDelay > 0 {
+		p.nmiDelay--
+		if p.nmiDelay == 0 && p.nmiLine {
+			p.NonMaskableInterrupt = true
+		}
+	}
 
 	incrementScrollX := func(p *PPU2C02) {
 		if p.GetFlag(renderBackground, maskRegister) || p.GetFlag(renderSprites, maskRegister) {
@@ -711,13 +856,9 @@ func (p *PPU2C02) Clock() {
 
 	if p.scanLine >= -1 && p.scanLine < 240 {
 
-		if p.scanLine == 0 && p.cycle == 0 {
-			// Odd frame, cycle skip
-			p.cycle = 1
-		}
-
 		if p.scanLine == -1 && p.cycle == 1 {
 			p.ClearFlag(verticalBlank, statusRegister)
+			p.updateNMI()
 		}
 
 		if (p.cycle >= 2 && p.cycle < 258) || (p.cycle >= 321 && p.cycle < 338) {
@@ -725,7 +866,7 @@ func (p *PPU2C02) Clock() {
 			switch (p.cycle - 1) % 8 {
 			case 0:
 				loadBackgroundShifters(p)
-				p.bgNextTileID, _ = p.PPURead(Word(0x0200)|(p.vRAM.getAddress()&0x0FFF), false)
+				p.bgNextTileID, _ = p.PPURead(Word(0x2000)|(p.vRAM.getAddress()&0x0FFF), false)
 				break
 			case 2:
 				p.bgNextTileAttrib, _ = p.PPURead(
@@ -774,61 +915,72 @@ func (p *PPU2C02) Clock() {
 		// DO NOTHING - POST RENDER SCANLINE
 	}
 
-	if p.scanLine >= 241 && p.scanLine < 261 {
-		if p.scanLine == 241 && p.cycle == 1 {
-			// Effectively end of frame, so set vertical blank flag
-			p.SetFlag(verticalBlank, statusRegister)
+	if t := p.tvSystem.timing(); p.scanLine >= t.vblankLine && p.scanLine < t.preRenderLine {
+		if p.scanLine == t.vblankLine && p.cycle == 1 {
+			// Effectively end of frame, so set vertical blank flag,
+			// unless $2002 was read right before
+			if !p.suppressVBL {
+				p.SetFlag(verticalBlank, statusRegister)
+			}
+			p.suppressVBL = false
 
 			// If the control register tells us to emit a NMI when
 			// entering vertical blanking period, do it! The CPU
 			// will be informed that rendering is complete so it can
 			// perform operations with the PPU knowing it wont
 			// produce visible artefacts
-
-			if p.GetFlag(enableNMI, controlRegister) {
-				p.NonMaskableInterrupt = true
-			}
+			p.updateNMI()
 		}
 	}
 
-	// bgPixel := byte(0x00)
-	// bgPalette := byte(0x00)
-
-	// if p.GetFlag(renderBackground, maskRegister) {
-	// 	bitMux := Word(0x8000) >> p.fineX
-	// 	p0Pixel := byte(0)
-	// 	if p.bgShifterAttribLo&bitMux > 0 {
-	// 		p0Pixel = 1
-	// 	}
-	// 	p1Pixel := byte(0)
-	// 	if p.bgShifterAttribHi&bitMux > 0 {
-	// 		p1Pixel = 1
-	// 	}
-
-	// 	bgPixel = (p1Pixel << 1) | p0Pixel
-
-	// 	bgPal0 := byte(0)
-	// 	if p.bgShifterAttribLo&bitMux > 0 {
-	// 		bgPal0 = 1
-	// 	}
-	// 	bgPal1 := byte(0)
-	// 	if p.bgShifterAttribHi&bitMux > 0 {
-	// 		bgPal1 = 1
-	// 	}
-
-	// 	bgPalette = (bgPal1 << 1) | bgPal0
-	// }
+	bgPixel := byte(0x00)
+	bgPalette := byte(0x00)
+
+	if p.GetFlag(renderBackground, maskRegister) && (p.cycle > 8 || p.GetFlag(renderBackgroundLeft, maskRegister)) {
+		bitMux := Word(0x8000) >> p.fineX
+		p0Pixel := byte(0)
+		if p.bgShifterPatternLo&bitMux > 0 {
+			p0Pixel = 1
+		}
+		p1Pixel := byte(0)
+		if p.bgShifterPatternHi&bitMux > 0 {
+			p1Pixel = 1
+		}
+
+		bgPixel = (p1Pixel << 1) | p0Pixel
+
+		bgPal0 := byte(0)
+		if p.bgShifterAttribLo&bitMux > 0 {
+			bgPal0 = 1
+		}
+		bgPal1 := byte(0)
+		if p.bgShifterAttribHi&bitMux > 0 {
+			bgPal1 = 1
+		}
+
+		bgPalette = (bgPal1 << 1) | bgPal0
+	}
 
 	// Paint pixel
-	// p.GetScreen().SetPixel(int(p.cycle)-1, int(p.scanLine), p.GetColorFromPaletteRAM(bgPalette, bgPixel))
+	if p.scanLine >= 0 && p.scanLine < FrameHeight && p.cycle >= 1 && p.cycle <= FrameWidth {
+		p.screen[int(p.scanLine)*FrameWidth+int(p.cycle)-1] = p.outputColor(bgPalette, bgPixel)
+	}
 
 	p.cycle++
+	if p.scanLine == -1 && p.cycle == 340 && p.oddFrame && p.tvSystem.timing().oddFrameSkip &&
+		(p.GetFlag(renderBackground, maskRegister) || p.GetFlag(renderSprites, maskRegister)) {
+		// odd frames skip the last dot of the pre-render line while rendering
+		p.cycle = 341
+	}
 	if p.cycle >= 341 {
 		p.cycle = 0
 		p.scanLine++
-		if p.scanLine >= 261 {
+		if p.scanLine >= p.tvSystem.timing().preRenderLine {
 			p.scanLine = -1
 			p.frameComplete = true
+			p.frameCount++
+			p.oddFrame = !p.oddFrame
+			p.decayOpenBus()
 		}
 	}
 }
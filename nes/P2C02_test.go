@@ -0,0 +1,246 @@
+package nes
+
+import (
+	"testing"
+)
+
+// timingPPU : a PPU with a cartridge and nothing else clocking it
+func timingPPU() *PPU2C02 {
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+	return nes.bus.ppu
+}
+
+// runTo : clocks the PPU until the next dot it draws is cycle of line
+func runTo(p *PPU2C02, line, cycle int16) {
+	for p.scanLine != line || p.cycle != cycle {
+		p.Clock()
+	}
+}
+
+func TestOddFrameSkip(t *testing.T) {
+	frameLengths := func(tv TVSystem, mask byte) []int {
+		nes := CreateConsole()
+		nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+		nes.SetTVSystem(tv)
+		nes.bus.ppu.maskRegister = mask
+		nes.RunFrame()
+		var lengths []int
+		for i := 0; i < 4; i++ {
+			start := nes.bus.clockCount
+			nes.RunFrame()
+			lengths = append(lengths, nes.bus.clockCount-start)
+		}
+		return lengths
+	}
+
+	// rendering on, every other frame is a dot shorter
+	lengths := frameLengths(NTSC, 0x08)
+	assertTrue(t, lengths[0]+lengths[1] == 2*341*262-1)
+	assertTrue(t, lengths[0] == lengths[2] && lengths[1] == lengths[3])
+	lengths = frameLengths(NTSC, 0x10)
+	assertTrue(t, lengths[0]+lengths[1] == 2*341*262-1)
+	for _, l := range frameLengths(NTSC, 0x00) {
+		assertTrue(t, l == 341*262)
+	}
+	for _, l := range frameLengths(PAL, 0x18) {
+		assertTrue(t, l == 341*312)
+	}
+}
+
+func TestVBLReadRace(t *testing.T) {
+	for _, c := range []struct {
+		cycle int16
+		read  byte
+		flag  bool
+		nmi   bool
+	}{
+		{0, 0x00, true, true},   // well before, nothing special
+		{1, 0x00, false, false}, // a dot before, the flag never gets set
+		{2, 0x80, false, false}, // as it is set, it reads set and the NMI is lost
+		{3, 0x80, false, false}, // a dot after too
+		{4, 0x80, false, true},  // later the NMI is already on its way
+	} {
+		p := timingPPU()
+		p.CPUWrite(controlRegister, 0x80)
+		runTo(p, 241, c.cycle)
+		data, _ := p.CPURead(statusRegister, false)
+		runTo(p, 241, 20)
+		if data&0x80 != c.read || p.GetFlag(verticalBlank, statusRegister) != c.flag || p.NonMaskableInterrupt != c.nmi {
+			t.Errorf("Reading $2002 at dot %d: expected $%02X, flag %v and NMI %v, got: $%02X, %v and %v",
+				c.cycle, c.read, c.flag, c.nmi, data&0x80, p.GetFlag(verticalBlank, statusRegister), p.NonMaskableInterrupt)
+		}
+	}
+}
+
+func TestNMIEnableRace(t *testing.T) {
+	// enabling NMI during vertical blank raises it, every time
+	p := timingPPU()
+	runTo(p, 250, 0)
+	assertFalse(t, p.NonMaskableInterrupt)
+	p.CPUWrite(controlRegister, 0x80)
+	runTo(p, 250, 10)
+	assertTrue(t, p.NonMaskableInterrupt)
+	p.NonMaskableInterrupt = false
+	p.CPUWrite(controlRegister, 0x80)
+	runTo(p, 250, 20)
+	assertFalse(t, p.NonMaskableInterrupt)
+	p.CPUWrite(controlRegister, 0x00)
+	p.CPUWrite(controlRegister, 0x80)
+	runTo(p, 250, 30)
+	assertTrue(t, p.NonMaskableInterrupt)
+
+	// disabling it right as the vertical blank starts loses it
+	p = timingPPU()
+	p.CPUWrite(controlRegister, 0x80)
+	runTo(p, 241, 2)
+	p.CPUWrite(controlRegister, 0x00)
+	runTo(p, 241, 20)
+	assertFalse(t, p.NonMaskableInterrupt)
+	assertTrue(t, p.GetFlag(verticalBlank, statusRegister))
+
+	// no NMI once the vertical blank is over
+	p = timingPPU()
+	runTo(p, -1, 2)
+	p.CPUWrite(controlRegister, 0x80)
+	runTo(p, -1, 20)
+	assertFalse(t, p.NonMaskableInterrupt)
+}
+
+func TestNMIBetweenInstructions(t *testing.T) {
+	// the handler counts NMIs in $10, the main loop never stops
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge("A9 80 8D 00 20 4C 05 80 E6 10 40", 0x8000))
+	nes.bus.cart.PRGMemory[0x3FFA], nes.bus.cart.PRGMemory[0x3FFB] = 0x08, 0x80
+	for i := 0; i < 3; i++ {
+		nes.RunFrame()
+	}
+	assertEqualsB(t, 3, nes.RAM()[0x10])
+}
+
+func TestPPUOpenBus(t *testing.T) {
+	p := timingPPU()
+	read := func(register Word) byte {
+		data, _ := p.CPURead(register, false)
+		return data
+	}
+
+	// write only registers return the last value written to any register
+	p.CPUWrite(oamAddress, 0x5A)
+	for _, register := range []Word{controlRegister, maskRegister, oamAddress, scrollRegister, addressRegister} {
+		assertEqualsB(t, 0x5A, read(register))
+	}
+	// $2002 only drives the top 3 bits
+	p.statusRegister = 0xA0
+	assertEqualsB(t, 0xBA, read(statusRegister))
+	assertEqualsB(t, 0xBA, read(controlRegister))
+
+	// the bits fade after about 600ms, 36 frames, unless driven again
+	frames := func(n int) {
+		for i := 0; i < n; i++ {
+			p.Clock()
+			runTo(p, -1, 0)
+		}
+	}
+	p.CPUWrite(oamAddress, 0xFF)
+	frames(20)
+	p.statusRegister = 0x80
+	assertEqualsB(t, 0x9F, read(statusRegister))
+	frames(20)
+	assertEqualsB(t, 0x80, read(controlRegister))
+	frames(20)
+	assertEqualsB(t, 0x00, read(controlRegister))
+}
+
+func TestPPUDataReads(t *testing.T) {
+	p := timingPPU()
+	read := func(register Word) byte {
+		data, _ := p.CPURead(register, false)
+		return data
+	}
+	setAddress := func(address Word) {
+		p.CPUWrite(addressRegister, byte(address>>8))
+		p.CPUWrite(addressRegister, byte(address))
+	}
+	setAddress(0x2F00)
+	p.CPUWrite(dataRegister, 0x77)
+	setAddress(0x3F00)
+	p.CPUWrite(dataRegister, 0x21)
+
+	// palette reads skip the buffer, which gets the nametable underneath
+	setAddress(0x3F00)
+	p.CPUWrite(oamAddress, 0xC0)
+	assertEqualsB(t, 0xE1, read(dataRegister))
+	setAddress(0x2000)
+	assertEqualsB(t, 0x77, read(dataRegister))
+
+	// the unused attribute bits read as 0
+	p.CPUWrite(oamAddress, 0x02)
+	p.CPUWrite(oamData, 0xFF)
+	p.CPUWrite(oamAddress, 0x02)
+	assertEqualsB(t, 0xE3, read(oamData))
+
+	// while rendering the first 64 dots of a line read the cleared
+	// secondary OAM
+	p.CPUWrite(maskRegister, 0x08)
+	runTo(p, 10, 30)
+	assertEqualsB(t, 0xFF, read(oamData))
+	runTo(p, 10, 100)
+	assertEqualsB(t, 0xE3, read(oamData))
+}
+
+func TestPPUGetFlag(t *testing.T) {
+	p := CreatePPU()
+
+	// a set bit reads true, the others false
+	p.maskRegister = 1 << renderBackground
+	assertTrue(t, p.GetFlag(renderBackground, maskRegister))
+	assertFalse(t, p.GetFlag(renderSprites, maskRegister))
+	p.controlRegister = 1 << enableNMI
+	assertTrue(t, p.GetFlag(enableNMI, controlRegister))
+	assertFalse(t, p.GetFlag(incrementMode, controlRegister))
+	p.statusRegister = 1 << verticalBlank
+	assertTrue(t, p.GetFlag(verticalBlank, statusRegister))
+	assertFalse(t, p.GetFlag(spriteZeroHit, statusRegister))
+}
+
+func TestPPUNameTableMirroring(t *testing.T) {
+	p := timingPPU()
+	for _, c := range []struct {
+		mirror int
+		// nametable each of $2000, $2400, $2800 and $2C00 lands in
+		tables [4]int
+	}{
+		{Vertical, [4]int{0, 1, 0, 1}},
+		{Horizontal, [4]int{0, 0, 1, 1}},
+	} {
+		p.cart.Mirror = c.mirror
+		for i, table := range c.tables {
+			address := 0x2000 + Word(i)*0x0400 + 0x0123
+			value := byte(0x40 + 0x10*i + table)
+			assertNil(t, p.PPUWrite(address, value))
+			assertEqualsB(t, value, p.nameTable[table][0x0123])
+
+			// the same nametable reads back through every address mapped on
+			// it, $3000-$3EFF included
+			for j, other := range c.tables {
+				if other != table {
+					continue
+				}
+				for _, mirrored := range []Word{0x2000, 0x3000} {
+					data, err := p.PPURead(mirrored+Word(j)*0x0400+0x0123, false)
+					assertNil(t, err)
+					assertEqualsB(t, value, data)
+				}
+			}
+		}
+	}
+}
+
+func TestPPUReadsCartridge(t *testing.T) {
+	p := timingPPU()
+	p.cart.CHAMemory[0x1010] = 0x5A
+	data, err := p.PPURead(0x1010, false)
+	assertNil(t, err)
+	assertEqualsB(t, 0x5A, data)
+}
@@ -0,0 +1,295 @@
+package nes
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// paletteColors : colors the PPU can output for each emphasis
+	paletteColors = 64
+	// emphasisAttenuation : how much the 2C02 darkens the channels that are
+	// not emphasized, for palettes that only give the 64 base colors
+	emphasisAttenuation = 0.816
+)
+
+// Palette : RGB of the 64 PPU colors for each of the 8 combinations of the
+// emphasis bits of $2001, index 1 emphasizes red, 2 green and 4 blue
+type Palette [8][paletteColors]color.RGBA
+
+// DefaultPalette : the 2C02 palette the emulator starts with
+func DefaultPalette() *Palette {
+	var base [paletteColors]color.RGBA
+	for i, c := range defaultColors {
+		base[i] = *c
+	}
+	return emphasize(base, attenuate)
+}
+
+// rgbPPUColors : colors of the 2C03 and 2C05 RGB PPUs of the Vs. System and
+// PlayChoice-10, 3 bits per channel written as octal digits
+var rgbPPUColors = [paletteColors]uint16{
+	0333, 0014, 0006, 0326, 0403, 0503, 0510, 0420, 0320, 0120, 0031, 0040, 0022, 0000, 0000, 0000,
+	0555, 0036, 0027, 0407, 0507, 0704, 0700, 0630, 0430, 0140, 0040, 0053, 0044, 0000, 0000, 0000,
+	0777, 0357, 0447, 0637, 0707, 0737, 0740, 0750, 0660, 0360, 0070, 0276, 0077, 0000, 0000, 0000,
+	0777, 0567, 0657, 0757, 0747, 0755, 0764, 0772, 0773, 0572, 0473, 0276, 0467, 0000, 0000, 0000,
+}
+
+// RGBPalette : the palette of the 2C03 and 2C05 RGB PPUs used by Vs. System
+// games, emphasis turns a channel fully on instead of darkening the others
+func RGBPalette() *Palette {
+	var base [paletteColors]color.RGBA
+	for i, c := range rgbPPUColors {
+		level := func(shift uint) uint8 { return uint8((c >> shift & 0x07) * 255 / 7) }
+		base[i] = color.RGBA{level(6), level(3), level(0), 255}
+	}
+	return emphasize(base, func(c uint8, emphasized, anyBit bool) uint8 {
+		if emphasized {
+			return 255
+		}
+		return c
+	})
+}
+
+// PaletteFor : the palette a cartridge looks right with, the RGB palette for
+// Vs. System games and the 2C02 one for everything else
+func PaletteFor(cart *Cartridge) *Palette {
+	if cart != nil && cart.VsSystem() {
+		return RGBPalette()
+	}
+	return DefaultPalette()
+}
+
+// attenuate : a channel of a 2C02 color under emphasis
+func attenuate(c uint8, emphasized, anyBit bool) uint8 {
+	if anyBit && !emphasized {
+		return uint8(math.Round(float64(c) * emphasisAttenuation))
+	}
+	return c
+}
+
+// emphasize : builds the 7 emphasized copies of the base colors, channel
+// says what a channel becomes when its own bit or any bit is set
+func emphasize(base [paletteColors]color.RGBA, channel func(c uint8, emphasized, anyBit bool) uint8) *Palette {
+	var p Palette
+	for e := 0; e < 8; e++ {
+		for i, c := range base {
+			anyBit := e != 0
+			p[e][i] = color.RGBA{
+				channel(c.R, e&0x01 != 0, anyBit),
+				channel(c.G, e&0x02 != 0, anyBit),
+				channel(c.B, e&0x04 != 0, anyBit),
+				255,
+			}
+		}
+	}
+	return &p
+}
+
+// OpenPalette : loads a .pal file
+func OpenPalette(path string) (*Palette, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	p, err := ReadPalette(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// ReadPalette : reads a palette as RGB triplets, either the 64 base colors
+// (192 bytes), the emphasis copies being worked out like the 2C02 does, or
+// all 8 emphasis combinations one after the other (1536 bytes)
+func ReadPalette(r io.Reader) (*Palette, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, 8*paletteColors*3+1))
+	if err != nil {
+		return nil, err
+	}
+	rgb := func(i int) color.RGBA {
+		return color.RGBA{data[3*i], data[3*i+1], data[3*i+2], 255}
+	}
+
+	switch len(data) {
+	case paletteColors * 3:
+		var base [paletteColors]color.RGBA
+		for i := range base {
+			base[i] = rgb(i)
+		}
+		return emphasize(base, attenuate), nil
+	case 8 * paletteColors * 3:
+		var p Palette
+		for i := 0; i < 8*paletteColors; i++ {
+			p[i/paletteColors][i%paletteColors] = rgb(i)
+		}
+		return &p, nil
+	}
+	return nil, fmt.Errorf("a palette is %d or %d bytes, not %d", paletteColors*3, 8*paletteColors*3, len(data))
+}
+
+// Write : writes all 8 emphasis combinations as a 1536 bytes .pal file
+func (p *Palette) Write(w io.Writer) error {
+	data := make([]byte, 0, 8*paletteColors*3)
+	for _, colors := range p {
+		for _, c := range colors {
+			data = append(data, c.R, c.G, c.B)
+		}
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// NTSCParams : knobs of the TV decoding the NTSC signal of the PPU
+type NTSCParams struct {
+	// Hue : rotation of the colors in degrees
+	Hue float64
+	// Saturation : 1 is the signal as it is, 0 is black and white
+	Saturation float64
+	// Contrast : scales the whole signal
+	Contrast float64
+	// Brightness : added to the luma, 0 leaves it alone
+	Brightness float64
+	// Gamma : the colors are raised to 1/Gamma, 1 leaves them alone
+	Gamma float64
+}
+
+// DefaultNTSCParams : the decoder with every knob in the middle
+func DefaultNTSCParams() NTSCParams {
+	return NTSCParams{Hue: 0, Saturation: 1, Contrast: 1, Brightness: 0, Gamma: 1}
+}
+
+// ParseNTSCParams : reads decoder settings written as "hue=-5,saturation=1.2",
+// the ones left out keep their default
+func ParseNTSCParams(s string) (NTSCParams, error) {
+	params := DefaultNTSCParams()
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return params, fmt.Errorf("bad NTSC setting %q", field)
+		}
+		value, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return params, fmt.Errorf("bad value in %q", field)
+		}
+		switch strings.ToLower(parts[0]) {
+		case "hue":
+			params.Hue = value
+		case "saturation":
+			params.Saturation = value
+		case "contrast":
+			params.Contrast = value
+		case "brightness":
+			params.Brightness = value
+		case "gamma":
+			if value <= 0 {
+				return params, fmt.Errorf("gamma must be positive in %q", field)
+			}
+			params.Gamma = value
+		default:
+			return params, fmt.Errorf("unknown NTSC setting %q", field)
+		}
+	}
+	return params, nil
+}
+
+// levels of the 2C02 composite signal in volts, low then high for the four
+// luma rows, and the voltages of black and white
+var (
+	ntscLevels      = [8]float64{0.350, 0.518, 0.962, 1.550, 1.094, 1.506, 1.962, 1.962}
+	ntscBlack       = 0.518
+	ntscWhite       = 1.962
+	ntscAttenuation = 0.746
+)
+
+// inColorPhase : true during the half of the 12 color clock phases the
+// square wave of a hue is high
+func inColorPhase(hue, phase int) bool {
+	return (hue+phase)%12 < 6
+}
+
+// ntscSignal : level of the signal the 2C02 outputs for a color index with
+// the emphasis bits at one of the 12 phases of the color clock, 0 is black
+// and 1 is white
+func ntscSignal(index, emphasis, phase int) float64 {
+	hue, level := index&0x0F, index>>4&0x03
+	if hue >= 0x0E {
+		level = 1
+	}
+	low, high := ntscLevels[level], ntscLevels[4+level]
+	if hue == 0 {
+		low = high
+	} else if hue > 0x0C {
+		high = low
+	}
+
+	signal := low
+	if inColorPhase(hue, phase) {
+		signal = high
+	}
+	if (emphasis&0x01 != 0 && inColorPhase(0, phase)) ||
+		(emphasis&0x02 != 0 && inColorPhase(4, phase)) ||
+		(emphasis&0x04 != 0 && inColorPhase(8, phase)) {
+		signal *= ntscAttenuation
+	}
+	return (signal - ntscBlack) / (ntscWhite - ntscBlack)
+}
+
+// carrier : the color subcarrier at a phase in twelfths of the color clock,
+// to demodulate I and Q
+func (params NTSCParams) carrier(phase float64) (cos, sin float64) {
+	return math.Cos(math.Pi * (phase + 3.5 + params.Hue/30) / 6),
+		math.Sin(math.Pi * (phase + 3.5 + params.Hue/30) / 6)
+}
+
+// rgb : the color of the sums of Y, I and Q over a color clock, with the
+// knobs of the decoder applied
+func (params NTSCParams) rgb(y, i, q float64) color.RGBA {
+	y = y/12*params.Contrast + params.Brightness
+	i *= params.Saturation * params.Contrast / 6
+	q *= params.Saturation * params.Contrast / 6
+
+	channel := func(v float64) uint8 {
+		v = math.Max(0, math.Min(1, v))
+		return uint8(math.Round(255 * math.Pow(v, 1/params.Gamma)))
+	}
+	return color.RGBA{
+		channel(y + 0.946882*i + 0.623557*q),
+		channel(y - 0.274788*i - 0.635691*q),
+		channel(y - 1.108545*i + 1.709007*q),
+		255,
+	}
+}
+
+// GeneratePalette : works out the palette by decoding the signal the 2C02
+// outputs for every color and emphasis like an NTSC TV would
+func GeneratePalette(params NTSCParams) *Palette {
+	var p Palette
+	for e := 0; e < 8; e++ {
+		for index := 0; index < paletteColors; index++ {
+			// sample the 12 phases of the color clock and demodulate to YIQ
+			var y, i, q float64
+			for phase := 0; phase < 12; phase++ {
+				signal := ntscSignal(index, e, phase)
+				cos, sin := params.carrier(float64(phase))
+				y += signal
+				i += signal * cos
+				q += signal * sin
+			}
+			p[e][index] = params.rgb(y, i, q)
+		}
+	}
+	return &p
+}
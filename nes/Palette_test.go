@@ -0,0 +1,143 @@
+package nes
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+func TestReadPalette(t *testing.T) {
+	data := make([]byte, 192)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	p, err := ReadPalette(bytes.NewReader(data))
+	assertNil(t, err)
+	assertTrue(t, p[0][1] == color.RGBA{3, 4, 5, 255})
+	// red emphasis darkens green and blue
+	assertTrue(t, p[1][0x3F] == color.RGBA{189, 155, 156, 255})
+	assertTrue(t, p[7][0x3F] == p[0][0x3F])
+
+	var out bytes.Buffer
+	assertNil(t, p.Write(&out))
+	assertTrue(t, out.Len() == 1536)
+	full, err := ReadPalette(&out)
+	assertNil(t, err)
+	assertTrue(t, *full == *p)
+
+	for _, size := range []int{0, 191, 193, 1537} {
+		if _, err := ReadPalette(bytes.NewReader(make([]byte, size))); err == nil {
+			t.Errorf("Expected an error for a %d bytes palette", size)
+		}
+	}
+}
+
+func TestRGBPalette(t *testing.T) {
+	p := RGBPalette()
+	assertTrue(t, p[0][0x30] == color.RGBA{255, 255, 255, 255})
+	assertTrue(t, p[0][0x16] == color.RGBA{255, 0, 0, 255})
+	assertTrue(t, p[0][0x0F] == color.RGBA{0, 0, 0, 255})
+	// emphasis turns the channel on
+	assertTrue(t, p[4][0x0F] == color.RGBA{0, 0, 255, 255})
+
+	cart := TestCartridge("EA", 0x8000)
+	assertTrue(t, *PaletteFor(cart) == *DefaultPalette())
+	cart.header.mapper2 |= 0x01
+	assertTrue(t, *PaletteFor(cart) == *p)
+}
+
+func TestGeneratePalette(t *testing.T) {
+	p := GeneratePalette(DefaultNTSCParams())
+	assertTrue(t, p[0][0x0F] == color.RGBA{0, 0, 0, 255})
+	assertTrue(t, p[0][0x20] == color.RGBA{255, 255, 255, 255})
+
+	dominant := func(c color.RGBA) string {
+		switch {
+		case c.R > c.G && c.R > c.B:
+			return "red"
+		case c.G > c.R && c.G > c.B:
+			return "green"
+		case c.B > c.R && c.B > c.G:
+			return "blue"
+		}
+		return "gray"
+	}
+	for index, want := range map[byte]string{0x16: "red", 0x1A: "green", 0x12: "blue", 0x00: "gray"} {
+		if got := dominant(p[0][index]); got != want {
+			t.Errorf("Expected $%02X to be %s, got: %v", index, want, p[0][index])
+		}
+	}
+	// emphasis darkens the rest of the colors
+	assertTrue(t, p[1][0x2A].G < p[0][0x2A].G)
+
+	gray := GeneratePalette(NTSCParams{Saturation: 0, Contrast: 1, Gamma: 1})
+	c := gray[0][0x16]
+	assertTrue(t, c.R == c.G && c.G == c.B)
+	dark := GeneratePalette(NTSCParams{Saturation: 1, Contrast: 1, Brightness: -0.2, Gamma: 1})
+	assertTrue(t, dark[0][0x10].R < p[0][0x10].R)
+}
+
+func TestParseNTSCParams(t *testing.T) {
+	params, err := ParseNTSCParams("hue=-5, saturation=1.5,GAMMA=2.2")
+	assertNil(t, err)
+	assertTrue(t, params == NTSCParams{Hue: -5, Saturation: 1.5, Contrast: 1, Brightness: 0, Gamma: 2.2})
+
+	params, err = ParseNTSCParams("")
+	assertNil(t, err)
+	assertTrue(t, params == DefaultNTSCParams())
+
+	for _, bad := range []string{"hue", "tint=1", "hue=x", "gamma=0"} {
+		if _, err := ParseNTSCParams(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestConsolePalette(t *testing.T) {
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+	nes.RunFrame()
+	assertTrue(t, nes.Frame().RGBAAt(0, 0) == *defaultColors[0])
+
+	nes.SetPalette(RGBPalette())
+	assertTrue(t, nes.Frame().RGBAAt(0, 0) == color.RGBA{109, 109, 109, 255})
+	// the other consoles keep their colors
+	assertTrue(t, CreatePPU().palette == *DefaultPalette())
+}
+
+func TestEmphasisAndGrayscale(t *testing.T) {
+	for _, c := range []struct {
+		mask     byte
+		emphasis int
+		index    byte
+	}{
+		{0x00, 0, 0x2A},
+		{0x20, 1, 0x2A},
+		{0xC0, 6, 0x2A},
+		{0x01, 0, 0x20},
+		{0xE1, 7, 0x20},
+	} {
+		program := asm.MustAssemble(fmt.Sprintf(`
+			.org $8000
+			LDA #$3F
+			STA $2006
+			LDA #$00
+			STA $2006
+			LDA #$2A
+			STA $2007
+			LDA #$%02X
+			STA $2001
+	@loop:	JMP @loop`, c.mask))
+		nes := CreateConsole()
+		nes.InsertCartridge(TestCartridge(program.Hex(), 0x8000))
+		nes.RunFrame()
+		nes.RunFrame()
+		want := DefaultPalette()[c.emphasis][c.index]
+		if got := nes.Frame().RGBAAt(100, 100); got != want {
+			t.Errorf("Expected mask $%02X to show %v, got: %v", c.mask, want, got)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package nes
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// RewindInterval : frames between two snapshots
+	RewindInterval = 4
+	// RewindBudget : memory used by the snapshots, in bytes
+	RewindBudget = 16 * 1024 * 1024
+)
+
+// Rewind : keeps the recent history of the console so the game can be played
+// backwards.
+//
+// Only the newest snapshot is kept whole, every older one is stored as the
+// difference to the snapshot that came after it, with the unchanged bytes run
+// length encoded. Stepping back rebuilds the older snapshots one by one and
+// the oldest ones are dropped when the memory budget is exceeded.
+//
+// The controllers of the frames after each snapshot are kept with it, the
+// frames emulated again when stepping back get the input they had.
+type Rewind struct {
+	bus      *Bus
+	interval int
+	budget   int
+
+	// frame : frames captured so far, the current frame of the console
+	frame int
+
+	newest      []byte
+	newestFrame int
+	newestInput [][2]Buttons
+
+	// ring of deltas, oldest first
+	deltas []rewindDelta
+	start  int
+	count  int
+	size   int
+}
+
+// rewindDelta : turns a snapshot into the one captured before it
+type rewindDelta struct {
+	frame int
+	data  []byte
+	// input : the controllers of the frames after the snapshot
+	input [][2]Buttons
+}
+
+// rewindInputSize : bytes counted in the budget for the input of a frame
+const rewindInputSize = 2
+
+// CreateRewind : creates the rewind history of a console, capturing a
+// snapshot every interval frames while it fits in budget bytes
+func CreateRewind(bus *Bus, interval, budget int) *Rewind {
+	if interval < 1 {
+		interval = 1
+	}
+	return &Rewind{bus: bus, interval: interval, budget: budget}
+}
+
+// Capture : called once per emulated frame, takes a snapshot when it is due
+func (r *Rewind) Capture() error {
+	r.frame++
+	if r.newest != nil && r.frame-r.newestFrame < r.interval {
+		r.newestInput = append(r.newestInput, [2]Buttons{r.bus.controller[0].buttons, r.bus.controller[1].buttons})
+		r.size += rewindInputSize
+		return nil
+	}
+
+	var state bytes.Buffer
+	if err := r.bus.SaveState(&state); err != nil {
+		return err
+	}
+
+	if r.newest != nil {
+		r.push(rewindDelta{r.newestFrame, encodeDelta(state.Bytes(), r.newest), r.newestInput})
+	}
+	r.size += state.Len() - len(r.newest)
+	r.newest = state.Bytes()
+	r.newestFrame = r.frame
+	r.newestInput = nil
+
+	for r.size > r.budget && r.count > 0 {
+		r.dropOldest()
+	}
+	return nil
+}
+
+// StepBack : moves the console one frame back in time, false when the
+// history does not go that far
+func (r *Rewind) StepBack() (bool, error) {
+	target := r.frame - 1
+	if r.newest == nil || target < r.OldestFrame() {
+		return false, nil
+	}
+
+	for r.newestFrame > target {
+		r.popNewest()
+	}
+	if err := r.bus.LoadState(bytes.NewReader(r.newest)); err != nil {
+		return false, err
+	}
+	// snapshots are interval frames apart, the frames in between are emulated
+	// again from the snapshot before them, with the input they had
+	replayed := target - r.newestFrame
+	for _, input := range r.newestInput[:replayed] {
+		r.bus.SetController(0, input[0])
+		r.bus.SetController(1, input[1])
+		r.bus.ExecuteFrame()
+	}
+	r.size -= (len(r.newestInput) - replayed) * rewindInputSize
+	r.newestInput = r.newestInput[:replayed]
+	r.frame = target
+	return true, nil
+}
+
+// Frame : frames captured so far, stepping back goes down by one
+func (r *Rewind) Frame() int {
+	return r.frame
+}
+
+// OldestFrame : oldest frame the console can step back to
+func (r *Rewind) OldestFrame() int {
+	if r.count > 0 {
+		return r.deltas[r.start].frame
+	}
+	return r.newestFrame
+}
+
+// Len : snapshots held
+func (r *Rewind) Len() int {
+	if r.newest == nil {
+		return 0
+	}
+	return r.count + 1
+}
+
+// Size : memory used by the snapshots, in bytes
+func (r *Rewind) Size() int {
+	return r.size
+}
+
+// Clear : forgets the history, e.g. after a reset or loading a state
+func (r *Rewind) Clear() {
+	r.newest, r.newestInput = nil, nil
+	r.deltas, r.start, r.count, r.size = nil, 0, 0, 0
+}
+
+func (r *Rewind) push(d rewindDelta) {
+	if r.count == len(r.deltas) {
+		grown := make([]rewindDelta, 2*len(r.deltas)+16)
+		for i := 0; i < r.count; i++ {
+			grown[i] = r.deltas[(r.start+i)%len(r.deltas)]
+		}
+		r.deltas, r.start = grown, 0
+	}
+	r.deltas[(r.start+r.count)%len(r.deltas)] = d
+	r.count++
+	r.size += len(d.data)
+}
+
+func (r *Rewind) dropOldest() {
+	r.size -= len(r.deltas[r.start].data) + len(r.deltas[r.start].input)*rewindInputSize
+	r.deltas[r.start] = rewindDelta{}
+	r.start = (r.start + 1) % len(r.deltas)
+	r.count--
+}
+
+// popNewest : replaces the newest snapshot by the one captured before it
+func (r *Rewind) popNewest() {
+	r.size -= len(r.newest) + len(r.newestInput)*rewindInputSize
+	if r.count == 0 {
+		r.newest, r.newestInput = nil, nil
+		return
+	}
+	i := (r.start + r.count - 1) % len(r.deltas)
+	d := r.deltas[i]
+	r.deltas[i] = rewindDelta{}
+	r.count--
+	r.size -= len(d.data)
+
+	previous, err := decodeDelta(r.newest, d.data)
+	if err != nil {
+		// deltas are only made by encodeDelta, this is a bug
+		panic(err)
+	}
+	r.newest = previous
+	r.newestFrame = d.frame
+	// the input of the delta was already counted
+	r.newestInput = d.input
+	r.size += len(r.newest)
+}
+
+// encodeDelta : difference from base to target as runs of
+// [unchanged bytes][changed bytes][target XOR base of the changed bytes],
+// all sizes as uvarints, preceded by the size of target
+func encodeDelta(base, target []byte) []byte {
+	var out bytes.Buffer
+	number := make([]byte, binary.MaxVarintLen64)
+	write := func(v int) {
+		out.Write(number[:binary.PutUvarint(number, uint64(v))])
+	}
+	at := func(i int) byte {
+		v := target[i]
+		if i < len(base) {
+			v ^= base[i]
+		}
+		return v
+	}
+
+	write(len(target))
+	for i := 0; i < len(target); {
+		same := i
+		for same < len(target) && at(same) == 0 {
+			same++
+		}
+		changed := same
+		for changed < len(target) && at(changed) != 0 {
+			changed++
+		}
+		write(same - i)
+		write(changed - same)
+		for j := same; j < changed; j++ {
+			out.WriteByte(at(j))
+		}
+		i = changed
+	}
+	return out.Bytes()
+}
+
+// decodeDelta : rebuilds target from base and the encoded delta
+func decodeDelta(base, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	read := func() int {
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return -1
+		}
+		return int(v)
+	}
+
+	size := read()
+	if size < 0 {
+		return nil, errors.New("corrupted rewind delta")
+	}
+	target := make([]byte, size)
+	copy(target, base)
+	for i := 0; i < size; {
+		same, changed := read(), read()
+		if same < 0 || changed < 0 || i+same+changed > size {
+			return nil, errors.New("corrupted rewind delta")
+		}
+		i += same
+		for j := 0; j < changed; j++ {
+			v, _ := r.ReadByte()
+			if i < len(base) {
+				v ^= base[i]
+			}
+			target[i] = v
+			i++
+		}
+	}
+	return target, nil
+}
@@ -0,0 +1,164 @@
+package nes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// frameState : what identifies the state of the console after a frame
+func frameState(nes *Bus) string {
+	var state bytes.Buffer
+	state.WriteString(nes.TraceLine())
+	state.Write(nes.ram[:])
+	state.Write(nes.cart.PRGRam)
+	state.Write(nes.ppu.paletteTable[:])
+	return state.String()
+}
+
+func TestRewindStepBack(t *testing.T) {
+	nes := stateBus()
+	r := CreateRewind(nes, 3, RewindBudget)
+
+	var frames []string
+	for i := 0; i < 20; i++ {
+		nes.ExecuteFrame()
+		assertNil(t, r.Capture())
+		frames = append(frames, frameState(nes))
+	}
+	assertTrue(t, r.Frame() == 20)
+	assertTrue(t, r.Len() == 7)
+
+	// back to the first captured frame, one frame at a time
+	for frame := 19; frame >= 1; frame-- {
+		ok, err := r.StepBack()
+		assertNil(t, err)
+		assertTrue(t, ok)
+		assertTrue(t, r.Frame() == frame)
+		if frameState(nes) != frames[frame-1] {
+			t.Fatalf("Expected the state of frame %d", frame)
+		}
+	}
+	ok, err := r.StepBack()
+	assertNil(t, err)
+	assertFalse(t, ok)
+
+	// playing again after rewinding records the new history
+	nes.ExecuteFrame()
+	assertNil(t, r.Capture())
+	if frameState(nes) != frames[1] {
+		t.Errorf("Expected the state of frame 2")
+	}
+	ok, _ = r.StepBack()
+	assertTrue(t, ok)
+	if frameState(nes) != frames[0] {
+		t.Errorf("Expected the state of frame 1")
+	}
+}
+
+// padProgram : adds the A button of controller 1 to $10 every time it reads it
+var padProgram = asm.MustAssemble(`
+		.org $8000
+reset:  LDA #$01
+        STA $4016
+        LDA #$00
+        STA $4016
+        LDA $4016
+        AND #$01
+        CLC
+        ADC $10
+        STA $10
+        JMP reset
+`)
+
+func TestRewindReplaysInput(t *testing.T) {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(TestCartridge(padProgram.Hex(), 0x8000))
+	nes.Reset()
+	r := CreateRewind(nes, 4, RewindBudget)
+
+	// A is held on every third frame only, replaying a frame with the
+	// buttons of another one ends with another count
+	var frames []string
+	for i := 0; i < 12; i++ {
+		if i%3 == 0 {
+			nes.SetController(0, ButtonA)
+		} else {
+			nes.SetController(0, 0)
+		}
+		nes.ExecuteFrame()
+		assertNil(t, r.Capture())
+		frames = append(frames, frameState(nes))
+	}
+
+	for frame := 11; frame >= 1; frame-- {
+		ok, err := r.StepBack()
+		assertNil(t, err)
+		assertTrue(t, ok)
+		if frameState(nes) != frames[frame-1] {
+			t.Fatalf("Expected the state of frame %d, $10 is $%02X", frame, nes.ram[0x10])
+		}
+	}
+}
+
+func TestRewindBudget(t *testing.T) {
+	nes := stateBus()
+	r := CreateRewind(nes, 1, 1)
+	nes.ExecuteFrame()
+	assertNil(t, r.Capture())
+	full := r.Size()
+
+	// room for the newest snapshot and a few deltas
+	r = CreateRewind(nes, 1, full+full/2)
+	for i := 0; i < 100; i++ {
+		nes.ExecuteFrame()
+		assertNil(t, r.Capture())
+		if r.Size() > full+full/2 {
+			t.Fatalf("Expected at most %d bytes, got: %d", full+full/2, r.Size())
+		}
+	}
+	assertTrue(t, r.Len() > 2)
+	assertTrue(t, r.Len() < 100)
+	assertTrue(t, r.OldestFrame() == 100-r.Len()+1)
+
+	held, steps := r.Len(), 0
+	for {
+		ok, err := r.StepBack()
+		assertNil(t, err)
+		if !ok {
+			break
+		}
+		steps++
+	}
+	assertTrue(t, steps == held-1)
+	assertTrue(t, r.Frame() == r.OldestFrame())
+}
+
+func TestRewindDelta(t *testing.T) {
+	base := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	for _, target := range [][]byte{
+		{1, 2, 3, 4, 5, 6, 7, 8},
+		{1, 2, 9, 4, 5, 6, 7, 0},
+		{0, 0, 0},
+		{1, 2, 3, 4, 5, 6, 7, 8, 9, 0, 11},
+		{},
+	} {
+		delta := encodeDelta(base, target)
+		got, err := decodeDelta(base, delta)
+		assertNil(t, err)
+		if !bytes.Equal(got, target) {
+			t.Errorf("Expected: %v, got: %v", target, got)
+		}
+	}
+
+	large := make([]byte, 4096)
+	changed := append([]byte(nil), large...)
+	changed[100], changed[2000] = 1, 2
+	if n := len(encodeDelta(large, changed)); n > 16 {
+		t.Errorf("Expected a small delta, got %d bytes", n)
+	}
+
+	_, err := decodeDelta(base, []byte{8, 20, 0})
+	assertTrue(t, err != nil)
+}
@@ -0,0 +1,483 @@
+package nes
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Save state layout, all numbers are little endian:
+//
+//	"GNST" magic, uint16 version
+//	chunks: 4 byte id, uint32 size, size bytes of fields
+//	fields: uint8 name size, name, uint32 size, size bytes of data
+//
+// Every component writes its own chunk of named fields. Chunks and fields
+// that are not known are skipped and missing fields keep the value the
+// component already had, so states keep loading when fields are added.
+const (
+	stateMagic   = "GNST"
+	stateVersion = 1
+
+	chunkInfo    = "INFO"
+	chunkBus     = "BUS "
+	chunkCPU     = "CPU "
+	chunkPPU     = "PPU "
+	chunkCart    = "CART"
+	chunkMapper  = "MAPR"
+	stateIDSize  = 4
+	stateMaxSize = 16 * 1024 * 1024
+)
+
+var (
+	// ErrStateFormat : the data is not a save state
+	ErrStateFormat = errors.New("not a GoNES save state")
+	// ErrStateVersion : the state was written by a newer version of GoNES
+	ErrStateVersion = errors.New("unsupported save state version")
+	// ErrStateROM : the state belongs to another ROM
+	ErrStateROM = errors.New("save state was made with a different ROM")
+)
+
+// stateChunk : named fields of a single component
+type stateChunk struct {
+	id     string
+	names  []string
+	fields map[string][]byte
+}
+
+func newStateChunk(id string) *stateChunk {
+	return &stateChunk{id: id, fields: make(map[string][]byte)}
+}
+
+func (c *stateChunk) put(name string, data []byte) {
+	if _, ok := c.fields[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.fields[name] = append([]byte(nil), data...)
+}
+
+func (c *stateChunk) putByte(name string, v byte) {
+	c.put(name, []byte{v})
+}
+
+func (c *stateChunk) putBool(name string, v bool) {
+	if v {
+		c.putByte(name, 1)
+	} else {
+		c.putByte(name, 0)
+	}
+}
+
+func (c *stateChunk) putWord(name string, v Word) {
+	data := make([]byte, 2)
+	binary.LittleEndian.PutUint16(data, uint16(v))
+	c.put(name, data)
+}
+
+func (c *stateChunk) putInt(name string, v int) {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, uint64(int64(v)))
+	c.put(name, data)
+}
+
+// get : data of a field, missing fields are not an error
+func (c *stateChunk) get(name string, size int) ([]byte, bool, error) {
+	data, ok := c.fields[name]
+	if !ok {
+		return nil, false, nil
+	}
+	if size >= 0 && len(data) != size {
+		return nil, false, fmt.Errorf("save state field %s.%s has %d bytes, expected %d", c.id, name, len(data), size)
+	}
+	return data, true, nil
+}
+
+// stateLoader : reads fields into variables, keeping the first error so the
+// loading code does not have to check every field
+type stateLoader struct {
+	chunk *stateChunk
+	err   error
+}
+
+func (l *stateLoader) field(name string, size int) []byte {
+	if l.err != nil || l.chunk == nil {
+		return nil
+	}
+	data, ok, err := l.chunk.get(name, size)
+	if err != nil {
+		l.err = err
+	}
+	if !ok {
+		return nil
+	}
+	return data
+}
+
+func (l *stateLoader) bytes(name string, v []byte) {
+	if data := l.field(name, len(v)); data != nil {
+		copy(v, data)
+	}
+}
+
+func (l *stateLoader) byte(name string, v *byte) {
+	if data := l.field(name, 1); data != nil {
+		*v = data[0]
+	}
+}
+
+func (l *stateLoader) bool(name string, v *bool) {
+	if data := l.field(name, 1); data != nil {
+		*v = data[0] != 0
+	}
+}
+
+func (l *stateLoader) word(name string, v *Word) {
+	if data := l.field(name, 2); data != nil {
+		*v = Word(binary.LittleEndian.Uint16(data))
+	}
+}
+
+func (l *stateLoader) int(name string, v *int) {
+	if data := l.field(name, 8); data != nil {
+		*v = int(int64(binary.LittleEndian.Uint64(data)))
+	}
+}
+
+func (l *stateLoader) int16(name string, v *int16) {
+	if data := l.field(name, 8); data != nil {
+		*v = int16(int64(binary.LittleEndian.Uint64(data)))
+	}
+}
+
+// writeState : writes the chunks in the save state layout
+func writeState(w io.Writer, chunks []*stateChunk) error {
+	out := bufio.NewWriter(w)
+	out.WriteString(stateMagic)
+	binary.Write(out, binary.LittleEndian, uint16(stateVersion))
+
+	for _, c := range chunks {
+		var payload bytes.Buffer
+		for _, name := range c.names {
+			payload.WriteByte(byte(len(name)))
+			payload.WriteString(name)
+			binary.Write(&payload, binary.LittleEndian, uint32(len(c.fields[name])))
+			payload.Write(c.fields[name])
+		}
+		out.WriteString(c.id)
+		binary.Write(out, binary.LittleEndian, uint32(payload.Len()))
+		out.Write(payload.Bytes())
+	}
+	return out.Flush()
+}
+
+// readState : reads the chunks of a save state by id
+func readState(r io.Reader) (map[string]*stateChunk, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, stateMaxSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(stateMagic)+2 || string(data[:len(stateMagic)]) != stateMagic {
+		return nil, ErrStateFormat
+	}
+	if version := binary.LittleEndian.Uint16(data[len(stateMagic):]); version == 0 || version > stateVersion {
+		return nil, fmt.Errorf("%w %d", ErrStateVersion, version)
+	}
+	data = data[len(stateMagic)+2:]
+
+	next := func(n int) ([]byte, error) {
+		if n < 0 || n > len(data) {
+			return nil, fmt.Errorf("%w: truncated", ErrStateFormat)
+		}
+		v := data[:n]
+		data = data[n:]
+		return v, nil
+	}
+
+	chunks := make(map[string]*stateChunk)
+	for len(data) > 0 {
+		header, err := next(stateIDSize + 4)
+		if err != nil {
+			return nil, err
+		}
+		c := newStateChunk(string(header[:stateIDSize]))
+		payload, err := next(int(binary.LittleEndian.Uint32(header[stateIDSize:])))
+		if err != nil {
+			return nil, err
+		}
+
+		for len(payload) > 0 {
+			n := int(payload[0])
+			if 1+n+4 > len(payload) {
+				return nil, fmt.Errorf("%w: truncated chunk %s", ErrStateFormat, c.id)
+			}
+			name := string(payload[1 : 1+n])
+			size := int(binary.LittleEndian.Uint32(payload[1+n:]))
+			payload = payload[1+n+4:]
+			if size > len(payload) {
+				return nil, fmt.Errorf("%w: truncated field %s.%s", ErrStateFormat, c.id, name)
+			}
+			c.put(name, payload[:size])
+			payload = payload[size:]
+		}
+		chunks[c.id] = c
+	}
+	return chunks, nil
+}
+
+// SaveState : writes a snapshot of the whole console
+func (b *Bus) SaveState(w io.Writer) error {
+	if b.cart == nil {
+		return errors.New("cannot save state without a cartridge")
+	}
+
+	info := newStateChunk(chunkInfo)
+	info.put("emulator", []byte("GoNES"))
+	info.put("rom", b.cart.romHash[:])
+
+	bus := newStateChunk(chunkBus)
+	bus.put("ram", b.ram[:])
+	bus.putInt("clockCount", b.clockCount)
+	bus.putInt("operationCount", b.operationCount)
+	bus.putByte("dataBus", b.dataBus)
+	bus.putBool("controllerRead", b.controllerRead)
+	bus.putBool("lagged", b.lagged)
+	bus.putInt("lagCount", b.lagCount)
+	bus.putByte("tvSystem", byte(b.tvSystem))
+	bus.putByte("dmaPage", b.dmaPage)
+	bus.putByte("dmaAddr", b.dmaAddr)
+	bus.putByte("dmaData", b.dmaData)
+	bus.putBool("dmaDummy", b.dmaDummy)
+	bus.putBool("dmaTransfer", b.dmaTransfer)
+	for i, port := range b.controller {
+		bus.putByte(fmt.Sprintf("controller%dShifter", i+1), port.shifter)
+		bus.putBool(fmt.Sprintf("controller%dStrobe", i+1), port.strobe)
+	}
+
+	c := b.cpu
+	cpu := newStateChunk(chunkCPU)
+	cpu.putByte("a", c.a)
+	cpu.putByte("x", c.x)
+	cpu.putByte("y", c.y)
+	cpu.putByte("stkp", c.stkp)
+	cpu.putByte("status", c.status)
+	cpu.putByte("fetched", c.fetched)
+	cpu.putByte("opcode", c.opcode)
+	cpu.putByte("cycles", c.cycles)
+	cpu.putWord("pc", c.pc)
+	cpu.putWord("addressAbs", c.addressAbs)
+	cpu.putWord("addressRel", c.addressRel)
+	cpu.putInt("clockCount", c.clockCount)
+	cpu.putBool("jammed", c.jammed)
+
+	p := b.ppu
+	ppu := newStateChunk(chunkPPU)
+	ppu.put("nameTable", append(p.nameTable[0][:], p.nameTable[1][:]...))
+	ppu.put("paletteTable", p.paletteTable[:])
+	ppu.put("patternTable", append(p.patternTable[0][:], p.patternTable[1][:]...))
+	ppu.putBool("frameComplete", p.frameComplete)
+	ppu.putInt("frameCount", p.frameCount)
+	ppu.putBool("oddFrame", p.oddFrame)
+	ppu.putBool("nmiLine", p.nmiLine)
+	ppu.putByte("nmiDelay", p.nmiDelay)
+	ppu.putBool("suppressVBL", p.suppressVBL)
+	ppu.putByte("openBus", p.openBus)
+	ppu.put("openBusTimer", p.openBusTimer[:])
+	ppu.putInt("scanLine", int(p.scanLine))
+	ppu.putInt("cycle", int(p.cycle))
+	ppu.putByte("controlRegister", p.controlRegister)
+	ppu.putByte("maskRegister", p.maskRegister)
+	ppu.putByte("statusRegister", p.statusRegister)
+	ppu.putByte("scrollRegister", p.scrollRegister)
+	ppu.putWord("addressRegister", p.addressRegister)
+	ppu.putByte("dataRegister", p.dataRegister)
+	ppu.putByte("addressLatch", p.addressLatch)
+	ppu.putByte("ppuDataBuffer", p.ppuDataBuffer)
+	ppu.putBool("nmi", p.NonMaskableInterrupt)
+	ppu.putWord("vRAM", p.vRAM.getAddress())
+	ppu.putWord("tRAM", p.tRAM.getAddress())
+	ppu.putByte("fineX", p.fineX)
+	ppu.putByte("bgNextTileID", p.bgNextTileID)
+	ppu.putByte("bgNextTileAttrib", p.bgNextTileAttrib)
+	ppu.putByte("bgNextTileLsb", p.bgNextTileLsb)
+	ppu.putByte("bgNextTileMsb", p.bgNextTileMsb)
+	ppu.putWord("bgShifterPatternLo", p.bgShifterPatternLo)
+	ppu.putWord("bgShifterPatternHi", p.bgShifterPatternHi)
+	ppu.putWord("bgShifterAttribLo", p.bgShifterAttribLo)
+	ppu.putWord("bgShifterAttribHi", p.bgShifterAttribHi)
+	ppu.put("oam", p.oam[:])
+	ppu.putByte("oamAddr", p.oamAddr)
+
+	cart := newStateChunk(chunkCart)
+	cart.put("prgRam", b.cart.PRGRam)
+	if b.cart.CHABanks == 0 {
+		cart.put("chrRam", b.cart.CHAMemory)
+	}
+	cart.putInt("mirror", b.cart.Mirror)
+
+	mapper := newStateChunk(chunkMapper)
+	mapper.putByte("id", b.cart.mapperID)
+	b.cart.mapper.saveState(mapper)
+
+	return writeState(w, []*stateChunk{info, bus, cpu, ppu, cart, mapper})
+}
+
+// LoadState : restores a snapshot written by SaveState, the console is left
+// untouched when the state is invalid or belongs to another ROM
+func (b *Bus) LoadState(r io.Reader) error {
+	if b.cart == nil {
+		return errors.New("cannot load state without a cartridge")
+	}
+	chunks, err := readState(r)
+	if err != nil {
+		return err
+	}
+
+	info, ok := chunks[chunkInfo]
+	if !ok {
+		return fmt.Errorf("%w: missing %s chunk", ErrStateFormat, chunkInfo)
+	}
+	if hash, ok, _ := info.get("rom", sha1.Size); !ok || !bytes.Equal(hash, b.cart.romHash[:]) {
+		return ErrStateROM
+	}
+
+	// everything is loaded into copies first, so a bad field does not leave
+	// the console half restored
+	ram := b.ram
+	cpu := *b.cpu
+	ppu := *b.ppu
+	vRAM, tRAM := *b.ppu.vRAM, *b.ppu.tRAM
+	ppu.vRAM, ppu.tRAM = &vRAM, &tRAM
+	prgRAM := append([]byte(nil), b.cart.PRGRam...)
+	chrRAM := append([]byte(nil), b.cart.CHAMemory...)
+	mirror := b.cart.Mirror
+	mapper := b.cart.mapper.clone()
+	clockCount, operationCount := b.clockCount, b.operationCount
+	dataBus := b.dataBus
+	controllerRead, lagged, lagCount := b.controllerRead, b.lagged, b.lagCount
+	tvSystem := byte(b.tvSystem)
+	controller := b.controller
+	dmaPage, dmaAddr, dmaData, dmaDummy, dmaTransfer := b.dmaPage, b.dmaAddr, b.dmaData, b.dmaDummy, b.dmaTransfer
+
+	l := &stateLoader{chunk: chunks[chunkBus]}
+	l.bytes("ram", ram[:])
+	l.int("clockCount", &clockCount)
+	l.int("operationCount", &operationCount)
+	l.byte("dataBus", &dataBus)
+	l.bool("controllerRead", &controllerRead)
+	l.bool("lagged", &lagged)
+	l.int("lagCount", &lagCount)
+	l.byte("tvSystem", &tvSystem)
+	l.byte("dmaPage", &dmaPage)
+	l.byte("dmaAddr", &dmaAddr)
+	l.byte("dmaData", &dmaData)
+	l.bool("dmaDummy", &dmaDummy)
+	l.bool("dmaTransfer", &dmaTransfer)
+	for i := range controller {
+		l.byte(fmt.Sprintf("controller%dShifter", i+1), &controller[i].shifter)
+		l.bool(fmt.Sprintf("controller%dStrobe", i+1), &controller[i].strobe)
+	}
+
+	l.chunk = chunks[chunkCPU]
+	l.byte("a", &cpu.a)
+	l.byte("x", &cpu.x)
+	l.byte("y", &cpu.y)
+	l.byte("stkp", &cpu.stkp)
+	l.byte("status", &cpu.status)
+	l.byte("fetched", &cpu.fetched)
+	l.byte("opcode", &cpu.opcode)
+	l.byte("cycles", &cpu.cycles)
+	l.word("pc", &cpu.pc)
+	l.word("addressAbs", &cpu.addressAbs)
+	l.word("addressRel", &cpu.addressRel)
+	l.int("clockCount", &cpu.clockCount)
+	l.bool("jammed", &cpu.jammed)
+
+	l.chunk = chunks[chunkPPU]
+	nameTable := append(ppu.nameTable[0][:], ppu.nameTable[1][:]...)
+	l.bytes("nameTable", nameTable)
+	copy(ppu.nameTable[0][:], nameTable)
+	copy(ppu.nameTable[1][:], nameTable[len(ppu.nameTable[0]):])
+	l.bytes("paletteTable", ppu.paletteTable[:])
+	patternTable := append(ppu.patternTable[0][:], ppu.patternTable[1][:]...)
+	l.bytes("patternTable", patternTable)
+	copy(ppu.patternTable[0][:], patternTable)
+	copy(ppu.patternTable[1][:], patternTable[len(ppu.patternTable[0]):])
+	l.bool("frameComplete", &ppu.frameComplete)
+	l.int("frameCount", &ppu.frameCount)
+	l.bool("oddFrame", &ppu.oddFrame)
+	l.bool("nmiLine", &ppu.nmiLine)
+	l.byte("nmiDelay", &ppu.nmiDelay)
+	l.bool("suppressVBL", &ppu.suppressVBL)
+	l.byte("openBus", &ppu.openBus)
+	l.bytes("openBusTimer", ppu.openBusTimer[:])
+	l.int16("scanLine", &ppu.scanLine)
+	l.int16("cycle", &ppu.cycle)
+	l.byte("controlRegister", &ppu.controlRegister)
+	l.byte("maskRegister", &ppu.maskRegister)
+	l.byte("statusRegister", &ppu.statusRegister)
+	l.byte("scrollRegister", &ppu.scrollRegister)
+	l.word("addressRegister", &ppu.addressRegister)
+	l.byte("dataRegister", &ppu.dataRegister)
+	l.byte("addressLatch", &ppu.addressLatch)
+	l.byte("ppuDataBuffer", &ppu.ppuDataBuffer)
+	l.bool("nmi", &ppu.NonMaskableInterrupt)
+	address := vRAM.getAddress()
+	l.word("vRAM", &address)
+	vRAM.set(address)
+	address = tRAM.getAddress()
+	l.word("tRAM", &address)
+	tRAM.set(address)
+	l.byte("fineX", &ppu.fineX)
+	l.byte("bgNextTileID", &ppu.bgNextTileID)
+	l.byte("bgNextTileAttrib", &ppu.bgNextTileAttrib)
+	l.byte("bgNextTileLsb", &ppu.bgNextTileLsb)
+	l.byte("bgNextTileMsb", &ppu.bgNextTileMsb)
+	l.word("bgShifterPatternLo", &ppu.bgShifterPatternLo)
+	l.word("bgShifterPatternHi", &ppu.bgShifterPatternHi)
+	l.word("bgShifterAttribLo", &ppu.bgShifterAttribLo)
+	l.word("bgShifterAttribHi", &ppu.bgShifterAttribHi)
+	l.bytes("oam", ppu.oam[:])
+	l.byte("oamAddr", &ppu.oamAddr)
+
+	l.chunk = chunks[chunkCart]
+	l.bytes("prgRam", prgRAM)
+	if b.cart.CHABanks == 0 {
+		l.bytes("chrRam", chrRAM)
+	}
+	l.int("mirror", &mirror)
+
+	l.chunk = chunks[chunkMapper]
+	var mapperID byte
+	l.byte("id", &mapperID)
+	if l.chunk != nil && mapperID != b.cart.mapperID {
+		return fmt.Errorf("save state is for mapper %d, the cartridge uses mapper %d", mapperID, b.cart.mapperID)
+	}
+	mapper.loadState(l)
+
+	if l.err != nil {
+		return l.err
+	}
+
+	b.ram = ram
+	cpu.bus, ppu.bus = b, b
+	*b.cpu = cpu
+	*b.ppu = ppu
+	copy(b.cart.PRGRam, prgRAM)
+	copy(b.cart.CHAMemory, chrRAM)
+	b.cart.Mirror = mirror
+	b.cart.mapper = mapper
+	b.clockCount, b.operationCount = clockCount, operationCount
+	b.dataBus = dataBus
+	b.controllerRead, b.lagged, b.lagCount = controllerRead, lagged, lagCount
+	b.SetTVSystem(TVSystem(tvSystem))
+	b.dmaPage, b.dmaAddr, b.dmaData, b.dmaDummy, b.dmaTransfer = dmaPage, dmaAddr, dmaData, dmaDummy, dmaTransfer
+	for i := range controller {
+		b.controller[i].shifter, b.controller[i].strobe = controller[i].shifter, controller[i].strobe
+	}
+	return nil
+}
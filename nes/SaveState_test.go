@@ -0,0 +1,143 @@
+package nes
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// stateProgram : keeps the CPU, RAM, PRG RAM and the PPU busy so every chunk
+// of the state changes while it runs
+var stateProgram = asm.MustAssemble(`
+		.org $8000
+reset:  LDA #$3F
+        STA $2006
+        LDA #$00
+        STA $2006
+@loop:  INX
+        STX $0200
+        STX $6000
+        STX $2007
+        TXA
+        PHA
+        PLA
+        JMP @loop
+`)
+
+func stateBus() *Bus {
+	nes := CreateBus(CreateCPU(), CreatePPU())
+	nes.InsertCartridge(TestCartridge(stateProgram.Hex(), 0x8000))
+	nes.Reset()
+	return nes
+}
+
+func traceOperations(nes *Bus, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		nes.ExecuteOperation()
+		lines[i] = nes.TraceLine()
+	}
+	return lines
+}
+
+func TestSaveStateRoundTrip(t *testing.T) {
+	nes := stateBus()
+	traceOperations(nes, 1000)
+
+	var state bytes.Buffer
+	assertNil(t, nes.SaveState(&state))
+	saved := state.Bytes()
+	want := traceOperations(nes, 1000)
+	palette := nes.ppu.paletteTable
+
+	// a fresh console picks up exactly where the first one was
+	other := stateBus()
+	assertNil(t, other.LoadState(bytes.NewReader(saved)))
+	got := traceOperations(other, 1000)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected: %q, got: %q", want[i], got[i])
+		}
+	}
+	if other.ppu.paletteTable != palette || other.ram != nes.ram || !bytes.Equal(other.cart.PRGRam, nes.cart.PRGRam) {
+		t.Errorf("Expected the memory of both consoles to match")
+	}
+
+	// and the first one can go back in time
+	assertNil(t, nes.LoadState(bytes.NewReader(saved)))
+	got = traceOperations(nes, 1000)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected: %q, got: %q", want[i], got[i])
+		}
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	nes := stateBus()
+	var state bytes.Buffer
+	assertNil(t, nes.SaveState(&state))
+	saved := state.Bytes()
+
+	err := nes.LoadState(bytes.NewReader([]byte("PNG.....")))
+	assertTrue(t, errors.Is(err, ErrStateFormat))
+
+	newer := append([]byte(nil), saved...)
+	newer[4] = stateVersion + 1
+	assertTrue(t, errors.Is(nes.LoadState(bytes.NewReader(newer)), ErrStateVersion))
+
+	assertTrue(t, errors.Is(nes.LoadState(bytes.NewReader(saved[:len(saved)-3])), ErrStateFormat))
+
+	other := CreateBus(CreateCPU(), CreatePPU())
+	other.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+	assertTrue(t, errors.Is(other.LoadState(bytes.NewReader(saved)), ErrStateROM))
+
+	// a field with the wrong size leaves the console as it was
+	traceOperations(nes, 10)
+	before := nes.TraceLine()
+	cpu := newStateChunk(chunkCPU)
+	cpu.putWord("pc", 0x1234)
+	cpu.putWord("a", 0x0102)
+	assertTrue(t, nes.LoadState(bytes.NewReader(stateWith(t, saved, cpu))) != nil)
+	if nes.TraceLine() != before {
+		t.Errorf("Expected: %q, got: %q", before, nes.TraceLine())
+	}
+}
+
+func TestLoadStateCompatibility(t *testing.T) {
+	nes := stateBus()
+	var state bytes.Buffer
+	assertNil(t, nes.SaveState(&state))
+
+	// states from older versions miss fields, newer ones have unknown chunks
+	// and fields, neither stops the state from loading
+	cpu := newStateChunk(chunkCPU)
+	cpu.putWord("pc", 0x8004)
+	cpu.putByte("a", 0x42)
+	cpu.putByte("futureRegister", 0x01)
+	future := newStateChunk("APU ")
+	future.putByte("pulse1", 0x0F)
+
+	traceOperations(nes, 10)
+	x := nes.cpu.x
+	assertNil(t, nes.LoadState(bytes.NewReader(stateWith(t, state.Bytes(), cpu, future))))
+	assertEqualsW(t, 0x8004, nes.cpu.pc)
+	assertEqualsB(t, 0x42, nes.cpu.a)
+	assertEqualsB(t, x, nes.cpu.x)
+}
+
+// stateWith : a saved state with some of its chunks replaced or added
+func stateWith(t *testing.T, saved []byte, replace ...*stateChunk) []byte {
+	chunks, err := readState(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := []*stateChunk{chunks[chunkInfo], chunks[chunkBus], chunks[chunkPPU], chunks[chunkCart], chunks[chunkMapper]}
+	list = append(list, replace...)
+
+	var state bytes.Buffer
+	assertNil(t, writeState(&state, list))
+	return state.Bytes()
+}
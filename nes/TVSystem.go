@@ -0,0 +1,78 @@
+package nes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TVSystem : the region a console was built for, it sets how fast the CPU
+// runs against the PPU and how many scanlines a frame has
+type TVSystem byte
+
+const (
+	// NTSC : American and Japanese consoles, 262 lines at 60Hz
+	NTSC TVSystem = iota
+	// PAL : European consoles, 312 lines at 50Hz with a slower CPU
+	PAL
+	// Dendy : PAL famiclones, 312 lines at 50Hz with the NTSC CPU to
+	// PPU ratio and a late vertical blank
+	Dendy
+)
+
+// tvSystemTiming : the timing of the chips in a region
+type tvSystemTiming struct {
+	name string
+	// ppuClocks, cpuClocks : the PPU runs ppuClocks dots every cpuClocks
+	// CPU cycles
+	ppuClocks int
+	cpuClocks int
+	// preRenderLine : last scanline of the frame, the PPU counts it as -1
+	preRenderLine int16
+	// vblankLine : scanline the vertical blank starts on
+	vblankLine int16
+	// oddFrameSkip : odd frames are a dot shorter while rendering
+	oddFrameSkip bool
+	// cpuClockRate : CPU cycles per second
+	cpuClockRate int
+	// frameRate : frames per second
+	frameRate float64
+}
+
+var tvSystemTimings = [...]tvSystemTiming{
+	NTSC:  {"ntsc", 3, 1, 261, 241, true, 1789773, 60.0988},
+	PAL:   {"pal", 16, 5, 311, 241, false, 1662607, 50.0070},
+	Dendy: {"dendy", 3, 1, 311, 291, false, 1773448, 50.0070},
+}
+
+// timing : the timing of the region, unknown regions run as NTSC
+func (r TVSystem) timing() *tvSystemTiming {
+	if int(r) >= len(tvSystemTimings) {
+		return &tvSystemTimings[NTSC]
+	}
+	return &tvSystemTimings[r]
+}
+
+// String : the name of the region as read by ParseTVSystem
+func (r TVSystem) String() string {
+	return r.timing().name
+}
+
+// FrameRate : frames per second the region runs at
+func (r TVSystem) FrameRate() float64 {
+	return r.timing().frameRate
+}
+
+// CPUClockRate : CPU cycles per second in the region
+func (r TVSystem) CPUClockRate() int {
+	return r.timing().cpuClockRate
+}
+
+// ParseTVSystem : reads "ntsc", "pal" or "dendy"
+func ParseTVSystem(s string) (TVSystem, error) {
+	for r, t := range tvSystemTimings {
+		if strings.EqualFold(s, t.name) {
+			return TVSystem(r), nil
+		}
+	}
+	return NTSC, fmt.Errorf("unknown region %q", s)
+}
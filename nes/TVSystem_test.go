@@ -0,0 +1,137 @@
+package nes
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTVSystemTiming(t *testing.T) {
+	for _, c := range []struct {
+		tv         TVSystem
+		dots       []int
+		cpuCycles  []int
+		vblankLine int16
+	}{
+		{NTSC, []int{341*262 - 1, 341 * 262}, []int{29780, 29781}, 241},
+		{PAL, []int{341 * 312}, []int{33247, 33248}, 241},
+		{Dendy, []int{341 * 312}, []int{35464}, 291},
+	} {
+		nes := CreateConsole()
+		nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+		nes.SetTVSystem(c.tv)
+		assertTrue(t, nes.TVSystem() == c.tv)
+		nes.RunFrame()
+
+		clocks, cycles := nes.bus.clockCount, nes.bus.cpu.clockCount
+		for nes.bus.ppu.GetFlag(verticalBlank, statusRegister) {
+			nes.bus.Clock()
+		}
+		for !nes.bus.ppu.GetFlag(verticalBlank, statusRegister) {
+			nes.bus.Clock()
+		}
+		if nes.bus.ppu.scanLine != c.vblankLine {
+			t.Errorf("Expected the %s vertical blank on line %d, got: %d", c.tv, c.vblankLine, nes.bus.ppu.scanLine)
+		}
+		nes.RunFrame()
+
+		dots, cpuCycles := nes.bus.clockCount-clocks, nes.bus.cpu.clockCount-cycles
+		if !contains(c.dots, dots) || !contains(c.cpuCycles, cpuCycles) {
+			t.Errorf("Expected a %s frame of %v dots and %v CPU cycles, got: %d and %d", c.tv, c.dots, c.cpuCycles, dots, cpuCycles)
+		}
+	}
+}
+
+func TestTVSystemEmphasis(t *testing.T) {
+	for _, c := range []struct {
+		tv TVSystem
+		// emphasis output for $2001 bit 5, 6 and 7, 1 is red, 2 green, 4 blue
+		emphasis [3]uint16
+	}{
+		{NTSC, [3]uint16{1, 2, 4}},
+		{PAL, [3]uint16{2, 1, 4}},
+		{Dendy, [3]uint16{2, 1, 4}},
+	} {
+		nes := CreateConsole()
+		nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+		nes.SetTVSystem(c.tv)
+		for bit, want := range c.emphasis {
+			nes.bus.ppu.maskRegister = 1 << (enhanceRed + byte(bit))
+			if got := nes.bus.ppu.outputColor(0, 0) >> 6; got != want {
+				t.Errorf("Expected %s $2001 bit %d to give emphasis %d, got: %d", c.tv, enhanceRed+bit, want, got)
+			}
+		}
+	}
+}
+
+func contains(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTVSystemAudio(t *testing.T) {
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+	nes.SetTVSystem(PAL)
+	samples := make([]float32, 4096)
+	total := 0
+	for i := 0; i < 50; i++ {
+		nes.RunFrame()
+		total += nes.ReadAudio(samples)
+	}
+	// 50 PAL frames are a second
+	assertTrue(t, total > 44000 && total < 44200)
+}
+
+func TestCartridgeTVSystem(t *testing.T) {
+	for _, c := range []struct {
+		flags7, flags9, timing byte
+		want                   TVSystem
+	}{
+		{0x00, 0x00, 0x00, NTSC},
+		{0x00, 0x01, 0x00, PAL},
+		{0x08, 0x00, 0x01, PAL},
+		{0x08, 0x00, 0x02, NTSC},
+		{0x08, 0x00, 0x03, Dendy},
+		{0x08, 0x01, 0x00, NTSC},
+	} {
+		header := []byte{'N', 'E', 'S', 0x1A, 1, 1, 0, c.flags7, 0, c.flags9, 0, 0, c.timing, 0, 0, 0}
+		cart, err := ReadCartridge(bytes.NewReader(append(header, make([]byte, 16384+8192)...)))
+		assertNil(t, err)
+		if cart.TVSystem() != c.want {
+			t.Errorf("Expected flags 7 $%02X, 9 $%02X and timing $%02X to be %s, got: %s", c.flags7, c.flags9, c.timing, c.want, cart.TVSystem())
+		}
+
+		nes := CreateConsole()
+		nes.InsertCartridge(cart)
+		assertTrue(t, nes.TVSystem() == c.want)
+	}
+}
+
+func TestParseTVSystem(t *testing.T) {
+	for _, tv := range []TVSystem{NTSC, PAL, Dendy} {
+		parsed, err := ParseTVSystem(tv.String())
+		assertNil(t, err)
+		assertTrue(t, parsed == tv)
+	}
+	parsed, err := ParseTVSystem("PAL")
+	assertTrue(t, err == nil && parsed == PAL)
+	_, err = ParseTVSystem("secam")
+	assertTrue(t, err != nil)
+	assertTrue(t, PAL.FrameRate() < NTSC.FrameRate() && PAL.CPUClockRate() < NTSC.CPUClockRate())
+}
+
+func TestTVSystemSaveState(t *testing.T) {
+	nes := CreateConsole()
+	nes.InsertCartridge(TestCartridge("4C 00 80", 0x8000))
+	nes.SetTVSystem(Dendy)
+	var state bytes.Buffer
+	assertNil(t, nes.SaveState(&state))
+
+	nes.SetTVSystem(NTSC)
+	assertNil(t, nes.LoadState(&state))
+	assertTrue(t, nes.TVSystem() == Dendy && nes.bus.ppu.tvSystem == Dendy)
+}
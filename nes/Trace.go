@@ -0,0 +1,103 @@
+package nes
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// TraceLine : returns the state of the machine at the current instruction
+// boundary using the same layout as the nestest.log golden file
+// example: "C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD PPU:  0, 21 CYC:7"
+func (b *Bus) TraceLine() string {
+	c := b.cpu
+	d := c.Decode(c.pc)
+
+	var sBytes bytes.Buffer
+	sBytes.WriteString(Hex(uint32(d.Opcode), 2))
+	for _, operand := range d.Operands {
+		sBytes.WriteByte(' ')
+		sBytes.WriteString(Hex(uint32(operand), 2))
+	}
+
+	illegal := byte(' ')
+	if d.Illegal {
+		illegal = '*'
+	}
+
+	scanLine := b.ppu.scanLine
+	if scanLine < 0 {
+		scanLine = b.tvSystem.timing().preRenderLine
+	}
+
+	return fmt.Sprintf("%s  %-9s%c%-32sA:%s X:%s Y:%s P:%s SP:%s PPU:%3d,%3d CYC:%d",
+		Hex(uint32(d.Address), 4),
+		sBytes.String(),
+		illegal,
+		b.traceOperand(&d),
+		Hex(uint32(c.a), 2),
+		Hex(uint32(c.x), 2),
+		Hex(uint32(c.y), 2),
+		Hex(uint32(c.status), 2),
+		Hex(uint32(c.stkp), 2),
+		scanLine,
+		b.ppu.cycle,
+		c.clockCount)
+}
+
+// peek : reads the bus without side effects
+func (b *Bus) peek(address Word) byte {
+	data, _ := b.CPURead(address, true)
+	return data
+}
+
+// traceOperand : writes the decoded instruction in the nestest.log style,
+// including the effective address and the value stored there
+func (b *Bus) traceOperand(d *Decoded) string {
+	name := d.Mnemonic
+	value := Hex(uint32(b.peek(d.Target)), 2)
+
+	switch d.Mode {
+	case ModeACC:
+		return name + " A"
+	case ModeIMM:
+		return fmt.Sprintf("%s #$%s", name, Hex(uint32(d.Operand), 2))
+	case ModeZP0:
+		return fmt.Sprintf("%s $%s = %s", name, Hex(uint32(d.Operand), 2), value)
+	case ModeZPX, ModeZPY:
+		return fmt.Sprintf("%s $%s,%s @ %s = %s", name, Hex(uint32(d.Operand), 2), d.Mode.String()[2:],
+			Hex(uint32(d.Target), 2), value)
+	case ModeABS:
+		if d.IsJump() {
+			return fmt.Sprintf("%s $%s", name, Hex(uint32(d.Target), 4))
+		}
+		return fmt.Sprintf("%s $%s = %s", name, Hex(uint32(d.Operand), 4), value)
+	case ModeABX, ModeABY:
+		return fmt.Sprintf("%s $%s,%s @ %s = %s", name, Hex(uint32(d.Operand), 4), d.Mode.String()[2:],
+			Hex(uint32(d.Target), 4), value)
+	case ModeIND:
+		return fmt.Sprintf("%s ($%s) = %s", name, Hex(uint32(d.Operand), 4), Hex(uint32(d.Target), 4))
+	case ModeIZX:
+		return fmt.Sprintf("%s ($%s,X) @ %s = %s = %s", name, Hex(uint32(d.Operand), 2), Hex(uint32(d.Pointer), 2),
+			Hex(uint32(d.Target), 4), value)
+	case ModeIZY:
+		return fmt.Sprintf("%s ($%s),Y = %s @ %s = %s", name, Hex(uint32(d.Operand), 2), Hex(uint32(d.Pointer), 4),
+			Hex(uint32(d.Target), 4), value)
+	case ModeREL:
+		return fmt.Sprintf("%s $%s", name, Hex(uint32(d.Target), 4))
+	}
+	return name
+}
+
+// StartAutomation : puts the machine in the power up state used by nestest
+// automation mode, running from the given address instead of the reset vector
+func (b *Bus) StartAutomation(entry Word) {
+	b.Reset()
+	b.clockCount = 0
+	b.cpu.pc = entry
+	b.cpu.status = 0x24
+	b.cpu.stkp = 0xFD
+	b.cpu.cycles = 0
+	b.cpu.clockCount = 7
+	b.ppu.scanLine = 0
+	b.ppu.cycle = 21
+}
@@ -1,4 +1,4 @@
-package main
+package nes
 
 import (
 	"bytes"
@@ -30,15 +30,12 @@ func Hex(n uint32, d int) string {
 	return b.String()
 }
 
-// WriteDisassemble : WriteDisassemble
-func WriteDisassemble(disassemble map[Word]string, filename string) {
-	f, _ := os.Create(filename)
-	defer f.Close()
-	var i Word
-	for i < 0xFFFF {
-		if v, ok := disassemble[i]; ok {
-			f.WriteString(v + "\n")
-		}
-		i++
+// WriteDisassemble : writes a listing as ca65 source to a file
+func WriteDisassemble(listing *Listing, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	return listing.Source(f)
 }
@@ -0,0 +1,132 @@
+package nes
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// paletteSwatch : size of a color in the palette viewer
+	paletteSwatch = 16
+)
+
+// scrollColor : outline of the visible area in the nametable viewer
+var scrollColor = color.RGBA{255, 0, 255, 255}
+
+// GetPatternTable : the 256 tiles of pattern table 0 or 1 as a 128x128
+// picture, drawn with one of the 8 palettes, 0-3 for the background and 4-7
+// for the sprites
+func (p *PPU2C02) GetPatternTable(i int, palette byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+	for tile := 0; tile < 256; tile++ {
+		p.drawTile(img, (tile%16)*8, (tile/16)*8, Word(i)<<12|Word(tile)<<4, palette&0x07, false, false)
+	}
+	return img
+}
+
+// GetNameTables : the four logical nametables as a 512x480 picture with the
+// area shown on the screen outlined, wrapping around the edges
+func (p *PPU2C02) GetNameTables() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2*FrameWidth, 2*FrameHeight))
+	bank := Word(p.GetFlagByte(patternBackground, controlRegister)) << 12
+
+	for table := Word(0); table < 4; table++ {
+		base := 0x2000 + table*0x0400
+		left, top := int(table&1)*FrameWidth, int(table>>1)*FrameHeight
+		for y := Word(0); y < 30; y++ {
+			for x := Word(0); x < 32; x++ {
+				tile, _ := p.PPURead(base+y*32+x, true)
+				attrib, _ := p.PPURead(base+0x03C0+(y>>2)*8+(x>>2), true)
+				palette := attrib >> ((y & 0x02 << 1) | (x & 0x02)) & 0x03
+				p.drawTile(img, left+int(x)*8, top+int(y)*8, bank|Word(tile)<<4, palette, false, false)
+			}
+		}
+	}
+
+	// the scroll the next frame starts from
+	scrollX := int(p.tRAM.nametableX)*FrameWidth + int(p.tRAM.coarseX)*8 + int(p.fineX)
+	scrollY := int(p.tRAM.nametableY)*FrameHeight + int(p.tRAM.coarseY)*8 + int(p.tRAM.fineY)
+	set := func(x, y int) {
+		img.SetRGBA((scrollX+x)%(2*FrameWidth), (scrollY+y)%(2*FrameHeight), scrollColor)
+	}
+	for x := 0; x < FrameWidth; x++ {
+		set(x, 0)
+		set(x, FrameHeight-1)
+	}
+	for y := 0; y < FrameHeight; y++ {
+		set(0, y)
+		set(FrameWidth-1, y)
+	}
+	return img
+}
+
+// GetPaletteRAM : the 32 colors of the palette RAM, the background palettes
+// on the top row and the sprite palettes on the bottom one
+func (p *PPU2C02) GetPaletteRAM() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 16*paletteSwatch, 2*paletteSwatch))
+	for i := 0; i < 32; i++ {
+		c := p.GetColorFromPaletteRAM(byte(i/4), byte(i%4))
+		left, top := (i%16)*paletteSwatch, (i/16)*paletteSwatch
+		for y := top; y < top+paletteSwatch; y++ {
+			for x := left; x < left+paletteSwatch; x++ {
+				img.SetRGBA(x, y, *c)
+			}
+		}
+	}
+	return img
+}
+
+// GetSprites : the 64 sprites of the OAM in an 8x8 grid, each cell is 8x16
+// pixels so 8x16 sprites fit, transparent pixels are left transparent
+func (p *PPU2C02) GetSprites() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 8*8, 8*16))
+	tall := p.GetFlag(spriteSize, controlRegister)
+
+	for i := 0; i < 64; i++ {
+		tile, attrib := p.oam[i*4+1], p.oam[i*4+2]
+		palette := 4 + attrib&0x03
+		flipX, flipY := attrib&0x40 != 0, attrib&0x80 != 0
+		left, top := (i%8)*8, (i/8)*16
+
+		if !tall {
+			bank := Word(p.GetFlagByte(patternSprite, controlRegister)) << 12
+			p.drawTile(img, left, top, bank|Word(tile)<<4, palette, flipX, flipY)
+			continue
+		}
+		// 8x16 sprites take the bank from bit 0 of the tile, the bottom half
+		// is the next tile and both swap places when flipped vertically
+		bank := Word(tile&0x01) << 12
+		first, second := Word(tile&0xFE), Word(tile|0x01)
+		if flipY {
+			first, second = second, first
+		}
+		p.drawTile(img, left, top, bank|first<<4, palette, flipX, flipY)
+		p.drawTile(img, left, top+8, bank|second<<4, palette, flipX, flipY)
+	}
+	return img
+}
+
+// drawTile : draws the 8x8 tile at address in the pattern tables
+func (p *PPU2C02) drawTile(img *image.RGBA, left, top int, address Word, palette byte, flipX, flipY bool) {
+	sprite := palette >= 4
+	for row := 0; row < 8; row++ {
+		lsb, _ := p.PPURead(address+Word(row), true)
+		msb, _ := p.PPURead(address+Word(row)+8, true)
+		y := top + row
+		if flipY {
+			y = top + 7 - row
+		}
+		for col := 0; col < 8; col++ {
+			pixel := (msb>>7)<<1 | lsb>>7
+			lsb, msb = lsb<<1, msb<<1
+			x := left + col
+			if flipX {
+				x = left + 7 - col
+			}
+			if sprite && pixel == 0 {
+				continue
+			}
+			img.SetRGBA(x, y, p.palette[0][p.paletteIndex(palette, pixel)])
+		}
+	}
+}
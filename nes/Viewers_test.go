@@ -0,0 +1,144 @@
+package nes
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/Scoppio/GoNES/asm"
+)
+
+// viewerConsole : palette entries are their own index, except for the
+// backdrop entries which are $0F, tile 1 is color 1 and tile 2 color 2
+func viewerConsole(t *testing.T) *Console {
+	program := asm.MustAssemble(`
+		.org $8000
+reset:  LDA #$3F
+        STA $2006
+        LDA #$00
+        STA $2006
+        LDX #$00
+@pal:   TXA
+        AND #$03
+        BNE @color
+        LDA #$0F
+        BNE @write
+@color: TXA
+@write: STA $2007
+        INX
+        CPX #$20
+        BNE @pal
+
+        LDA #$20
+        STA $2006
+        LDA #$21
+        STA $2006
+        LDA #$01        ; tile 1 at row 1, column 1 of the first nametable
+        STA $2007
+
+        LDX #$00
+@oam:   LDA sprites,X
+        STA $0200,X
+        INX
+        CPX #$08
+        BNE @oam
+        LDA #$00
+        STA $2003
+        LDA #$02
+        STA $4014
+
+        LDA #$00
+        STA $2000
+        LDA #$10
+        STA $2005
+        LDA #$08
+        STA $2005
+@loop:  JMP @loop
+
+sprites: .byte $10, $01, $41, $20   ; palette 5, flipped horizontally
+         .byte $20, $02, $00, $30
+`)
+	cart := TestCartridge(program.Hex(), 0x8000)
+	for row := 0; row < 8; row++ {
+		cart.CHAMemory[0x10+row] = 0xFF
+		cart.CHAMemory[0x28+row] = 0xFF
+	}
+	nes := CreateConsole()
+	nes.InsertCartridge(cart)
+	nes.RunFrame()
+	return nes
+}
+
+func assertColor(t *testing.T, img *image.RGBA, x, y int, index byte) {
+	if got, want := img.RGBAAt(x, y), *defaultColors[index]; got != want {
+		t.Errorf("Expected color $%02X %v at %d,%d, got: %v", index, want, x, y, got)
+	}
+}
+
+func TestOAMDMA(t *testing.T) {
+	nes := viewerConsole(t)
+	ppu := nes.PPU()
+	for i, b := range []byte{0x10, 0x01, 0x41, 0x20, 0x20, 0x02, 0x00, 0x30} {
+		assertEqualsB(t, b, ppu.oam[i])
+	}
+	d, _ := nes.bus.CPURead(0x2004, true)
+	assertEqualsB(t, 0x10, d)
+
+	// the copy takes 513 or 514 cycles on top of the STA
+	bus := stateBus()
+	bus.CPUWrite(0x4014, 0x02)
+	start := bus.cpu.clockCount
+	for bus.dmaTransfer {
+		bus.Clock()
+	}
+	cycles := bus.cpu.clockCount - start
+	assertTrue(t, cycles == 513 || cycles == 514)
+}
+
+func TestPatternTableViewer(t *testing.T) {
+	ppu := viewerConsole(t).PPU()
+	img := ppu.GetPatternTable(0, 0)
+	assertTrue(t, img.Bounds().Dx() == 128 && img.Bounds().Dy() == 128)
+	assertColor(t, img, 0, 0, 0x0F)
+	assertColor(t, img, 8, 0, 0x01)
+	assertColor(t, img, 23, 7, 0x02)
+	assertColor(t, ppu.GetPatternTable(0, 1), 8, 0, 0x05)
+	assertColor(t, ppu.GetPatternTable(1, 0), 8, 0, 0x0F)
+}
+
+func TestNameTableViewer(t *testing.T) {
+	img := viewerConsole(t).PPU().GetNameTables()
+	assertTrue(t, img.Bounds().Dx() == 512 && img.Bounds().Dy() == 480)
+	assertColor(t, img, 9, 9, 0x01)
+	// horizontal mirroring repeats the first nametable on its right
+	assertColor(t, img, FrameWidth+9, 9, 0x01)
+	assertColor(t, img, 9, FrameHeight+9, 0x0F)
+
+	// the screen is outlined from the scroll position
+	for _, p := range [][2]int{{16, 8}, {16 + 255, 8}, {16, 8 + 239}, {16 + 255, 8 + 239}} {
+		if img.RGBAAt(p[0], p[1]) != scrollColor {
+			t.Errorf("Expected the scroll outline at %d,%d", p[0], p[1])
+		}
+	}
+	assertColor(t, img, 17, 9, 0x0F)
+}
+
+func TestPaletteViewer(t *testing.T) {
+	img := viewerConsole(t).PPU().GetPaletteRAM()
+	assertTrue(t, img.Bounds().Dx() == 16*paletteSwatch && img.Bounds().Dy() == 2*paletteSwatch)
+	assertColor(t, img, 0, 0, 0x0F)
+	assertColor(t, img, paletteSwatch+3, 3, 0x01)
+	assertColor(t, img, 15*paletteSwatch+1, 1, 0x0F)
+	assertColor(t, img, 2*paletteSwatch, paletteSwatch, 0x12)
+	assertColor(t, img, 16*paletteSwatch-1, 2*paletteSwatch-1, 0x1F)
+}
+
+func TestSpriteViewer(t *testing.T) {
+	img := viewerConsole(t).PPU().GetSprites()
+	assertTrue(t, img.Bounds().Dx() == 64 && img.Bounds().Dy() == 128)
+	assertColor(t, img, 0, 0, 0x15)
+	assertColor(t, img, 15, 7, 0x12)
+	// below an 8x8 sprite and empty sprites stay transparent
+	assertTrue(t, img.RGBAAt(0, 8) == color.RGBA{})
+	assertTrue(t, img.RGBAAt(20, 4) == color.RGBA{})
+}
@@ -1 +1,2 @@
-go build -o ..\\output\\GoNES.exe ..\\internal\\Main.go ..\\internal\\Bus.go ..\\internal\\C6502.go ..\\internal\\Cartridge.go ..\\internal\\DataTypes.go ..\\internal\\Mapper.go ..\\internal\\P2C02.go ..\\internal\\Utils.go ..\\internal\\Debug.go
+cd /d "%~dp0.."
+go build -o output\GoNES.exe .\cmd\GoNES
\ No newline at end of file
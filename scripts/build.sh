@@ -1,10 +1,3 @@
-go build -o ../output/GoNES \
-    ../internal/Main.go \
-    ../internal/Bus.go \
-    ../internal/C6502.go \
-    ../internal/P2C02.go \
-    ../internal/DataTypes.go \
-    ../internal/Utils.go \
-    ../internal/Cartridge.go \
-    ../internal/Mapper.go \
-    ../internal/Debug.go
+# builds from the module root so go.mod resolves the dependencies
+cd "$(dirname "$0")/.." || exit 1
+go build -o output/GoNES ./cmd/GoNES
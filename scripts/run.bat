@@ -1 +1 @@
-go run ..\\internal\\Main.go ..\\internal\\Bus.go ..\\internal\\C6502.go ..\\internal\\Cartridge.go ..\\internal\\DataTypes.go ..\\internal\\Mapper.go ..\\internal\\P2C02.go ..\\internal\\Utils.go ..\\internal\\Debug.go
\ No newline at end of file
+go run ..\\cmd\\GoNES %*
\ No newline at end of file
@@ -1,9 +1 @@
-go run ../internal/Main.go \
-    ../internal/Bus.go \
-    ../internal/C6502.go \
-    ../internal/Cartridge.go \
-    ../internal/DataTypes.go \
-    ../internal/Mapper.go \
-    ../internal/P2C02.go \
-    ../internal/Debug.go \
-    ../internal/Utils.go 
+go run ../cmd/GoNES "$@"